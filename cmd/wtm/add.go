@@ -5,15 +5,17 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
 	"github.com/vansdevcode/worktree-manager/internal/git"
 	"github.com/vansdevcode/worktree-manager/internal/hook"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
 	"github.com/vansdevcode/worktree-manager/internal/pr"
-	"github.com/vansdevcode/worktree-manager/internal/template"
 	"github.com/vansdevcode/worktree-manager/internal/worktree"
 	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
@@ -25,39 +27,128 @@ var addCmd = &cobra.Command{
 
 If the branch doesn't exist, it will be created from the base branch.
 Supports PR syntax: pr/<number> or pr/<number>/<custom-name>
+and GitLab merge requests: mr/<number> or mr/<number>/<custom-name>
+Issues (issue/<number>) get a new branch named after the issue title,
+created from the default branch.
 
 Examples:
   wtmadd main feature-x          # Create feature-x from main
   wtmadd main                    # Create worktree for main branch
   wtmadd main feature-y my-dir   # Create in custom directory
   wtmadd pr/123                  # Checkout PR #123
-  wtmadd pr/123 custom-name      # PR #123 in custom directory`,
+  wtmadd pr/123 custom-name      # PR #123 in custom directory
+  wtmadd mr/45                   # Checkout GitLab MR !45
+  wtmadd issue/12                # Work on issue #12 (e.g., fix/12-crash-on-start)
+  wtmadd main feature-z --seed-from main  # Reuse main's node_modules/vendor
+  wtmadd main feature-x --draft-pr        # Also push it and open a draft PR
+  wtmadd main feature-x --sparse api      # Only check out the api profile's directories`,
 	Args: cobra.RangeArgs(1, 3),
 	RunE: runAdd,
 }
 
-var addNoHooks bool
+var (
+	addNoHooks  bool
+	addSeedFrom string
+	addNoSeed   bool
+	addDraftPR  bool
+	addSparse   string
+)
 
 func init() {
 	addCmd.Flags().BoolVar(&addNoHooks, "no-hooks", false, "Skip running post-create hooks")
+	addCmd.Flags().StringVar(&addSeedFrom, "seed-from", "", "Copy the configured seed paths from this worktree")
+	addCmd.Flags().BoolVar(&addNoSeed, "no-seed", false, "Skip seeding even if a default is configured")
+	addCmd.Flags().BoolVar(&addDraftPR, "draft-pr", false, "Push the new branch and open a draft pull request (see 'wtm publish')")
+	addCmd.Flags().StringVar(&addSparse, "sparse", "", "Only check out the directories of this sparse-checkout profile from config.yaml")
 }
 
 // normalizeRemoteBranch extracts the local branch name from a remote branch reference
 // e.g., "origin/develop" -> ("develop", "origin/develop")
 // Returns (localName, startPoint) where startPoint is the full reference if it's a remote branch
-func normalizeRemoteBranch(branchRef string) (string, string) {
-	// Check for origin/ prefix
-	if strings.HasPrefix(branchRef, "origin/") {
-		localName := strings.TrimPrefix(branchRef, "origin/")
-		return localName, branchRef
+func normalizeRemoteBranch(branchRef string, remotes []string) (string, string) {
+	remote, branch := splitRemoteRef(branchRef, remotes)
+	if remote == "" {
+		// Not a remote branch reference, return as-is
+		return branchRef, ""
+	}
+	return branch, branchRef
+}
+
+// splitRemoteRef splits <remote>/<branch> when it starts with one of the
+// remotes, preferring the longest as remote names may contain slashes.
+// remote is empty when the reference names no remote.
+func splitRemoteRef(ref string, remotes []string) (remote, branch string) {
+	for _, name := range remotes {
+		if len(name) > len(remote) && len(ref) > len(name)+1 && strings.HasPrefix(ref, name+"/") {
+			remote = name
+		}
+	}
+	if remote == "" {
+		return "", ref
+	}
+	return remote, strings.TrimPrefix(ref, remote+"/")
+}
+
+// fetchRemoteBranch makes sure the remote-tracking branch <remote>/<branch>
+// exists, fetching it when the remote's refspec did not bring it in
+func fetchRemoteBranch(bareDir, remote, branch string) error {
+	if _, err := git.ResolveCommit(bareDir, "refs/remotes/"+remote+"/"+branch); err == nil {
+		return nil
+	}
+	ui.Info("Fetching %s/%s...", remote, branch)
+	if err := git.FetchRemoteBranch(bareDir, remote, branch); err != nil {
+		return fmt.Errorf("failed to fetch '%s/%s': %w", remote, branch, err)
+	}
+
+	// A single-branch clone only fetches the branches it names: add this one
+	// so 'wtm fetch' keeps it up to date
+	if refspecs, err := git.GetFetchRefspecs(bareDir, remote); err == nil && len(refspecs) > 0 && !slices.Contains(refspecs, git.FetchRefspec(remote)) {
+		if err := git.AddRemoteBranch(bareDir, remote, branch); err != nil {
+			ui.Warning("⚠ Could not add '%s' to the branches %s fetches: %v", branch, remote, err)
+		}
+	}
+	return nil
+}
+
+// checkLocalCollision reports whether an existing local branch can stand in
+// for <remote>/<branch>: it must track it, or track nothing when the remote
+// is origin (bare clones create local branches without tracking). Otherwise
+// it asks whether to check out the local branch anyway.
+func checkLocalCollision(bareDir, localName, startPoint string) error {
+	upstream, err := git.GetUpstream(bareDir, localName)
+	if err != nil {
+		return err
 	}
+	if upstream == startPoint || (upstream == "" && startPoint == "origin/"+localName) {
+		return nil
+	}
+
+	tracking := "no remote branch"
+	if upstream != "" {
+		tracking = upstream
+	}
+	if ui.Confirm("Local branch '%s' already exists and tracks %s, not %s. Check out the local branch instead?", localName, tracking, startPoint) {
+		return nil
+	}
+	alternative := strings.ReplaceAll(startPoint, "/", "-")
+	return withHint(git.ErrBranchExists, "local branch '%s' already exists and tracks %s, not %s; use 'wtm add %s %s' to work on %s in a new branch", localName, tracking, startPoint, startPoint, alternative, startPoint)
+}
 
-	// Check for other remote prefixes (e.g., upstream/main)
-	// For now, only handle origin/ explicitly
-	// Other cases fall through to default behavior
+// addWorktree adds a worktree, restricted to the sparse-checkout paths if any
+func addWorktree(bareDir, branch, path, startPoint string, sparsePaths []string) error {
+	if len(sparsePaths) > 0 {
+		return git.AddWorktreeSparse(bareDir, branch, path, startPoint, sparsePaths)
+	}
+	return git.AddWorktree(bareDir, branch, path, startPoint)
+}
 
-	// Not a remote branch reference, return as-is
-	return branchRef, ""
+// pushToUpstream makes 'git push' in a worktree push to the upstream branch
+// even when its name differs from the local branch (push.default=upstream)
+func pushToUpstream(bareDir, worktreePath string) error {
+	if err := git.EnableWorktreeConfig(bareDir); err != nil {
+		return err
+	}
+	return git.SetWorktreeConfig(worktreePath, "push.default", "upstream")
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
@@ -68,6 +159,18 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	}
 
 	bareDir := config.GetBareDir(rootDir)
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+
+	var sparsePaths []string
+	if addSparse != "" {
+		if sparsePaths, err = settings.SparsePaths(addSparse); err != nil {
+			return &usageError{err}
+		}
+	}
+
 	baseBranch := args[0]
 	newBranch := ""
 	directory := ""
@@ -80,15 +183,17 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		directory = args[2]
 	}
 
-	// Check if base branch is PR syntax
+	// Check if base branch is PR syntax (pr/<n> on GitHub, mr/<n> on GitLab)
 	isPR := false
 	prNumber := 0
-	if strings.HasPrefix(baseBranch, "pr/") {
+	prKind := ""
+	if strings.HasPrefix(baseBranch, "pr/") || strings.HasPrefix(baseBranch, "mr/") {
 		isPR = true
-		re := regexp.MustCompile(`^pr/(\d+)(?:/(.+))?$`)
+		prKind = baseBranch[:2]
+		re := regexp.MustCompile(`^(?:pr|mr)/(\d+)(?:/(.+))?$`)
 		matches := re.FindStringSubmatch(baseBranch)
 		if matches == nil {
-			return fmt.Errorf("invalid PR syntax, use pr/<number> or pr/<number>/<name>")
+			return fmt.Errorf("invalid %s syntax, use %s/<number> or %s/<number>/<name>", strings.ToUpper(prKind), prKind, prKind)
 		}
 
 		prNumber, _ = strconv.Atoi(matches[1])
@@ -100,15 +205,62 @@ func runAdd(cmd *cobra.Command, args []string) error {
 			newBranch = ""
 		}
 		if directory == "" {
-			directory = fmt.Sprintf("pr-%d", prNumber)
+			directory = fmt.Sprintf("%s-%d", prKind, prNumber)
+		}
+	}
+
+	// Check for issue syntax (issue/<n>): a new branch named after the issue,
+	// created from the default branch
+	var issue *metadata.Issue
+	if strings.HasPrefix(baseBranch, "issue/") {
+		issueNumber, err := strconv.Atoi(strings.TrimPrefix(baseBranch, "issue/"))
+		if err != nil || issueNumber <= 0 {
+			return fmt.Errorf("invalid issue syntax, use issue/<number>")
+		}
+		if baseBranch, err = git.GetDefaultBranch(bareDir); err != nil {
+			return fmt.Errorf("failed to determine the default branch: %w", err)
 		}
+
+		f, repo, err := forge.ForRemote(bareDir, "origin", settings.Forges)
+		if err != nil {
+			return err
+		}
+		ui.Info("Fetching issue #%d...", issueNumber)
+		found, err := f.GetIssue(repo, issueNumber)
+		if err != nil {
+			return fmt.Errorf("failed to fetch issue #%d: %w", issueNumber, err)
+		}
+		ui.Info("  %s (@%s)", found.Title, found.Author)
+		if found.State == "CLOSED" {
+			ui.Warning("⚠ Issue #%d is closed", issueNumber)
+		}
+		issue = &metadata.Issue{Number: found.Number, Title: found.Title, URL: found.URL}
+
+		// An explicit branch name wins over the derived one
+		if newBranch == "" {
+			if newBranch, err = worktree.IssueBranch(rootDir, settings.Issues, settings.Datasources, found.Number, found.Title, found.Labels); err != nil {
+				return err
+			}
+		}
+		if err := git.CheckBranchName(newBranch); err != nil {
+			return err
+		}
+	}
+
+	if addDraftPR && (isPR || newBranch == "") {
+		return fmt.Errorf("--draft-pr needs a new branch, e.g. 'wtm add main feature-x --draft-pr'")
+	}
+
+	remotes, err := git.ListRemotes(bareDir)
+	if err != nil {
+		return err
 	}
 
 	// If no new branch specified, use base branch
-	// Handle remote branch references (e.g., origin/develop)
+	// Handle remote branch references (e.g., origin/develop, upstream/main)
 	startPoint := ""
 	if newBranch == "" && !isPR {
-		localName, remoteRef := normalizeRemoteBranch(baseBranch)
+		localName, remoteRef := normalizeRemoteBranch(baseBranch, remotes)
 		newBranch = localName
 		startPoint = remoteRef
 	}
@@ -116,7 +268,7 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	// Determine directory name
 	if directory == "" {
 		if isPR {
-			directory = fmt.Sprintf("pr-%d", prNumber)
+			directory = fmt.Sprintf("%s-%d", prKind, prNumber)
 		} else {
 			directory = worktree.GenerateWorktreeDirectory(newBranch)
 		}
@@ -124,26 +276,97 @@ func runAdd(cmd *cobra.Command, args []string) error {
 
 	worktreePath := filepath.Join(rootDir, directory)
 
-	// Check if directory already exists
+	// Check if directory already exists. Checking out the same PR again updates it.
 	if _, err := os.Stat(worktreePath); err == nil {
-		return fmt.Errorf("directory '%s' already exists", directory)
+		if isPR {
+			meta, err := metadata.Load(rootDir, worktreePath)
+			if err != nil {
+				return err
+			}
+			if change := worktreeChange(meta, directory); change != nil && change.Kind == prKind && change.Number == prNumber {
+				ui.Info("%s is already checked out in '%s', updating it", changeLabel(prKind, prNumber), directory)
+				return updateChange(rootDir, worktreePath, settings, false)
+			}
+		}
+		return withHint(git.ErrPathExists, "directory '%s' already exists", directory)
+	}
+
+	// State left by a worktree of the same name that was removed without wtm
+	// (git worktree remove, rm -rf) must not apply to the new one
+	if err := os.RemoveAll(config.GetWorktreeStateDir(rootDir, filepath.Base(worktreePath))); err != nil {
+		return fmt.Errorf("failed to clear stale worktree state: %w", err)
+	}
+
+	// Determine seed source (flag overrides config default)
+	seedFrom := settings.Seed.From
+	if addSeedFrom != "" {
+		if _, err := os.Stat(resolveWorktreePath(rootDir, addSeedFrom)); err != nil {
+			return fmt.Errorf("seed source '%s' does not exist", addSeedFrom)
+		}
+		seedFrom = addSeedFrom
+	}
+	if addNoSeed {
+		seedFrom = ""
 	}
 
-	// Handle PR checkout
+	// Handle PR/MR checkout
 	if isPR {
-		ui.Info("Fetching PR #%d...", prNumber)
+		label := changeLabel(prKind, prNumber)
+		fetch := pr.FetchPR
+		if prKind == "mr" {
+			fetch = pr.FetchMR
+		}
+		ui.Info("Fetching %s...", label)
+
+		pullRequest, err := fetch(bareDir, prNumber, directory, settings.Forges)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", strings.ToUpper(prKind), err)
+		}
+		if pullRequest.Title != "" {
+			ui.Info("  %s (@%s, into %s)", pullRequest.Title, pullRequest.Author, pullRequest.BaseRef)
+		}
 
-		branchName, err := pr.FetchPR(bareDir, prNumber, directory)
+		// Let maintainers push fixes back to the contributor's fork
+		forkRemote, err := pr.TrackFork(bareDir, pullRequest)
 		if err != nil {
-			return fmt.Errorf("failed to fetch PR: %w", err)
+			ui.Warning("⚠ Could not track the fork: %v", err)
+		} else if forkRemote != "" {
+			ui.Info("  Tracking %s/%s (fork %s)", forkRemote, pullRequest.HeadRef, pullRequest.HeadRepo)
+			if pullRequest.MaintainerCanModify {
+				ui.Info("  Maintainers can push to the fork branch")
+			} else {
+				ui.Warning("⚠ The author does not allow edits from maintainers, pushes to %s will be rejected", forkRemote)
+			}
+		}
+
+		ui.Info("Creating worktree for %s (branch: %s)", label, pullRequest.Branch)
+		if err := addWorktree(bareDir, pullRequest.Branch, worktreePath, "", sparsePaths); err != nil {
+			return worktreeAddError(rootDir, err)
 		}
 
-		ui.Info("Creating worktree for PR #%d (branch: %s)", prNumber, branchName)
-		if err := git.AddWorktree(bareDir, branchName, worktreePath, ""); err != nil {
-			return fmt.Errorf("failed to create worktree: %w", err)
+		newBranch = pullRequest.Branch
+
+		// A branch renamed because its head branch name was taken pushes to its upstream
+		if forkRemote != "" && newBranch != pullRequest.HeadRef {
+			if err := pushToUpstream(bareDir, worktreePath); err != nil {
+				ui.Warning("⚠ Could not configure pushing to %s/%s: %v", forkRemote, pullRequest.HeadRef, err)
+			}
 		}
 
-		newBranch = branchName
+		// Remember the change so the worktree can be updated later
+		change := &metadata.Change{
+			Kind:     prKind,
+			Number:   prNumber,
+			BaseRef:  pullRequest.BaseRef,
+			HeadRef:  pullRequest.HeadRef,
+			HeadRepo: pullRequest.HeadRepo,
+		}
+		if head, err := git.ResolveCommit(bareDir, "refs/heads/"+newBranch); err == nil {
+			change.Head = head
+		}
+		if err := metadata.Save(rootDir, worktreePath, &metadata.Metadata{Change: change}); err != nil {
+			ui.Warning("Failed to record worktree metadata: %v", err)
+		}
 	} else {
 		// Check if branch exists locally (not just remote)
 		localBranchExists, err := git.LocalBranchExists(bareDir, newBranch)
@@ -151,11 +374,39 @@ func runAdd(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to check if branch exists: %w", err)
 		}
 
+		// A remote base (e.g., upstream/main) may not have been fetched yet
+		baseRemote, baseRemoteBranch := splitRemoteRef(baseBranch, remotes)
+		if baseRemote != "" {
+			if err := fetchRemoteBranch(bareDir, baseRemote, baseRemoteBranch); err != nil {
+				return err
+			}
+		}
+		// A branch a single-branch or shallow clone left out is fetched on demand
+		if baseRemote == "" && !localBranchExists && slices.Contains(remotes, "origin") {
+			if _, err := git.ResolveCommit(bareDir, baseBranch); err != nil && git.CheckBranchName(baseBranch) == nil {
+				if err := fetchRemoteBranch(bareDir, "origin", baseBranch); err == nil {
+					if startPoint == "" && newBranch == baseBranch {
+						startPoint = "origin/" + baseBranch
+					}
+					baseRemote, baseRemoteBranch = "origin", baseBranch
+					baseBranch = "origin/" + baseBranch
+				}
+			}
+		}
+		if localBranchExists && startPoint != "" {
+			if err := checkLocalCollision(bareDir, newBranch, startPoint); err != nil {
+				return err
+			}
+		}
+
 		if localBranchExists {
 			// Local branch exists - check out directly
 			ui.Info("Creating worktree for existing branch: %s", newBranch)
-			if err := git.AddWorktree(bareDir, newBranch, worktreePath, ""); err != nil {
-				return fmt.Errorf("failed to create worktree: %w", err)
+			if err := addWorktree(bareDir, newBranch, worktreePath, "", sparsePaths); err != nil {
+				return worktreeAddError(rootDir, err)
+			}
+			if err := metadata.Save(rootDir, worktreePath, &metadata.Metadata{Issue: issue}); err != nil {
+				ui.Warning("Failed to record worktree metadata: %v", err)
 			}
 		} else {
 			// Local branch doesn't exist - create it from startPoint or baseBranch
@@ -167,26 +418,42 @@ func runAdd(cmd *cobra.Command, args []string) error {
 			} else {
 				ui.Info("Creating new branch '%s' from '%s'", newBranch, baseBranch)
 			}
-			if err := git.AddWorktree(bareDir, newBranch, worktreePath, createFrom); err != nil {
-				return fmt.Errorf("failed to create worktree: %w", err)
+			if err := addWorktree(bareDir, newBranch, worktreePath, createFrom, sparsePaths); err != nil {
+				return worktreeAddError(rootDir, err)
+			}
+
+			// Remember the base so a pull request can target it later
+			base, _ := normalizeRemoteBranch(createFrom, remotes)
+			if err := metadata.Save(rootDir, worktreePath, &metadata.Metadata{Base: base, Issue: issue}); err != nil {
+				ui.Warning("Failed to record worktree metadata: %v", err)
 			}
 		}
-	}
 
-	// Process files
-	filesDir := config.GetFilesDir(rootDir)
-	if _, err := os.Stat(filesDir); err == nil {
-		ui.Info("Processing files...")
-		data := template.TemplateData{
-			Branch:        newBranch,
-			Directory:     worktreePath,
-			RootDirectory: rootDir,
+		// A remote branch checked out under its own name tracks it
+		if startPoint != "" {
+			if upstream, err := git.GetUpstream(bareDir, newBranch); err == nil && upstream == "" {
+				if err := git.SetUpstream(bareDir, newBranch, baseRemote, baseRemoteBranch); err != nil {
+					ui.Warning("⚠ Could not set '%s' to track %s: %v", newBranch, startPoint, err)
+				}
+			}
 		}
-		if err := template.ProcessTemplates(filesDir, worktreePath, data); err != nil {
-			ui.Warning("Failed to process files: %v", err)
+	}
+
+	// Remember the profile for templates, hooks and 'wtm sparse'
+	if addSparse != "" {
+		ui.Info("  Sparse checkout: %s (%s)", addSparse, strings.Join(sparsePaths, ", "))
+		if err := recordSparseProfile(rootDir, worktreePath, addSparse); err != nil {
+			ui.Warning("Failed to record worktree metadata: %v", err)
 		}
 	}
 
+	setupWorktree(rootDir, worktreePath, newBranch, settings)
+
+	// Seed ignored artifacts from another worktree
+	if seedFrom != "" {
+		seedWorktree(rootDir, worktreePath, seedFrom, settings.Seed)
+	}
+
 	// Run post-create hook
 	if !addNoHooks {
 		ui.Info("Running post-create hook...")
@@ -199,5 +466,11 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	ui.Info("  Branch: %s", newBranch)
 	ui.Info("  Directory: %s", worktreePath)
 
+	if addDraftPR {
+		if err := publishWorktree(rootDir, worktreePath, settings); err != nil {
+			return fmt.Errorf("worktree created, but the draft PR could not be opened (retry with 'wtm publish %s'): %w", directory, err)
+		}
+	}
+
 	return nil
 }
@@ -1,6 +1,17 @@
 package main
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/manifest"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
 
 func TestNormalizeRemoteBranch(t *testing.T) {
 	tests := []struct {
@@ -48,14 +59,34 @@ func TestNormalizeRemoteBranch(t *testing.T) {
 		{
 			name:           "upstream remote",
 			branchRef:      "upstream/main",
-			wantLocalName:  "upstream/main",
+			wantLocalName:  "main",
+			wantStartPoint: "upstream/main",
+		},
+		{
+			name:           "remote name with slash",
+			branchRef:      "team/fork/fix",
+			wantLocalName:  "fix",
+			wantStartPoint: "team/fork/fix",
+		},
+		{
+			name:           "not a configured remote",
+			branchRef:      "feature/upstream/main",
+			wantLocalName:  "feature/upstream/main",
+			wantStartPoint: "",
+		},
+		{
+			name:           "remote name alone",
+			branchRef:      "upstream/",
+			wantLocalName:  "upstream/",
 			wantStartPoint: "",
 		},
 	}
 
+	remotes := []string{"origin", "upstream", "team", "team/fork"}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotLocalName, gotStartPoint := normalizeRemoteBranch(tt.branchRef)
+			gotLocalName, gotStartPoint := normalizeRemoteBranch(tt.branchRef, remotes)
 			if gotLocalName != tt.wantLocalName {
 				t.Errorf("normalizeRemoteBranch() localName = %q, want %q", gotLocalName, tt.wantLocalName)
 			}
@@ -65,3 +96,90 @@ func TestNormalizeRemoteBranch(t *testing.T) {
 		})
 	}
 }
+
+// TestAddCommand_OtherRemote tests checking out <remote>/<branch> from a remote
+// other than origin
+func TestAddCommand_OtherRemote(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	upstreamDir := filepath.Join(t.TempDir(), "upstream.git")
+	if err := git.InitBare(upstreamDir); err != nil {
+		t.Fatalf("Failed to init upstream: %v", err)
+	}
+	for _, branch := range []string{"main", "release"} {
+		if err := git.CreateInitialBranch(upstreamDir, branch); err != nil {
+			t.Fatalf("Failed to create branch: %v", err)
+		}
+	}
+	// Without fetching: add must fetch the branch itself
+	runGitCmd(t, "--git-dir="+bareDir, "remote", "add", "upstream", upstreamDir)
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addNoHooks = true
+	defer func() { addNoHooks = false }()
+
+	if err := runAdd(addCmd, []string{"upstream/release"}); err != nil {
+		t.Fatalf("runAdd(upstream/release) error = %v", err)
+	}
+	if branch, err := git.GetWorktreeBranch(filepath.Join(rootDir, "release")); err != nil || branch != "release" {
+		t.Errorf("worktree branch = %q, %v, want release", branch, err)
+	}
+	if upstream, _ := git.GetUpstream(bareDir, "release"); upstream != "upstream/release" {
+		t.Errorf("upstream of release = %q, want upstream/release", upstream)
+	}
+
+	// The local main is origin's, so upstream/main collides with it
+	oldInput := ui.Input
+	ui.Input = strings.NewReader("n\n")
+	defer func() { ui.Input = oldInput }()
+
+	err := runAdd(addCmd, []string{"upstream/main", "", "upstream-main"})
+	if !errors.Is(err, git.ErrBranchExists) || !strings.Contains(err.Error(), "wtm add upstream/main upstream-main") {
+		t.Errorf("runAdd(upstream/main) error = %v, want a collision suggesting a new branch", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "upstream-main")); !os.IsNotExist(err) {
+		t.Error("no worktree should be created on a collision")
+	}
+}
+
+// TestAddCommand_StaleState tests that state left by a worktree removed
+// without wtm does not apply to a new worktree of the same name
+func TestAddCommand_StaleState(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	runGitCmd(t, "--git-dir="+bareDir, "branch", "feature", "main")
+	worktreePath := filepath.Join(rootDir, "feature")
+	stale := &metadata.Metadata{Base: "release", Change: &metadata.Change{Kind: "pr", Number: 5}, Sparse: "docs"}
+	if err := metadata.Save(rootDir, worktreePath, stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifest.Path(rootDir, worktreePath), []byte(`{"branch": "other"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addNoHooks = true
+	defer func() { addNoHooks = false }()
+
+	if err := runAdd(addCmd, []string{"feature"}); err != nil {
+		t.Fatalf("runAdd(feature) error = %v", err)
+	}
+	if meta, err := metadata.Load(rootDir, worktreePath); err != nil || meta.Change != nil || meta.Base != "" || meta.Sparse != "" {
+		t.Errorf("metadata = %+v, %v, want the stale state cleared", meta, err)
+	}
+	if _, err := os.Stat(manifest.Path(rootDir, worktreePath)); !os.IsNotExist(err) {
+		t.Errorf("the stale manifest should be gone: %v", err)
+	}
+}
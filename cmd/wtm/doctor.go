@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the repository setup and fix problems",
+	Long: `Check a worktree-managed repository for setup problems, such as those left
+by older versions of wtm, and fix them.
+
+It also reports the clone mode: full, or shallow, partial and single-branch
+as set up with 'wtm init --depth/--filter/--single-branch'.
+
+Checks:
+  - origin has a fetch refspec, so refs/remotes/origin/* are updated
+    (git clone --bare does not configure one)
+  - the links between .bare and the worktrees are intact, e.g., after the
+    root was moved (fixed like 'wtm repair')
+
+Examples:
+  wtm doctor             # Check and fix
+  wtm doctor --dry-run   # Only report problems`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+var doctorDryRun bool
+
+func init() {
+	doctorCmd.Flags().BoolVarP(&doctorDryRun, "dry-run", "n", false, "Only report problems, do not fix them")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCheck is a setup problem doctor detects and fixes
+type doctorCheck struct {
+	name  string
+	check func(rootDir string) (problem string, err error) // "" when healthy
+	fix   func(rootDir string) error
+}
+
+var doctorChecks = []doctorCheck{
+	{name: "fetch refspec", check: checkFetchRefspec, fix: fixFetchRefspec},
+	{name: "worktree links", check: checkWorktreeLinks, fix: fixWorktreeLinks},
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	ui.Info("Clone mode: %s", describeClone(config.GetBareDir(rootDir)))
+
+	problems, fixed := 0, 0
+	for _, c := range doctorChecks {
+		problem, err := c.check(rootDir)
+		if err != nil {
+			ui.Warning("⚠ Could not check %s: %v", c.name, err)
+			continue
+		}
+		if problem == "" {
+			ui.Success("✓ %s", c.name)
+			continue
+		}
+
+		problems++
+		ui.Warning("⚠ %s: %s", c.name, problem)
+		if doctorDryRun {
+			continue
+		}
+		if err := c.fix(rootDir); err != nil {
+			ui.Warning("⚠ Failed to fix %s: %v", c.name, err)
+			continue
+		}
+		fixed++
+		ui.Success("✓ Fixed %s", c.name)
+	}
+
+	switch {
+	case problems == 0:
+		ui.Success("✓ No problems found")
+	case doctorDryRun:
+		return fmt.Errorf("%d problem(s) found, run 'wtm doctor' without --dry-run to fix them", problems)
+	case fixed < problems:
+		return fmt.Errorf("%d of %d problem(s) could not be fixed", problems-fixed, problems)
+	}
+	return nil
+}
+
+// checkFetchRefspec detects an origin without fetch refspec, as git clone
+// --bare (and so wtm init before it configured one) leaves it
+func checkFetchRefspec(rootDir string) (string, error) {
+	bareDir := config.GetBareDir(rootDir)
+	remotes, err := git.ListRemotes(bareDir)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(remotes, "origin") {
+		return "", nil
+	}
+
+	refspecs, err := git.GetFetchRefspecs(bareDir, "origin")
+	if err != nil {
+		return "", err
+	}
+	if len(refspecs) == 0 {
+		return "origin has no fetch refspec, so refs/remotes/origin/* are missing or stale", nil
+	}
+	return "", nil
+}
+
+// fixFetchRefspec configures the standard refspec for origin and fetches it
+func fixFetchRefspec(rootDir string) error {
+	ui.Info("Configuring %s for origin and fetching...", git.FetchRefspec("origin"))
+	return configureFetch(config.GetBareDir(rootDir), "origin", "")
+}
+
+// checkWorktreeLinks detects worktrees whose links with .bare are stale
+func checkWorktreeLinks(rootDir string) (string, error) {
+	links, oldRoot, err := inspectWorktreeLinks(rootDir)
+	if err != nil {
+		return "", err
+	}
+
+	broken := 0
+	for _, link := range links {
+		if link.broken {
+			broken++
+		}
+	}
+	switch {
+	case broken == 0:
+		return "", nil
+	case oldRoot != "":
+		return fmt.Sprintf("%d worktree(s) still point to %s, where the root was before it moved", broken, oldRoot), nil
+	}
+	return fmt.Sprintf("%d worktree(s) have stale links with .bare", broken), nil
+}
+
+// fixWorktreeLinks repairs the links like 'wtm repair'
+func fixWorktreeLinks(rootDir string) error {
+	return repairRoot(rootDir, false)
+}
+
+// describeClone reports how the bare repository was cloned from origin
+func describeClone(bareDir string) string {
+	depth := 0
+	if value, _ := git.GetConfig(bareDir, git.DepthConfig); value != "" {
+		depth, _ = strconv.Atoi(value)
+	}
+
+	// A single-branch clone fetches named branches instead of all of them
+	singleBranch := false
+	if refspecs, err := git.GetFetchRefspecs(bareDir, "origin"); err == nil && len(refspecs) > 0 {
+		singleBranch = !slices.Contains(refspecs, git.FetchRefspec("origin"))
+	}
+
+	return cloneMode(git.IsShallow(bareDir), depth, git.PartialCloneFilter(bareDir, "origin"), singleBranch)
+}
+
+// cloneMode describes a clone, e.g., "shallow (depth 1), partial (blob:none)",
+// or "full"
+func cloneMode(shallow bool, depth int, filter string, singleBranch bool) string {
+	var modes []string
+	if shallow {
+		if depth > 0 {
+			modes = append(modes, fmt.Sprintf("shallow (depth %d)", depth))
+		} else {
+			modes = append(modes, "shallow")
+		}
+	}
+	if filter != "" {
+		modes = append(modes, fmt.Sprintf("partial (%s)", filter))
+	}
+	if singleBranch {
+		modes = append(modes, "single-branch")
+	}
+	if len(modes) == 0 {
+		return "full"
+	}
+	return strings.Join(modes, ", ")
+}
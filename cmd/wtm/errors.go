@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// Exit codes, documented in the README
+const (
+	exitError       = 1   // Any other failure
+	exitUsage       = 2   // Invalid arguments or flags
+	exitConflict    = 3   // A branch or directory is already taken, or a worktree is locked
+	exitNotFound    = 4   // A branch, ref or repository does not exist
+	exitRemote      = 5   // The remote cannot be reached or refused the credentials
+	exitDirty       = 6   // A worktree has changes that would be lost
+	exitInterrupted = 130 // Interrupted with Ctrl-C (128 + SIGINT)
+)
+
+// exitCode returns the exit code for the class of an error
+func exitCode(err error) int {
+	var usage *usageError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, git.ErrBranchExists), errors.Is(err, git.ErrBranchCheckedOut), errors.Is(err, git.ErrPathExists), errors.Is(err, git.ErrWorktreeLocked):
+		return exitConflict
+	case errors.Is(err, git.ErrInvalidRef), errors.Is(err, git.ErrRemoteNotFound):
+		return exitNotFound
+	case errors.Is(err, git.ErrNetwork), errors.Is(err, git.ErrAuth):
+		return exitRemote
+	case errors.Is(err, git.ErrDirtyWorktree):
+		return exitDirty
+	}
+	return exitError
+}
+
+// usageError is an invalid invocation: wrong arguments or flags
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// markUsageErrors makes the argument and flag validation errors of a command
+// and its subcommands usage errors
+func markUsageErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return &usageError{err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// hintError replaces the message of an error with an actionable one while
+// keeping the error for errors.Is and errors.As
+type hintError struct {
+	message string
+	err     error
+}
+
+func (e *hintError) Error() string { return e.message }
+func (e *hintError) Unwrap() error { return e.err }
+
+// withHint wraps err with an actionable message
+func withHint(err error, format string, args ...any) error {
+	return &hintError{message: fmt.Sprintf(format, args...), err: err}
+}
+
+// worktreeAddError explains why git could not create a worktree and what to
+// do instead
+func worktreeAddError(rootDir string, err error) error {
+	var gitErr *git.Error
+	errors.As(err, &gitErr)
+
+	switch {
+	case errors.Is(err, git.ErrBranchCheckedOut):
+		location := gitErr.Path
+		if rel, relErr := filepath.Rel(rootDir, gitErr.Path); relErr == nil && filepath.IsLocal(rel) {
+			location = "." + string(os.PathSeparator) + rel
+		}
+		return withHint(err, "branch '%s' is already checked out at %s, use 'cd %s' to work on it", gitErr.Branch, location, location)
+	case errors.Is(err, git.ErrBranchExists):
+		return withHint(err, "branch '%s' already exists, use 'wtm add %s' to check it out", gitErr.Branch, gitErr.Branch)
+	case errors.Is(err, git.ErrPathExists):
+		return withHint(err, "'%s' already exists, choose another directory name", gitErr.Path)
+	case errors.Is(err, git.ErrInvalidRef):
+		return withHint(err, "failed to create worktree: %v (run 'git fetch' if the branch is new on the remote)", err)
+	}
+	return fmt.Errorf("failed to create worktree: %w", err)
+}
+
+// cloneError explains why a repository could not be cloned
+func cloneError(url string, err error) error {
+	switch {
+	case errors.Is(err, git.ErrRemoteNotFound):
+		return withHint(err, "repository '%s' was not found, or you have no access to it", url)
+	case errors.Is(err, git.ErrAuth):
+		return withHint(err, "authentication to '%s' failed, check your credentials (e.g., 'gh auth login' or your SSH key): %v", url, err)
+	case errors.Is(err, git.ErrNetwork):
+		return withHint(err, "cannot reach '%s', check your network connection: %v", url, err)
+	}
+	return fmt.Errorf("failed to clone repository: %w", err)
+}
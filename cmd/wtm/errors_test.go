@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestWorktreeAddError_CheckedOut tests the message and exit code for a branch
+// that is already checked out in another worktree
+func TestWorktreeAddError_CheckedOut(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	if err := git.AddWorktree(bareDir, "main", filepath.Join(rootDir, "main"), ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	err := git.AddWorktree(bareDir, "main", filepath.Join(rootDir, "other"), "")
+	if !errors.Is(err, git.ErrBranchCheckedOut) {
+		t.Fatalf("AddWorktree() error = %v, want ErrBranchCheckedOut", err)
+	}
+
+	err = worktreeAddError(rootDir, err)
+	want := "branch 'main' is already checked out at ./main, use 'cd ./main' to work on it"
+	if err.Error() != want {
+		t.Errorf("worktreeAddError() = %q, want %q", err.Error(), want)
+	}
+	if code := exitCode(err); code != exitConflict {
+		t.Errorf("exitCode() = %d, want %d", code, exitConflict)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain error", errors.New("boom"), exitError},
+		{"usage", &usageError{errors.New("accepts 1 arg(s), received 0")}, exitUsage},
+		{"branch exists", fmt.Errorf("failed: %w", git.ErrBranchExists), exitConflict},
+		{"invalid ref", withHint(git.ErrInvalidRef, "no such branch"), exitNotFound},
+		{"repository not found", git.ErrRemoteNotFound, exitNotFound},
+		{"network", fmt.Errorf("fetch: %w", git.ErrNetwork), exitRemote},
+		{"authentication", git.ErrAuth, exitRemote},
+		{"dirty worktree", withHint(git.ErrDirtyWorktree, "worktree has uncommitted changes"), exitDirty},
+		{"interrupted", fmt.Errorf("git fetch failed: %w", context.Canceled), exitInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Fetch remote branches for all worktrees",
+	Long: `Fetch origin (or every remote with --all) once for the whole repository, and
+report the remote branches that appeared or, with --prune, were deleted.
+
+All worktrees share the bare repository, so there is no need to fetch from
+each of them.
+
+Examples:
+  wtm fetch                 # Fetch origin
+  wtm fetch --all --prune   # Fetch every remote, dropping deleted branches`,
+	Args: cobra.NoArgs,
+	RunE: runFetch,
+}
+
+var (
+	fetchAll   bool
+	fetchPrune bool
+)
+
+func init() {
+	fetchCmd.Flags().BoolVar(&fetchAll, "all", false, "Fetch every remote, not just origin")
+	fetchCmd.Flags().BoolVarP(&fetchPrune, "prune", "p", false, "Remove remote-tracking branches deleted on the remote")
+	rootCmd.AddCommand(fetchCmd)
+}
+
+func runFetch(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	bareDir := config.GetBareDir(rootDir)
+
+	if refspecs, err := git.GetFetchRefspecs(bareDir, "origin"); err == nil && len(refspecs) == 0 {
+		ui.Warning("⚠ origin has no fetch refspec, so its remote-tracking branches are not updated. Run 'wtm doctor' to fix it")
+	}
+
+	before, err := git.RemoteBranches(bareDir)
+	if err != nil {
+		return err
+	}
+
+	if fetchAll {
+		ui.Info("Fetching all remotes...")
+	} else {
+		ui.Info("Fetching origin...")
+	}
+	if err := git.Fetch(bareDir, "origin", fetchAll, fetchPrune); err != nil {
+		return err
+	}
+
+	after, err := git.RemoteBranches(bareDir)
+	if err != nil {
+		return err
+	}
+
+	added, deleted := diffRemoteBranches(before, after)
+	for _, branch := range added {
+		ui.Plain("  + %s", branch)
+	}
+	for _, branch := range deleted {
+		ui.Plain("  - %s", branch)
+	}
+	ui.Success("✓ Fetched: %d new, %d deleted remote branch(es)", len(added), len(deleted))
+	return nil
+}
+
+// diffRemoteBranches returns the remote branches only in after (added) and
+// only in before (deleted), sorted
+func diffRemoteBranches(before, after map[string]string) (added, deleted []string) {
+	for branch := range after {
+		if _, ok := before[branch]; !ok {
+			added = append(added, branch)
+		}
+	}
+	for branch := range before {
+		if _, ok := after[branch]; !ok {
+			deleted = append(deleted, branch)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(deleted)
+	return added, deleted
+}
+
+// configureFetch gives a remote the standard fetch refspec, if it has none,
+// and fetches it so refs/remotes/<remote>/* exist. A non-empty branch limits
+// the refspec to that branch, as for a single-branch clone.
+func configureFetch(bareDir, remote, branch string) error {
+	refspecs, err := git.GetFetchRefspecs(bareDir, remote)
+	if err != nil {
+		return err
+	}
+	if len(refspecs) == 0 {
+		if err := git.AddFetchRefspec(bareDir, remote, branch); err != nil {
+			return fmt.Errorf("failed to configure fetching %s: %w", remote, err)
+		}
+	}
+	if err := git.Fetch(bareDir, remote, false, false); err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", remote, err)
+	}
+	return nil
+}
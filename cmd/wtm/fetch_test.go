@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestDoctorAndFetch tests that doctor adds the fetch refspec a plain bare
+// clone lacks, and that fetch reports new and deleted remote branches
+func TestDoctorAndFetch(t *testing.T) {
+	originDir := filepath.Join(t.TempDir(), "origin.git")
+	if err := git.InitBare(originDir); err != nil {
+		t.Fatalf("Failed to init origin: %v", err)
+	}
+	if err := git.CreateInitialBranch(originDir, "main"); err != nil {
+		t.Fatalf("Failed to create initial branch: %v", err)
+	}
+	runGitCmd(t, "--git-dir="+originDir, "branch", "feature", "main")
+
+	// A root created by an older version: a bare clone without refspec
+	rootDir := t.TempDir()
+	bareDir := config.GetBareDir(rootDir)
+	runGitCmd(t, "clone", "--quiet", "--bare", originDir, bareDir)
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	doctorDryRun = true
+	err := runDoctor(doctorCmd, nil)
+	doctorDryRun = false
+	if err == nil || !strings.Contains(err.Error(), "1 problem(s) found") {
+		t.Fatalf("runDoctor() --dry-run error = %v, want 1 problem", err)
+	}
+
+	if err := runDoctor(doctorCmd, nil); err != nil {
+		t.Fatalf("runDoctor() error = %v", err)
+	}
+	if refspecs, _ := git.GetFetchRefspecs(bareDir, "origin"); len(refspecs) != 1 || refspecs[0] != git.FetchRefspec("origin") {
+		t.Errorf("origin refspecs = %v, want %s", refspecs, git.FetchRefspec("origin"))
+	}
+	branches, _ := git.RemoteBranches(bareDir)
+	if _, ok := branches["origin/feature"]; !ok {
+		t.Errorf("remote branches = %v, want origin/feature after doctor", branches)
+	}
+	if err := runDoctor(doctorCmd, nil); err != nil {
+		t.Errorf("runDoctor() on a healthy root error = %v", err)
+	}
+
+	runGitCmd(t, "--git-dir="+originDir, "branch", "release", "main")
+	runGitCmd(t, "--git-dir="+originDir, "branch", "-D", "feature")
+
+	fetchPrune = true
+	defer func() { fetchPrune = false }()
+	if err := runFetch(fetchCmd, nil); err != nil {
+		t.Fatalf("runFetch() error = %v", err)
+	}
+
+	after, _ := git.RemoteBranches(bareDir)
+	added, deleted := diffRemoteBranches(branches, after)
+	if strings.Join(added, ",") != "origin/release" || strings.Join(deleted, ",") != "origin/feature" {
+		t.Errorf("after fetch: added %v, deleted %v, want [origin/release] and [origin/feature]", added, deleted)
+	}
+}
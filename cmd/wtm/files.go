@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/hook"
+	"github.com/vansdevcode/worktree-manager/internal/manifest"
+	"github.com/vansdevcode/worktree-manager/internal/template"
+	"github.com/vansdevcode/worktree-manager/internal/worktree"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+// sampleBranch is used by 'files check' outside a worktree when no --branch is given
+const sampleBranch = "feature/sample"
+
+var filesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "Inspect files generated from .worktree/files",
+	Long: `Inspect the templates in .worktree/files and the hooks in .worktree/hooks,
+and the files generated from them into each worktree.
+
+The generated files of each worktree are recorded in .worktree/state/<worktree>/files.json.`,
+}
+
+var filesCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Lint and dry-render all templates and hooks",
+	Long: `Parse every .tmpl file in .worktree/files and every hook in .worktree/hooks,
+render them in memory and report errors as file:line.
+
+Templates are rendered with missingkey=error, so indexing a datasource with a
+missing key is reported too. Nothing is written to disk.
+
+The data comes from --branch when given, from the current worktree when run
+inside one, and from the sample branch '` + sampleBranch + `' otherwise.
+
+Exits non-zero when a problem is found, so it can be used in a pre-commit hook.
+
+Examples:
+  wtm files check
+  wtm files check --branch feature/user-auth`,
+	Args: cobra.NoArgs,
+	RunE: runFilesCheck,
+}
+
+var filesStatusCmd = &cobra.Command{
+	Use:   "status [worktree]",
+	Short: "Show generated files that were edited or are out of date",
+	Long: `Compare the files generated into a worktree with what was recorded when
+they were generated, and with what their sources render to now.
+
+  modified   the file was edited in the worktree
+  deleted    the file was removed from the worktree
+  outdated   the template (or a datasource it reads) changed since
+  orphaned   the source was removed from .worktree/files
+  new        the source was added after the worktree was created
+
+Defaults to the current worktree.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFilesStatus,
+}
+
+var filesCleanCmd = &cobra.Command{
+	Use:   "clean [worktree]",
+	Short: "Remove generated files from a worktree",
+	Long: `Remove the files generated from .worktree/files from a worktree.
+
+Files edited since they were generated are kept unless --force is given.
+Defaults to the current worktree.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFilesClean,
+}
+
+var (
+	filesCheckBranch string
+	filesCleanForce  bool
+)
+
+func init() {
+	filesCheckCmd.Flags().StringVar(&filesCheckBranch, "branch", "", "Branch name to render templates with")
+	filesCleanCmd.Flags().BoolVarP(&filesCleanForce, "force", "f", false, "Also remove files edited since they were generated")
+	filesCmd.AddCommand(filesCheckCmd)
+	filesCmd.AddCommand(filesStatusCmd)
+	filesCmd.AddCommand(filesCleanCmd)
+	rootCmd.AddCommand(filesCmd)
+}
+
+func runFilesCheck(cmd *cobra.Command, args []string) error {
+	// Find root directory
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+	data, err := checkTemplateData(rootDir)
+	if err != nil {
+		return err
+	}
+
+	ui.Info("Checking templates and hooks for branch '%s'...", data.Branch)
+
+	// Templates
+	problems, err := template.CheckTemplates(config.GetFilesDir(rootDir), data, settings.Datasources)
+	if err != nil {
+		return fmt.Errorf("failed to check templates: %w", err)
+	}
+	for i := range problems {
+		problems[i].File = filepath.Join(".worktree", "files", problems[i].File)
+	}
+
+	// Hooks
+	hookProblems, err := checkHooks(rootDir, data, settings.Datasources)
+	if err != nil {
+		return fmt.Errorf("failed to check hooks: %w", err)
+	}
+	problems = append(problems, hookProblems...)
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			ui.Plain("%s", problem)
+		}
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+
+	ui.Success("✓ All templates and hooks rendered successfully")
+	return nil
+}
+
+func runFilesStatus(cmd *cobra.Command, args []string) error {
+	// Find root directory
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+
+	generated, err := manifest.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+
+	branch := generated.Branch
+	if branch == "" {
+		if branch, err = git.GetWorktreeBranch(worktreePath); err != nil {
+			return fmt.Errorf("failed to determine branch name: %w", err)
+		}
+	}
+
+	data := template.TemplateData{
+		Branch:        branch,
+		Directory:     worktreePath,
+		RootDirectory: rootDir,
+		SparseProfile: sparseProfile(rootDir, worktreePath),
+	}
+	statuses, err := generated.Status(worktreePath, config.GetFilesDir(rootDir), data, settings.Datasources)
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		ui.Info("No generated files in %s", filepath.Base(worktreePath))
+		return nil
+	}
+
+	changed := 0
+	for _, status := range statuses {
+		if status.Clean() {
+			continue
+		}
+		changed++
+		ui.Plain("  %-20s %s (from %s)", strings.Join(statusLabels(status), ", "), status.Path, status.Source)
+		if status.Err != nil {
+			ui.Plain("  %-20s %v", "", status.Err)
+		}
+	}
+
+	if changed == 0 {
+		ui.Success("✓ All %d generated files are up to date", len(statuses))
+	}
+	return nil
+}
+
+// statusLabels returns the labels describing a generated file's status
+func statusLabels(status manifest.Status) []string {
+	var labels []string
+	if status.New {
+		labels = append(labels, "new")
+	}
+	if status.Deleted {
+		labels = append(labels, "deleted")
+	}
+	if status.Modified {
+		labels = append(labels, "modified")
+	}
+	if status.Outdated {
+		labels = append(labels, "outdated")
+	}
+	if status.Orphaned {
+		labels = append(labels, "orphaned")
+	}
+	if status.Err != nil {
+		labels = append(labels, "render error")
+	}
+	return labels
+}
+
+func runFilesClean(cmd *cobra.Command, args []string) error {
+	// Find root directory
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+
+	generated, err := manifest.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+
+	removed, kept, cleanErr := manifest.Clean(worktreePath, generated, filesCleanForce)
+	generated.Files = kept
+	if err := recordGenerated(rootDir, worktreePath, generated); err != nil {
+		return err
+	}
+	if cleanErr != nil {
+		return cleanErr
+	}
+
+	for _, path := range removed {
+		ui.Info("  removed %s", path)
+	}
+	for _, entry := range kept {
+		ui.Warning("⚠ Kept %s: edited since it was generated (use --force to remove)", entry.Path)
+	}
+	ui.Success("✓ Removed %d generated file(s)", len(removed))
+	return nil
+}
+
+// worktreeArg resolves the optional worktree argument, defaulting to the current worktree
+func worktreeArg(rootDir string, args []string) (string, error) {
+	if len(args) == 0 {
+		worktreePath, err := currentWorktree(rootDir)
+		if err != nil {
+			return "", fmt.Errorf("not inside a worktree, pass the worktree directory")
+		}
+		return worktreePath, nil
+	}
+
+	worktreePath := resolveWorktreePath(rootDir, args[0])
+	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
+		return "", fmt.Errorf("directory '%s' does not exist", args[0])
+	}
+	return worktreePath, nil
+}
+
+// checkTemplateData returns the data to render templates with: --branch,
+// the current worktree, or the sample branch
+func checkTemplateData(rootDir string) (template.TemplateData, error) {
+	data := template.TemplateData{RootDirectory: rootDir}
+
+	if filesCheckBranch == "" {
+		if worktreePath, err := currentWorktree(rootDir); err == nil {
+			branch, err := git.GetWorktreeBranch(worktreePath)
+			if err != nil {
+				return data, fmt.Errorf("failed to determine branch of current worktree: %w (use --branch)", err)
+			}
+			data.Branch = branch
+			data.Directory = worktreePath
+			data.SparseProfile = sparseProfile(rootDir, worktreePath)
+			return data, nil
+		}
+	}
+
+	data.Branch = filesCheckBranch
+	if data.Branch == "" {
+		data.Branch = sampleBranch
+	}
+	data.Directory = filepath.Join(rootDir, worktree.GenerateWorktreeDirectory(data.Branch))
+	return data, nil
+}
+
+// checkHooks renders every hook in .worktree/hooks in memory and returns the problems found
+func checkHooks(rootDir string, data template.TemplateData, sources map[string]config.Datasource) ([]template.Problem, error) {
+	entries, err := os.ReadDir(config.GetHooksDir(rootDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	hookData := hook.TemplateData{
+		Branch:        data.Branch,
+		Directory:     data.Directory,
+		RootDirectory: data.RootDirectory,
+		SparseProfile: data.SparseProfile,
+	}
+
+	var problems []template.Problem
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		hookPath := filepath.Join(config.GetHooksDir(rootDir), entry.Name())
+		file := filepath.Join(".worktree", "hooks", entry.Name())
+
+		script, err := hook.Render(hookPath, hookData, sources, true)
+		if err != nil {
+			problems = append(problems, template.ProblemFromError(file, err))
+			continue
+		}
+		if interpreter, _ := hook.ExtractShebang(script); interpreter == "" {
+			problems = append(problems, template.Problem{File: file, Line: 1, Message: "no shebang found"})
+			continue
+		}
+
+		if info, err := entry.Info(); err == nil && info.Mode()&0111 == 0 {
+			ui.Warning("⚠ %s is not executable and will be skipped", file)
+		}
+	}
+
+	return problems, nil
+}
+
+// currentWorktree returns the worktree directory (direct child of the root)
+// containing the current working directory
+func currentWorktree(rootDir string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	relPath, err := filepath.Rel(rootDir, cwd)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return "", fmt.Errorf("not inside a worktree")
+	}
+
+	name := strings.Split(relPath, string(filepath.Separator))[0]
+	if name == ".bare" || name == ".worktree" {
+		return "", fmt.Errorf("not inside a worktree")
+	}
+
+	return filepath.Join(rootDir, name), nil
+}
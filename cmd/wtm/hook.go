@@ -20,6 +20,7 @@ The script has access to template variables:
   - .Branch: The branch name (e.g., "feature/user-auth")
   - .Directory: Absolute path to worktree directory
   - .RootDirectory: Absolute path to repository root
+  - .SparseProfile: Sparse-checkout profile of the worktree, empty for a full checkout
 
 And all gomplate functions (https://docs.gomplate.ca/functions/):
   - strings.Slug: Convert to URL-friendly slug
@@ -7,9 +7,9 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
 	"github.com/vansdevcode/worktree-manager/internal/git"
 	"github.com/vansdevcode/worktree-manager/internal/hook"
-	"github.com/vansdevcode/worktree-manager/internal/template"
 	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
 
@@ -28,19 +28,29 @@ Examples:
   wtm init myorg/myrepo my-project
   wtm init https://github.com/myorg/myrepo.git
   wtm init myorg/myrepo --new
-  wtm init myorg/myrepo --no-hooks`,
+  wtm init myorg/myrepo --no-hooks
+  wtm init myorg/monorepo --filter=blob:none      # Download file contents on demand
+  wtm init myorg/monorepo --depth 1 --single-branch`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: runInit,
 }
 
 var (
-	initNew     bool
-	initNoHooks bool
+	initNew          bool
+	initNoHooks      bool
+	initDepth        int
+	initFilter       string
+	initSingleBranch bool
+	initBranch       string
 )
 
 func init() {
 	initCmd.Flags().BoolVar(&initNew, "new", false, "Create a new repository instead of cloning")
 	initCmd.Flags().BoolVar(&initNoHooks, "no-hooks", false, "Skip running post-create hooks")
+	initCmd.Flags().IntVar(&initDepth, "depth", 0, "Create a shallow clone with this many commits of history")
+	initCmd.Flags().StringVar(&initFilter, "filter", "", "Create a partial clone, e.g. blob:none or tree:0")
+	initCmd.Flags().BoolVar(&initSingleBranch, "single-branch", false, "Only clone one branch (--branch, or the default branch)")
+	initCmd.Flags().StringVar(&initBranch, "branch", "", "Branch to check out instead of the remote's default branch")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -56,9 +66,17 @@ func runInit(cmd *cobra.Command, args []string) error {
 		directory = strings.TrimSuffix(directory, ".git")
 	}
 
+	cloneOptions := git.CloneOptions{Depth: initDepth, Filter: initFilter, SingleBranch: initSingleBranch, Branch: initBranch}
+	if initNew && cloneOptions != (git.CloneOptions{}) {
+		return &usageError{fmt.Errorf("--depth, --filter, --single-branch and --branch only apply when cloning, not with --new")}
+	}
+	if initDepth < 0 {
+		return &usageError{fmt.Errorf("--depth must be a positive number of commits")}
+	}
+
 	// Check if directory already exists
 	if _, err := os.Stat(directory); err == nil {
-		return fmt.Errorf("directory '%s' already exists", directory)
+		return withHint(git.ErrPathExists, "directory '%s' already exists", directory)
 	}
 
 	// Create directory
@@ -94,8 +112,27 @@ func runInit(cmd *cobra.Command, args []string) error {
 		repoURL := git.ConvertGitHubFormat(repo)
 		ui.Info("Cloning repository: %s", repoURL)
 
-		if err := git.CloneBare(repoURL, bareDir); err != nil {
-			return fmt.Errorf("failed to clone repository: %w", err)
+		if mode := cloneMode(cloneOptions.Depth > 0, cloneOptions.Depth, cloneOptions.Filter, cloneOptions.SingleBranch); mode != "full" {
+			ui.Info("  Clone mode: %s", mode)
+		}
+
+		if err := git.CloneBare(repoURL, bareDir, cloneOptions); err != nil {
+			return cloneError(repoURL, err)
+		}
+
+		// git clone --bare does not set up remote-tracking branches. A
+		// single-branch clone keeps fetching only its branch.
+		fetchBranch := ""
+		if initSingleBranch {
+			branch, err := git.GetDefaultBranch(bareDir)
+			if err != nil {
+				return fmt.Errorf("failed to get default branch: %w", err)
+			}
+			fetchBranch = branch
+		}
+		ui.Info("Fetching remote branches...")
+		if err := configureFetch(bareDir, "origin", fetchBranch); err != nil {
+			return err
 		}
 	}
 
@@ -110,22 +147,14 @@ func runInit(cmd *cobra.Command, args []string) error {
 	ui.Info("Creating worktree for default branch: %s", defaultBranch)
 
 	if err := git.AddWorktree(bareDir, defaultBranch, worktreePath, ""); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+		return worktreeAddError(directory, err)
 	}
 
-	// Process files
-	filesDir := filepath.Join(worktreeDir, "files")
-	if _, err := os.Stat(filesDir); err == nil {
-		ui.Info("Processing files...")
-		data := template.TemplateData{
-			Branch:        defaultBranch,
-			Directory:     worktreePath,
-			RootDirectory: directory,
-		}
-		if err := template.ProcessTemplates(filesDir, worktreePath, data); err != nil {
-			ui.Warning("Failed to process files: %v", err)
-		}
+	settings, err := config.LoadSettings(directory)
+	if err != nil {
+		return err
 	}
+	setupWorktree(directory, worktreePath, defaultBranch, settings)
 
 	// Run post-create hook
 	if !initNoHooks {
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestInitCommand_ShallowSingleBranch tests a shallow single-branch clone,
+// and that add fetches a branch it left out on demand
+func TestInitCommand_ShallowSingleBranch(t *testing.T) {
+	originDir := filepath.Join(t.TempDir(), "origin.git")
+	if err := git.InitBare(originDir); err != nil {
+		t.Fatalf("Failed to init origin: %v", err)
+	}
+	if err := git.CreateInitialBranch(originDir, "main"); err != nil {
+		t.Fatalf("Failed to create initial branch: %v", err)
+	}
+	tree := gitOutput(t, "--git-dir="+originDir, "rev-parse", "main^{tree}")
+	second := gitOutput(t, "--git-dir="+originDir, "-c", "user.name=Test", "-c", "user.email=test@example.com",
+		"commit-tree", tree, "-p", "main", "-m", "Second commit")
+	runGitCmd(t, "--git-dir="+originDir, "update-ref", "refs/heads/main", second)
+	runGitCmd(t, "--git-dir="+originDir, "branch", "develop", "main")
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	initDepth, initSingleBranch, initNoHooks = 1, true, true
+	defer func() { initDepth, initSingleBranch, initNoHooks = 0, false, false }()
+
+	if err := runInit(initCmd, []string{"file://" + originDir, "app"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	rootDir, _ := filepath.Abs("app")
+	bareDir := config.GetBareDir(rootDir)
+
+	if mode := describeClone(bareDir); mode != "shallow (depth 1), single-branch" {
+		t.Errorf("describeClone() = %q, want shallow (depth 1), single-branch", mode)
+	}
+	if exists, _ := git.BranchExists(bareDir, "develop"); exists {
+		t.Error("develop should not be cloned")
+	}
+
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	addNoHooks = true
+	defer func() { addNoHooks = false }()
+	if err := runAdd(addCmd, []string{"develop"}); err != nil {
+		t.Fatalf("runAdd(develop) error = %v", err)
+	}
+
+	if upstream, _ := git.GetUpstream(bareDir, "develop"); upstream != "origin/develop" {
+		t.Errorf("upstream of develop = %q, want origin/develop", upstream)
+	}
+	refspecs, _ := git.GetFetchRefspecs(bareDir, "origin")
+	if !slices.Contains(refspecs, "+refs/heads/develop:refs/remotes/origin/develop") {
+		t.Errorf("origin refspecs = %v, want develop added", refspecs)
+	}
+	if !git.IsShallow(bareDir) {
+		t.Error("fetching develop should keep the clone shallow")
+	}
+	if count, err := git.CountCommits(bareDir, "refs/heads/develop"); err != nil || count != 1 {
+		t.Errorf("develop history = %d commit(s), %v, want 1", count, err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock [worktree]",
+	Short: "Lock a worktree against removal",
+	Long: `Lock a worktree (the current one by default) with 'git worktree lock'.
+
+A locked worktree is kept by 'wtm rm', 'wtm pr prune' and 'git worktree
+prune', even when its directory is missing, until it is unlocked or removed
+with --force --force. Lock worktrees on removable or network drives, and
+long-running experiments.
+
+Examples:
+  wtm lock experiment --reason "benchmark running until Friday"
+  wtm lock usb-wt --reason "on the USB drive"
+  wtm unlock experiment`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLock,
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock [worktree]",
+	Short: "Unlock a locked worktree",
+	Long:  `Unlock a worktree (the current one by default) locked with 'wtm lock'.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runUnlock,
+}
+
+var lockReason string
+
+func init() {
+	lockCmd.Flags().StringVarP(&lockReason, "reason", "r", "", "Why the worktree is locked, shown by 'wtm ls' and when removal is refused")
+	rootCmd.AddCommand(lockCmd)
+	rootCmd.AddCommand(unlockCmd)
+}
+
+func runLock(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(worktreePath)
+
+	locked, reason, err := git.WorktreeLock(worktreePath)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return withHint(git.ErrWorktreeLocked, "worktree '%s' is already %s, unlock it first to change the reason", name, lockStatus(reason))
+	}
+
+	if err := git.LockWorktree(config.GetBareDir(rootDir), worktreePath, lockReason); err != nil {
+		return err
+	}
+	ui.Success("✓ Locked %s", name)
+	return nil
+}
+
+func runUnlock(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(worktreePath)
+
+	locked, _, err := git.WorktreeLock(worktreePath)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		ui.Info("%s is not locked", name)
+		return nil
+	}
+
+	if err := git.UnlockWorktree(config.GetBareDir(rootDir), worktreePath); err != nil {
+		return err
+	}
+	ui.Success("✓ Unlocked %s", name)
+	return nil
+}
+
+// lockStatus describes a lock, e.g., "locked (on the USB drive)"
+func lockStatus(reason string) string {
+	if reason == "" {
+		return "locked"
+	}
+	return fmt.Sprintf("locked (%s)", reason)
+}
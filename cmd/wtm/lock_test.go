@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestLock tests that rm refuses locked worktrees unless forced twice
+func TestLock(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	for _, name := range []string{"usb", "experiment"} {
+		if err := git.AddWorktree(bareDir, name, filepath.Join(rootDir, name), "main"); err != nil {
+			t.Fatalf("Failed to create worktree: %v", err)
+		}
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	lockReason = "on the USB drive"
+	defer func() { lockReason = "" }()
+	for _, name := range []string{"usb", "experiment"} {
+		if err := runLock(lockCmd, []string{name}); err != nil {
+			t.Fatalf("runLock(%s) error = %v", name, err)
+		}
+	}
+	if locked, reason, err := git.WorktreeLock(filepath.Join(rootDir, "usb")); err != nil || !locked || reason != "on the USB drive" {
+		t.Errorf("WorktreeLock() = %v, %q, %v, want locked on the USB drive", locked, reason, err)
+	}
+	if err := runLock(lockCmd, []string{"usb"}); !errors.Is(err, git.ErrWorktreeLocked) {
+		t.Errorf("runLock() on a locked worktree error = %v, want ErrWorktreeLocked", err)
+	}
+
+	rmNoHooks = true
+	defer func() { rmNoHooks, rmForce = false, 0 }()
+	for _, force := range []int{0, 1} {
+		rmForce = force
+		err := runRm(rmCmd, []string{"usb"})
+		if exitCode(err) != exitConflict || !strings.Contains(err.Error(), "locked (on the USB drive)") {
+			t.Errorf("runRm() with force %d error = %v, want a locked worktree error", force, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "usb")); err != nil {
+		t.Fatal("a locked worktree must not be removed")
+	}
+
+	rmForce = 2
+	if err := runRm(rmCmd, []string{"usb"}); err != nil {
+		t.Errorf("runRm(--force --force) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "usb")); !os.IsNotExist(err) {
+		t.Error("--force --force should remove a locked worktree")
+	}
+
+	if err := runUnlock(unlockCmd, []string{"experiment"}); err != nil {
+		t.Fatalf("runUnlock() error = %v", err)
+	}
+	rmForce = 0
+	if err := runRm(rmCmd, []string{"experiment"}); err != nil {
+		t.Errorf("runRm() after unlock error = %v", err)
+	}
+}
+
+// TestWithLockStatus tests that ls shows the reason of a lock
+func TestWithLockStatus(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	for _, name := range []string{"usb", "experiment"} {
+		if err := git.AddWorktree(bareDir, name, filepath.Join(rootDir, name), "main"); err != nil {
+			t.Fatalf("Failed to create worktree: %v", err)
+		}
+	}
+	if err := git.LockWorktree(bareDir, filepath.Join(rootDir, "usb"), "on the USB drive"); err != nil {
+		t.Fatalf("LockWorktree() error = %v", err)
+	}
+
+	list, err := git.ListWorktrees(bareDir)
+	if err != nil {
+		t.Fatalf("ListWorktrees() error = %v", err)
+	}
+	records, err := git.WorktreeRecords(bareDir)
+	if err != nil {
+		t.Fatalf("WorktreeRecords() error = %v", err)
+	}
+
+	for _, line := range strings.Split(withLockStatus(list, records), "\n") {
+		switch {
+		case strings.Contains(line, "[usb]") && !strings.HasSuffix(line, "] locked (on the USB drive)"):
+			t.Errorf("usb line = %q, want its lock reason", line)
+		case strings.Contains(line, "[experiment]") && strings.Contains(line, "locked"):
+			t.Errorf("experiment line = %q, want no lock", line)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/vansdevcode/worktree-manager/internal/config"
@@ -31,7 +33,32 @@ func runLs(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to list worktrees: %w", err)
 	}
+	records, err := git.WorktreeRecords(bareDir)
+	if err != nil {
+		return fmt.Errorf("failed to list worktrees: %w", err)
+	}
 
-	ui.Plain(output)
+	ui.Plain(withLockStatus(output, records))
 	return nil
 }
+
+// withLockStatus replaces git's bare "locked" marker in a worktree list with
+// the lock's reason, e.g., "locked (on the USB drive)"
+func withLockStatus(list string, records []git.WorktreeRecord) string {
+	lines := strings.Split(list, "\n")
+	for i, line := range lines {
+		for _, record := range records {
+			if !strings.HasPrefix(line, record.Path+" ") {
+				continue
+			}
+			locked, reason, err := record.Lock()
+			if err != nil {
+				ui.Warning("⚠ Could not read the lock of %s: %v", filepath.Base(record.Path), err)
+			} else if locked {
+				lines[i] = strings.TrimSuffix(line, " locked") + " " + lockStatus(reason)
+			}
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var mrCmd = &cobra.Command{
+	Use:   "mr <number> [directory]",
+	Short: "Checkout a GitLab merge request (alias for 'add mr/<number>')",
+	Long: `Checkout a GitLab merge request by number. This is a convenience alias for 'wtm add mr/<number>'.
+
+Examples:
+  wtm mr 45            # Checkout MR !45 to mr-45/
+  wtm mr 45 my-dir     # Checkout MR !45 to my-dir/`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runMr,
+}
+
+func runMr(cmd *cobra.Command, args []string) error {
+	// Parse MR number
+	mrNumber, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid MR number: %s", args[0])
+	}
+
+	// Validate MR number
+	if mrNumber <= 0 {
+		return fmt.Errorf("MR number must be positive")
+	}
+
+	// Build add command arguments
+	addArgs := []string{fmt.Sprintf("mr/%d", mrNumber)}
+	if len(args) > 1 {
+		addArgs = append(addArgs, args[1])
+	}
+
+	// Call add command
+	return runAdd(cmd, addArgs)
+}
@@ -2,23 +2,87 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/internal/pr"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
 
 var prCmd = &cobra.Command{
 	Use:   "pr <number> [directory]",
 	Short: "Checkout a pull request (alias for 'add pr/<number>')",
 	Long: `Checkout a pull request by number. This is a convenience alias for 'wtm add pr/<number>'.
+If the pull request is already checked out, its worktree is updated instead.
 
 Examples:
   wtm pr 123           # Checkout PR #123 to pr-123/
-  wtm pr 123 my-dir    # Checkout PR #123 to my-dir/`,
+  wtm pr 123 my-dir    # Checkout PR #123 to my-dir/
+  wtm pr update        # Update the current PR worktree to the latest head
+  wtm pr reviewed      # Remember the current head as reviewed
+  wtm pr since-review  # Show what changed since the last review`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: runPr,
 }
 
+var prUpdateCmd = &cobra.Command{
+	Use:   "update [worktree]",
+	Short: "Update a pull request worktree to the latest head",
+	Long: `Fetch the latest head of the pull request (or merge request) checked out in a
+worktree and move the worktree's branch to it.
+
+A head that only gained commits is fast-forwarded. A head that was rewritten
+(e.g., force-pushed) is hard-reset to, after checking that no local commits
+or uncommitted changes would be lost; use --force to reset anyway. The
+previous head, and any uncommitted changes, are first backed up to refs under
+refs/wtm/backup/.
+
+Defaults to the current worktree.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPrUpdate,
+}
+
+var prReviewedCmd = &cobra.Command{
+	Use:   "reviewed [worktree]",
+	Short: "Record the checked out head of a pull request as reviewed",
+	Long: `Record the commit checked out in a pull request (or merge request) worktree as
+the last reviewed revision, for 'wtm pr since-review'.
+
+Defaults to the current worktree.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPrReviewed,
+}
+
+var prSinceReviewCmd = &cobra.Command{
+	Use:   "since-review [worktree]",
+	Short: "Show what changed in a pull request since the last review",
+	Long: `Fetch the latest head of the pull request (or merge request) checked out in a
+worktree and show 'git range-diff' between the series last marked with
+'wtm pr reviewed' and the new one, both taken against the PR base. This works
+across force-pushes and rebases. The worktree itself is left untouched; use
+'wtm pr update' to check out the new head.
+
+Defaults to the current worktree.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPrSinceReview,
+}
+
+var prUpdateForce bool
+
+func init() {
+	prUpdateCmd.Flags().BoolVarP(&prUpdateForce, "force", "f", false, "Reset even if local commits or uncommitted changes would be lost")
+	prCmd.AddCommand(prUpdateCmd)
+	prCmd.AddCommand(prReviewedCmd)
+	prCmd.AddCommand(prSinceReviewCmd)
+}
+
 func runPr(cmd *cobra.Command, args []string) error {
 	// Parse PR number
 	prNumber, err := strconv.Atoi(args[0])
@@ -40,3 +104,284 @@ func runPr(cmd *cobra.Command, args []string) error {
 	// Call add command
 	return runAdd(cmd, addArgs)
 }
+
+func runPrUpdate(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+
+	return updateChange(rootDir, worktreePath, settings, prUpdateForce)
+}
+
+// updateChange moves the branch of a PR/MR worktree to the latest head of the change
+func updateChange(rootDir, worktreePath string, settings *config.Settings, force bool) error {
+	bareDir := config.GetBareDir(rootDir)
+	name := filepath.Base(worktreePath)
+
+	meta, err := metadata.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+	change := worktreeChange(meta, name)
+	if change == nil {
+		return fmt.Errorf("worktree '%s' is not a pull request checkout", name)
+	}
+	label := changeLabel(change.Kind, change.Number)
+
+	branch, err := git.GetWorktreeBranch(worktreePath)
+	if err != nil {
+		return fmt.Errorf("failed to determine branch name: %w", err)
+	}
+	oldHead, err := git.ResolveCommit(bareDir, "refs/heads/"+branch)
+	if err != nil {
+		return err
+	}
+
+	ui.Info("Fetching %s...", label)
+	newHead, err := pr.FetchHead(bareDir, change.Kind, change.Number, settings.Forges)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", label, err)
+	}
+
+	if newHead == oldHead {
+		ui.Success("✓ %s is up to date (%s)", label, shortSHA(newHead))
+		return saveChangeHead(rootDir, worktreePath, meta, change, newHead)
+	}
+
+	added, err := git.CountCommits(bareDir, newHead, oldHead)
+	if err != nil {
+		return err
+	}
+
+	// A head that only gained commits can be fast-forwarded without losing anything
+	if git.IsAncestor(bareDir, oldHead, newHead) {
+		if err := git.FastForward(worktreePath, newHead); err != nil {
+			return err
+		}
+		ui.Success("✓ Fast-forwarded %s: %d new commit(s)", label, added)
+		return saveChangeHead(rootDir, worktreePath, meta, change, newHead)
+	}
+
+	// The head was rewritten: find what a reset would lose. Commits up to the
+	// recorded head belong to the old version of the change, not to us.
+	exclude := []string{newHead}
+	if change.Head != "" && git.IsAncestor(bareDir, change.Head, oldHead) {
+		exclude = append(exclude, change.Head)
+	}
+	local, err := git.CountCommits(bareDir, oldHead, exclude...)
+	if err != nil {
+		return err
+	}
+	dirty, err := git.HasUncommittedChanges(worktreePath)
+	if err != nil {
+		return fmt.Errorf("failed to check for uncommitted changes: %w", err)
+	}
+	if (local > 0 || dirty) && !force {
+		return fmt.Errorf("%s was rewritten and '%s' has %s that would be lost, push or stash them first or use --force (a backup ref is kept)", label, name, describeLocalWork(local, dirty))
+	}
+
+	// Back up the previous head and uncommitted changes before resetting
+	backupRef := fmt.Sprintf("refs/wtm/backup/%s/%s", name, time.Now().Format("20060102-150405"))
+	if dirty {
+		stash, err := git.StashCreate(worktreePath)
+		if err != nil {
+			return err
+		}
+		if stash != "" {
+			if err := git.UpdateRef(bareDir, backupRef+"-changes", stash); err != nil {
+				return err
+			}
+			ui.Info("  Uncommitted changes backed up to %s-changes", backupRef)
+		}
+	}
+	if err := git.UpdateRef(bareDir, backupRef, oldHead); err != nil {
+		return err
+	}
+
+	removed, err := git.CountCommits(bareDir, oldHead, newHead)
+	if err != nil {
+		return err
+	}
+	if err := git.ResetHard(worktreePath, newHead); err != nil {
+		return err
+	}
+
+	ui.Success("✓ Reset %s to the new head: %d commit(s) added, %d removed", label, added, removed)
+	ui.Info("  Previous head backed up to %s", backupRef)
+	return saveChangeHead(rootDir, worktreePath, meta, change, newHead)
+}
+
+func runPrReviewed(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+
+	return markReviewed(rootDir, worktreePath)
+}
+
+// markReviewed records the commit checked out in a PR/MR worktree as reviewed
+func markReviewed(rootDir, worktreePath string) error {
+	name := filepath.Base(worktreePath)
+
+	meta, err := metadata.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+	change := worktreeChange(meta, name)
+	if change == nil {
+		return fmt.Errorf("worktree '%s' is not a pull request checkout", name)
+	}
+
+	branch, err := git.GetWorktreeBranch(worktreePath)
+	if err != nil {
+		return fmt.Errorf("failed to determine branch name: %w", err)
+	}
+	head, err := git.ResolveCommit(config.GetBareDir(rootDir), "refs/heads/"+branch)
+	if err != nil {
+		return err
+	}
+
+	change.Reviewed = head
+	meta.Change = change
+	if err := metadata.Save(rootDir, worktreePath, meta); err != nil {
+		return err
+	}
+	ui.Success("✓ Marked %s as reviewed at %s", changeLabel(change.Kind, change.Number), shortSHA(head))
+	return nil
+}
+
+func runPrSinceReview(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+
+	return showSinceReview(rootDir, worktreePath, settings, cmd.OutOrStdout())
+}
+
+// showSinceReview fetches the latest head of a PR/MR and writes the
+// range-diff from the reviewed series to it
+func showSinceReview(rootDir, worktreePath string, settings *config.Settings, w io.Writer) error {
+	bareDir := config.GetBareDir(rootDir)
+	name := filepath.Base(worktreePath)
+
+	meta, err := metadata.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+	change := worktreeChange(meta, name)
+	if change == nil {
+		return fmt.Errorf("worktree '%s' is not a pull request checkout", name)
+	}
+	label := changeLabel(change.Kind, change.Number)
+	if change.Reviewed == "" {
+		return fmt.Errorf("no review recorded for %s, run 'wtm pr reviewed' after reviewing it", label)
+	}
+	if _, err := git.ResolveCommit(bareDir, change.Reviewed); err != nil {
+		return fmt.Errorf("reviewed commit %s of %s is no longer in the repository", shortSHA(change.Reviewed), label)
+	}
+
+	ui.Info("Fetching %s...", label)
+	newHead, err := pr.FetchHead(bareDir, change.Kind, change.Number, settings.Forges)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", label, err)
+	}
+	if newHead == change.Reviewed {
+		ui.Success("✓ %s has not changed since the review (%s)", label, shortSHA(newHead))
+		return nil
+	}
+
+	// Both series are taken against the current base, so a rebase onto a
+	// newer base doesn't show the base's commits
+	baseRef := change.BaseRef
+	if baseRef == "" {
+		if baseRef, err = git.GetDefaultBranch(bareDir); err != nil {
+			return fmt.Errorf("failed to determine the base branch of %s: %w", label, err)
+		}
+	}
+	base, err := git.FetchCommit(bareDir, "origin", "refs/heads/"+baseRef)
+	if err != nil {
+		return fmt.Errorf("failed to fetch base branch '%s': %w", baseRef, err)
+	}
+
+	ui.Info("Changes in %s since review: %s → %s (against %s)", label, shortSHA(change.Reviewed), shortSHA(newHead), baseRef)
+	return git.RangeDiff(bareDir, w, base, change.Reviewed, newHead)
+}
+
+// changeDirectory matches the default directory of a PR/MR checkout
+var changeDirectory = regexp.MustCompile(`^(pr|mr)-(\d+)$`)
+
+// worktreeChange returns the change recorded for a worktree. Worktrees created
+// before metadata was recorded are recognised by their default directory name.
+func worktreeChange(meta *metadata.Metadata, name string) *metadata.Change {
+	if meta.Change != nil {
+		return meta.Change
+	}
+	matches := changeDirectory.FindStringSubmatch(name)
+	if matches == nil {
+		return nil
+	}
+	number, _ := strconv.Atoi(matches[2])
+	return &metadata.Change{Kind: matches[1], Number: number}
+}
+
+// saveChangeHead records the head a change worktree was last updated to
+func saveChangeHead(rootDir, worktreePath string, meta *metadata.Metadata, change *metadata.Change, head string) error {
+	change.Head = head
+	meta.Change = change
+	return metadata.Save(rootDir, worktreePath, meta)
+}
+
+// changeLabel returns how a change is referred to: "PR #123" or "MR !45"
+func changeLabel(kind string, number int) string {
+	if kind == "mr" {
+		return fmt.Sprintf("MR !%d", number)
+	}
+	return fmt.Sprintf("PR #%d", number)
+}
+
+// describeLocalWork describes local commits and uncommitted changes for messages
+func describeLocalWork(commits int, dirty bool) string {
+	switch {
+	case commits > 0 && dirty:
+		return fmt.Sprintf("%d local commit(s) and uncommitted changes", commits)
+	case commits > 0:
+		return fmt.Sprintf("%d local commit(s)", commits)
+	}
+	return "uncommitted changes"
+}
+
+// shortSHA abbreviates a commit hash for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
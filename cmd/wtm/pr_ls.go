@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var prLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List open pull requests",
+	Long: `List the open pull requests (or merge requests) of the origin repository,
+with their author, draft state and requested reviewers, and the worktree they
+are checked out in, if any.
+
+Examples:
+  wtm pr ls                      # All open pull requests
+  wtm pr ls --mine               # Opened by you
+  wtm pr ls --review-requested   # Awaiting your review
+  wtm pr ls --label bug          # Labelled bug (repeat for several labels)
+  wtm pr ls --review-requested --checkout  # Create worktrees for all of them`,
+	Args: cobra.NoArgs,
+	RunE: runPrLs,
+}
+
+var (
+	prLsMine            bool
+	prLsReviewRequested bool
+	prLsLabels          []string
+	prLsCheckout        bool
+)
+
+func init() {
+	prLsCmd.Flags().BoolVar(&prLsMine, "mine", false, "Only pull requests opened by you")
+	prLsCmd.Flags().BoolVar(&prLsReviewRequested, "review-requested", false, "Only pull requests awaiting your review")
+	prLsCmd.Flags().StringSliceVar(&prLsLabels, "label", nil, "Only pull requests with this label (repeatable)")
+	prLsCmd.Flags().BoolVar(&prLsCheckout, "checkout", false, "Create worktrees for the listed pull requests")
+	prCmd.AddCommand(prLsCmd)
+}
+
+func runPrLs(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+
+	f, repo, err := forge.ForRemote(config.GetBareDir(rootDir), "origin", settings.Forges)
+	if err != nil {
+		return err
+	}
+	kind := "pr"
+	if f.Kind() == forge.GitLab {
+		kind = "mr"
+	}
+
+	opts := forge.ListOptions{Labels: prLsLabels}
+	if prLsMine {
+		opts.Author = forge.Me
+	}
+	if prLsReviewRequested {
+		opts.ReviewRequested = forge.Me
+	}
+	changes, err := f.ListChanges(repo, opts)
+	if err != nil {
+		return fmt.Errorf("failed to list pull requests of %s: %w", repo, err)
+	}
+	if len(changes) == 0 {
+		ui.Info("No open pull requests match")
+		return nil
+	}
+
+	worktrees, err := changeWorktrees(rootDir)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		ui.Plain(formatChange(change, worktrees[changeKey(kind, change.Number)]))
+	}
+
+	if !prLsCheckout {
+		return nil
+	}
+
+	// Check out the listed changes that have no worktree yet; one failure
+	// doesn't stop the others
+	created, failed := 0, 0
+	for _, change := range changes {
+		if worktrees[changeKey(kind, change.Number)] != "" {
+			continue
+		}
+		ui.Plain("")
+		if err := runAdd(cmd, []string{changeKey(kind, change.Number)}); err != nil {
+			ui.Warning("⚠ Failed to check out %s: %v", changeLabel(kind, change.Number), err)
+			failed++
+			continue
+		}
+		created++
+	}
+
+	ui.Plain("")
+	ui.Success("✓ Created %d worktree(s)", created)
+	if failed > 0 {
+		return fmt.Errorf("%d pull request(s) could not be checked out", failed)
+	}
+	return nil
+}
+
+// formatChange formats a change as a line of the pr ls listing
+func formatChange(change forge.Change, worktree string) string {
+	title := change.Title
+	if len(title) > 50 {
+		title = title[:47] + "..."
+	}
+
+	var details []string
+	if change.Draft {
+		details = append(details, "draft")
+	}
+	if len(change.Reviewers) > 0 {
+		details = append(details, "review: "+strings.Join(change.Reviewers, ","))
+	}
+	if worktree != "" {
+		details = append(details, "→ "+worktree+"/")
+	}
+
+	return strings.TrimRight(fmt.Sprintf("  #%-5d %-50s @%-15s %s", change.Number, title, change.Author, strings.Join(details, "  ")), " ")
+}
+
+// changeWorktrees maps the changes checked out in the root (e.g., "pr/7") to
+// their worktree directory
+func changeWorktrees(rootDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root directory: %w", err)
+	}
+
+	worktrees := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		worktreePath := filepath.Join(rootDir, name)
+		if _, err := os.Stat(filepath.Join(worktreePath, ".git")); err != nil {
+			continue
+		}
+		meta, err := metadata.Load(rootDir, worktreePath)
+		if err != nil {
+			return nil, err
+		}
+		if change := worktreeChange(meta, name); change != nil {
+			worktrees[changeKey(change.Kind, change.Number)] = name
+		}
+	}
+	return worktrees, nil
+}
+
+// changeKey returns the add argument of a change: "pr/123" or "mr/45"
+func changeKey(kind string, number int) string {
+	return fmt.Sprintf("%s/%d", kind, number)
+}
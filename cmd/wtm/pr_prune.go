@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/internal/pr"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var prPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the worktrees of merged or closed pull requests",
+	Long: `Look up the state of every pull request (or merge request) checked out with
+pr/<n> or mr/<n>, and remove the worktrees of those that were merged or closed
+like 'wtm rm --delete-branch' would: hooks run, and the fetched branch is
+deleted.
+
+Worktrees with local commits that are not part of the pull request are kept,
+as are worktrees with uncommitted changes and locked worktrees (see
+'wtm lock'). Pull requests opened with 'wtm publish' are left alone.
+
+Examples:
+  wtm pr prune                   # Remove worktrees of merged or closed PRs
+  wtm pr prune --dry-run         # Only show what would be removed
+  wtm pr prune --force --force   # Also remove locked worktrees`,
+	Args: cobra.NoArgs,
+	RunE: runPrPrune,
+}
+
+var (
+	prPruneDryRun  bool
+	prPruneNoHooks bool
+	prPruneForce   int
+)
+
+func init() {
+	prPruneCmd.Flags().BoolVarP(&prPruneDryRun, "dry-run", "n", false, "Only show which worktrees would be removed")
+	prPruneCmd.Flags().BoolVar(&prPruneNoHooks, "no-hooks", false, "Skip running post-delete hooks")
+	prPruneCmd.Flags().CountVarP(&prPruneForce, "force", "f", "Given twice (--force --force), also remove locked worktrees")
+	prCmd.AddCommand(prPruneCmd)
+}
+
+func runPrPrune(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	if prPruneForce == 1 {
+		return &usageError{fmt.Errorf("--force only overrides locks, give it twice (--force --force) to remove locked worktrees")}
+	}
+
+	bareDir := config.GetBareDir(rootDir)
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+
+	f, repo, err := forge.ForRemote(bareDir, "origin", settings.Forges)
+	if err != nil {
+		return err
+	}
+
+	worktrees, err := changeWorktrees(rootDir)
+	if err != nil {
+		return err
+	}
+	directories := make([]string, 0, len(worktrees))
+	for _, directory := range worktrees {
+		directories = append(directories, directory)
+	}
+	sort.Strings(directories)
+
+	var prunable []string
+	for _, directory := range directories {
+		worktreePath := filepath.Join(rootDir, directory)
+		meta, err := metadata.Load(rootDir, worktreePath)
+		if err != nil {
+			return err
+		}
+		change := worktreeChange(meta, directory)
+		if change.Published {
+			continue
+		}
+		label := changeLabel(change.Kind, change.Number)
+
+		current, err := f.GetChange(repo, change.Number)
+		if err != nil {
+			ui.Warning("⚠ Skipping '%s': could not look up %s: %v", directory, label, err)
+			continue
+		}
+		if current.State != "MERGED" && current.State != "CLOSED" {
+			continue
+		}
+
+		local, err := localCommits(bareDir, worktreePath, change, settings)
+		if err != nil {
+			ui.Warning("⚠ Skipping '%s': %v", directory, err)
+			continue
+		}
+		if local > 0 {
+			ui.Warning("⚠ Skipping '%s': %s is %s but the worktree has %d local commit(s)", directory, label, strings.ToLower(current.State), local)
+			continue
+		}
+
+		if dirty, err := git.HasUncommittedChanges(worktreePath); err != nil || dirty {
+			ui.Warning("⚠ Skipping '%s': %s is %s but the worktree has uncommitted changes", directory, label, strings.ToLower(current.State))
+			continue
+		}
+
+		locked, reason, err := git.WorktreeLock(worktreePath)
+		if err != nil {
+			ui.Warning("⚠ Skipping '%s': could not check whether it is locked: %v", directory, err)
+			continue
+		}
+		if locked && prPruneForce < 2 {
+			ui.Warning("⚠ Skipping '%s': %s is %s but the worktree is %s (use --force --force to remove it)", directory, label, strings.ToLower(current.State), lockStatus(reason))
+			continue
+		}
+
+		ui.Info("%s is %s: %s", label, strings.ToLower(current.State), directory)
+		prunable = append(prunable, directory)
+	}
+
+	if len(prunable) == 0 {
+		ui.Success("✓ No worktrees of merged or closed pull requests to remove")
+		return nil
+	}
+	if prPruneDryRun {
+		ui.Info("Would remove %d worktree(s), run without --dry-run to remove them", len(prunable))
+		return nil
+	}
+
+	// Remove through rm so hooks, links, generated files and state are handled alike
+	savedForce, savedDelete, savedNoHooks := rmForce, rmDeleteBranch, rmNoHooks
+	rmForce, rmDeleteBranch, rmNoHooks = prPruneForce, true, prPruneNoHooks
+	defer func() { rmForce, rmDeleteBranch, rmNoHooks = savedForce, savedDelete, savedNoHooks }()
+
+	removed := 0
+	for _, directory := range prunable {
+		ui.Plain("")
+		ui.Info("Removing '%s'...", directory)
+		if err := runRm(cmd, []string{directory}); err != nil {
+			ui.Warning("⚠ Failed to remove '%s': %v", directory, err)
+			continue
+		}
+		removed++
+	}
+
+	ui.Plain("")
+	ui.Success("✓ Removed %d of %d worktree(s)", removed, len(prunable))
+	if removed < len(prunable) {
+		return fmt.Errorf("%d worktree(s) could not be removed", len(prunable)-removed)
+	}
+	return nil
+}
+
+// localCommits counts the commits of a change worktree's branch that are
+// neither in the latest head of the change nor in the head it was last
+// updated to
+func localCommits(bareDir, worktreePath string, change *metadata.Change, settings *config.Settings) (int, error) {
+	branch, err := git.GetWorktreeBranch(worktreePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine branch name: %w", err)
+	}
+
+	var exclude []string
+	if head, err := pr.FetchHead(bareDir, change.Kind, change.Number, settings.Forges); err == nil {
+		exclude = append(exclude, head)
+	}
+	if change.Head != "" {
+		if _, err := git.ResolveCommit(bareDir, change.Head); err == nil {
+			exclude = append(exclude, change.Head)
+		}
+	}
+	if len(exclude) == 0 {
+		return 0, fmt.Errorf("cannot fetch the head of %s to check for local commits", changeLabel(change.Kind, change.Number))
+	}
+
+	return git.CountCommits(bareDir, "refs/heads/"+branch, exclude...)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+)
+
+// setupPRWorktree creates a root whose origin has PR #7 and a pr-7 worktree
+// checked out at the PR head. Returns the root, the worktree and the origin.
+func setupPRWorktree(t *testing.T) (rootDir, worktreePath, originDir string) {
+	t.Helper()
+
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	t.Cleanup(cleanup)
+
+	originDir = filepath.Join(t.TempDir(), "origin.git")
+	if err := git.InitBare(originDir); err != nil {
+		t.Fatalf("Failed to init origin: %v", err)
+	}
+	if err := git.CreateInitialBranch(originDir, "main"); err != nil {
+		t.Fatalf("Failed to create initial branch: %v", err)
+	}
+	pushPRHead(t, originDir, "refs/heads/main", "PR commit")
+
+	runGitCmd(t, "--git-dir="+bareDir, "remote", "add", "origin", originDir)
+	runGitCmd(t, "--git-dir="+bareDir, "fetch", "origin", "refs/pull/7/head:refs/heads/fix")
+
+	worktreePath = filepath.Join(rootDir, "pr-7")
+	if err := git.AddWorktree(bareDir, "fix", worktreePath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	head, _ := git.ResolveCommit(bareDir, "refs/heads/fix")
+	meta := &metadata.Metadata{Change: &metadata.Change{Kind: "pr", Number: 7, HeadRef: "fix", Head: head}}
+	if err := metadata.Save(rootDir, worktreePath, meta); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldDir) })
+
+	return rootDir, worktreePath, originDir
+}
+
+// pushPRHead points PR #7 of the origin at a new commit on top of parent
+func pushPRHead(t *testing.T, originDir, parent, message string) string {
+	t.Helper()
+	tree := gitOutput(t, "--git-dir="+originDir, "rev-parse", parent+"^{tree}")
+	commit := gitOutput(t, "--git-dir="+originDir, "-c", "user.name=Test", "-c", "user.email=test@example.com",
+		"commit-tree", tree, "-p", parent, "-m", message)
+	runGitCmd(t, "--git-dir="+originDir, "update-ref", "refs/pull/7/head", commit)
+	return commit
+}
+
+// gitOutput runs a git command and returns its trimmed output
+func gitOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s", strings.Join(args, " "), output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// commitInWorktree creates a local commit in a worktree
+func commitInWorktree(t *testing.T, worktreePath string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(worktreePath, "local.txt"), []byte("local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitCmd(t, "-C", worktreePath, "add", "local.txt")
+	runGitCmd(t, "-C", worktreePath, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "local")
+}
+
+func TestPrUpdate_FastForward(t *testing.T) {
+	rootDir, worktreePath, originDir := setupPRWorktree(t)
+	bareDir := config.GetBareDir(rootDir)
+	settings, _ := config.LoadSettings(rootDir)
+
+	// Nothing new
+	if err := updateChange(rootDir, worktreePath, settings, false); err != nil {
+		t.Fatalf("updateChange() up to date error = %v", err)
+	}
+
+	newHead := pushPRHead(t, originDir, "refs/pull/7/head", "Follow-up")
+	if err := updateChange(rootDir, worktreePath, settings, false); err != nil {
+		t.Fatalf("updateChange() error = %v", err)
+	}
+
+	if head, _ := git.ResolveCommit(bareDir, "refs/heads/fix"); head != newHead {
+		t.Errorf("Branch head = %s, want %s", head, newHead)
+	}
+	meta, _ := metadata.Load(rootDir, worktreePath)
+	if meta.Change.Head != newHead {
+		t.Errorf("Recorded head = %s, want %s", meta.Change.Head, newHead)
+	}
+	if refs := gitOutput(t, "--git-dir="+bareDir, "for-each-ref", "refs/wtm/backup"); refs != "" {
+		t.Errorf("Fast-forward created backup refs: %s", refs)
+	}
+}
+
+func TestPrUpdate_ForcePush(t *testing.T) {
+	rootDir, worktreePath, originDir := setupPRWorktree(t)
+	bareDir := config.GetBareDir(rootDir)
+	settings, _ := config.LoadSettings(rootDir)
+	oldHead, _ := git.ResolveCommit(bareDir, "refs/heads/fix")
+
+	// The PR was rewritten, nothing local is lost
+	newHead := pushPRHead(t, originDir, "refs/heads/main", "PR commit, amended")
+	if err := updateChange(rootDir, worktreePath, settings, false); err != nil {
+		t.Fatalf("updateChange() error = %v", err)
+	}
+	if head, _ := git.ResolveCommit(bareDir, "refs/heads/fix"); head != newHead {
+		t.Errorf("Branch head = %s, want %s", head, newHead)
+	}
+	backup := gitOutput(t, "--git-dir="+bareDir, "for-each-ref", "--format=%(objectname)", "refs/wtm/backup/pr-7")
+	if backup != oldHead {
+		t.Errorf("Backup ref points at %q, want previous head %s", backup, oldHead)
+	}
+}
+
+func TestPrUpdate_LocalCommits(t *testing.T) {
+	rootDir, worktreePath, originDir := setupPRWorktree(t)
+	bareDir := config.GetBareDir(rootDir)
+	settings, _ := config.LoadSettings(rootDir)
+
+	commitInWorktree(t, worktreePath)
+	localHead, _ := git.ResolveCommit(bareDir, "refs/heads/fix")
+	newHead := pushPRHead(t, originDir, "refs/heads/main", "PR commit, amended")
+
+	err := updateChange(rootDir, worktreePath, settings, false)
+	if err == nil || !strings.Contains(err.Error(), "1 local commit(s)") {
+		t.Fatalf("updateChange() error = %v, want local commits error", err)
+	}
+	if head, _ := git.ResolveCommit(bareDir, "refs/heads/fix"); head != localHead {
+		t.Errorf("Branch moved despite local commits")
+	}
+
+	// Uncommitted changes are backed up too with --force
+	if err := os.WriteFile(filepath.Join(worktreePath, "local.txt"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateChange(rootDir, worktreePath, settings, true); err != nil {
+		t.Fatalf("updateChange() with force error = %v", err)
+	}
+	if head, _ := git.ResolveCommit(bareDir, "refs/heads/fix"); head != newHead {
+		t.Errorf("Branch head = %s, want %s", head, newHead)
+	}
+	backups := gitOutput(t, "--git-dir="+bareDir, "for-each-ref", "--format=%(refname)", "refs/wtm/backup/pr-7")
+	if strings.Count(backups, "\n") != 1 || !strings.Contains(backups, "-changes") {
+		t.Errorf("Backup refs = %q, want previous head and uncommitted changes", backups)
+	}
+}
+
+// TestAddCommand_ExistingPR tests that checking out a PR again updates its worktree
+func TestAddCommand_ExistingPR(t *testing.T) {
+	rootDir, _, originDir := setupPRWorktree(t)
+	newHead := pushPRHead(t, originDir, "refs/pull/7/head", "Follow-up")
+
+	if err := runPr(prCmd, []string{"7"}); err != nil {
+		t.Fatalf("runPr() error = %v", err)
+	}
+	if head, _ := git.ResolveCommit(config.GetBareDir(rootDir), "refs/heads/fix"); head != newHead {
+		t.Errorf("Branch head = %s, want %s", head, newHead)
+	}
+
+	if err := runPr(prCmd, []string{"8", "pr-7"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("runPr() for another PR in the same directory error = %v", err)
+	}
+}
+
+// serveGitHubPulls points origin at github.com/acme/widgets, served by the
+// origin repository and a stub API answering the given paths
+func serveGitHubPulls(t *testing.T, bareDir, originDir string, responses map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("WTM_GITHUB_API_URL", server.URL)
+	runGitCmd(t, "--git-dir="+bareDir, "remote", "set-url", "origin", "https://github.com/acme/widgets.git")
+	runGitCmd(t, "--git-dir="+bareDir, "config", "url."+originDir+".insteadOf", "https://github.com/acme/widgets.git")
+}
+
+func TestPrLs(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed and would be used instead of the API")
+	}
+
+	rootDir, _, originDir := setupPRWorktree(t)
+	bareDir := config.GetBareDir(rootDir)
+	runGitCmd(t, "--git-dir="+originDir, "update-ref", "refs/pull/8/head", "refs/heads/main")
+
+	// Serve PRs 7 and 8 of acme/widgets, which the origin stands in for
+	pull8 := `{"number": 8, "title": "Docs", "state": "open", "draft": true, "user": {"login": "hubot"},
+		"head": {"ref": "docs", "repo": {"full_name": "acme/widgets"}}, "base": {"ref": "main"}}`
+	serveGitHubPulls(t, bareDir, originDir, map[string]string{
+		"/repos/acme/widgets/pulls": `[{"number": 7, "title": "Fix", "state": "open", "user": {"login": "octocat"},
+			"labels": [{"name": "bug"}], "head": {"ref": "fix"}, "base": {"ref": "main"}}, ` + pull8 + `]`,
+		"/repos/acme/widgets/pulls/8": pull8,
+	})
+
+	worktrees, err := changeWorktrees(rootDir)
+	if err != nil {
+		t.Fatalf("changeWorktrees() error = %v", err)
+	}
+	if len(worktrees) != 1 || worktrees["pr/7"] != "pr-7" {
+		t.Errorf("changeWorktrees() = %v, want pr/7 in pr-7", worktrees)
+	}
+
+	prLsLabels = []string{"bug"}
+	if err := runPrLs(prLsCmd, nil); err != nil {
+		t.Errorf("runPrLs() with label error = %v", err)
+	}
+	prLsLabels = nil
+
+	prLsCheckout = true
+	addNoHooks = true
+	defer func() { prLsCheckout, addNoHooks = false, false }()
+	if err := runPrLs(prLsCmd, nil); err != nil {
+		t.Fatalf("runPrLs() with checkout error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "pr-8")); err != nil {
+		t.Errorf("pr-8 worktree was not created: %v", err)
+	}
+	if head, err := git.GetWorktreeBranch(filepath.Join(rootDir, "pr-8")); err != nil || head != "docs" {
+		t.Errorf("pr-8 branch = %q, %v, want docs", head, err)
+	}
+}
+
+func TestFormatChange(t *testing.T) {
+	change := forge.Change{Number: 8, Title: "Docs", Author: "hubot", Draft: true, Reviewers: []string{"octocat", "core"}}
+	got := formatChange(change, "pr-8")
+	for _, want := range []string{"#8", "Docs", "@hubot", "draft", "review: octocat,core", "→ pr-8/"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatChange() = %q, missing %q", got, want)
+		}
+	}
+
+	change.Title = strings.Repeat("x", 60)
+	if got := formatChange(change, ""); strings.Contains(got, strings.Repeat("x", 48)) || strings.Contains(got, "→") {
+		t.Errorf("formatChange() = %q, want truncated title and no worktree", got)
+	}
+}
+
+func TestPrSinceReview(t *testing.T) {
+	rootDir, worktreePath, originDir := setupPRWorktree(t)
+	settings, _ := config.LoadSettings(rootDir)
+
+	var out bytes.Buffer
+	if err := showSinceReview(rootDir, worktreePath, settings, &out); err == nil || !strings.Contains(err.Error(), "wtm pr reviewed") {
+		t.Fatalf("showSinceReview() before a review error = %v", err)
+	}
+
+	if err := markReviewed(rootDir, worktreePath); err != nil {
+		t.Fatalf("markReviewed() error = %v", err)
+	}
+	meta, _ := metadata.Load(rootDir, worktreePath)
+	reviewed := meta.Change.Reviewed
+	if head, _ := git.ResolveCommit(config.GetBareDir(rootDir), "refs/heads/fix"); reviewed != head {
+		t.Errorf("Reviewed = %s, want worktree head %s", reviewed, head)
+	}
+
+	// Unchanged PR: nothing to show
+	if err := showSinceReview(rootDir, worktreePath, settings, &out); err != nil || out.Len() != 0 {
+		t.Errorf("showSinceReview() unchanged = %q, %v", out.String(), err)
+	}
+
+	// Force-pushed PR: the range-diff pairs the old and new commits
+	pushPRHead(t, originDir, "refs/heads/main", "PR commit, amended")
+	if err := showSinceReview(rootDir, worktreePath, settings, &out); err != nil {
+		t.Fatalf("showSinceReview() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "PR commit, amended") {
+		t.Errorf("range-diff output = %q, want the amended commit", out.String())
+	}
+
+	// The review mark and worktree are left as they were
+	meta, _ = metadata.Load(rootDir, worktreePath)
+	if meta.Change.Reviewed != reviewed {
+		t.Errorf("Reviewed changed to %s", meta.Change.Reviewed)
+	}
+}
+
+func TestPrPrune(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed and would be used instead of the API")
+	}
+
+	rootDir, worktreePath, originDir := setupPRWorktree(t)
+	bareDir := config.GetBareDir(rootDir)
+
+	// PR #8 was closed too, but its worktree has a commit of ours
+	runGitCmd(t, "--git-dir="+originDir, "update-ref", "refs/pull/8/head", "refs/heads/main")
+	runGitCmd(t, "--git-dir="+bareDir, "fetch", "-q", "origin", "refs/pull/8/head:refs/heads/docs")
+	keptPath := filepath.Join(rootDir, "pr-8")
+	if err := git.AddWorktree(bareDir, "docs", keptPath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitInWorktree(t, keptPath)
+
+	// PR #9 is still open
+	runGitCmd(t, "--git-dir="+bareDir, "branch", "wip", "main")
+	if err := git.AddWorktree(bareDir, "wip", filepath.Join(rootDir, "pr-9"), ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	serveGitHubPulls(t, bareDir, originDir, map[string]string{
+		"/repos/acme/widgets/pulls/7": `{"number": 7, "state": "closed", "merged": true, "head": {"ref": "fix"}, "base": {"ref": "main"}}`,
+		"/repos/acme/widgets/pulls/8": `{"number": 8, "state": "closed", "head": {"ref": "docs"}, "base": {"ref": "main"}}`,
+		"/repos/acme/widgets/pulls/9": `{"number": 9, "state": "open", "head": {"ref": "wip"}, "base": {"ref": "main"}}`,
+	})
+
+	prPruneDryRun, prPruneNoHooks = true, true
+	defer func() { prPruneDryRun, prPruneNoHooks = false, false }()
+	if err := runPrPrune(prPruneCmd, nil); err != nil {
+		t.Fatalf("runPrPrune() dry run error = %v", err)
+	}
+	if _, err := os.Stat(worktreePath); err != nil {
+		t.Fatalf("Dry run removed the worktree")
+	}
+
+	// A locked worktree is kept unless forced twice
+	runGitCmd(t, "--git-dir="+bareDir, "worktree", "lock", worktreePath)
+	prPruneDryRun = false
+	if err := runPrPrune(prPruneCmd, nil); err != nil {
+		t.Fatalf("runPrPrune() error = %v", err)
+	}
+	if _, err := os.Stat(worktreePath); err != nil {
+		t.Fatalf("runPrPrune() removed a locked worktree")
+	}
+
+	prPruneForce = 2
+	defer func() { prPruneForce = 0 }()
+	if err := runPrPrune(prPruneCmd, nil); err != nil {
+		t.Fatalf("runPrPrune() error = %v", err)
+	}
+	if _, err := os.Stat(worktreePath); !os.IsNotExist(err) {
+		t.Errorf("Worktree of the merged PR still exists")
+	}
+	if exists, _ := git.LocalBranchExists(bareDir, "fix"); exists {
+		t.Errorf("Branch of the merged PR was not deleted")
+	}
+	for _, kept := range []string{"pr-8", "pr-9"} {
+		if _, err := os.Stat(filepath.Join(rootDir, kept)); err != nil {
+			t.Errorf("Worktree %s was removed", kept)
+		}
+	}
+	if rmDeleteBranch || rmForce != 0 {
+		t.Errorf("runPrPrune() left rm flags set")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/internal/template"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+// prTemplateFile is the template of draft PR titles and bodies in .worktree/
+const prTemplateFile = "pr-template.md.tmpl"
+
+var publishCmd = &cobra.Command{
+	Use:   "publish [worktree]",
+	Short: "Push a worktree's branch and open a draft pull request",
+	Long: `Push the branch of a worktree to origin, set it as the branch's upstream and
+open a draft pull request (or merge request on GitLab) against the branch the
+worktree was created from, or the default branch.
+
+The title and body come from .worktree/pr-template.md.tmpl, rendered with the
+worktree's template data: the first line is the title (a leading "# " is
+dropped) and the rest is the body. Without a template, the title is the
+title of the issue the worktree was created for (wtm add issue/<n>), or the
+subject of the branch's latest commit. The body of a worktree created for an
+issue ends with "Closes #<n>".
+
+Forges reject pull requests without changes, so a branch with no commits of
+its own first gets an empty commit with the PR title as message.
+
+Defaults to the current worktree.
+
+Examples:
+  wtm publish              # Publish the current worktree
+  wtm publish feature-x    # Publish the feature-x worktree`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPublish,
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args)
+	if err != nil {
+		return err
+	}
+
+	return publishWorktree(rootDir, worktreePath, settings)
+}
+
+// publishWorktree pushes a worktree's branch and opens a draft PR/MR for it,
+// recording the change in the worktree's metadata
+func publishWorktree(rootDir, worktreePath string, settings *config.Settings) error {
+	bareDir := config.GetBareDir(rootDir)
+	name := filepath.Base(worktreePath)
+
+	meta, err := metadata.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+	if change := worktreeChange(meta, name); change != nil {
+		return fmt.Errorf("worktree '%s' already has %s", name, changeLabel(change.Kind, change.Number))
+	}
+
+	branch, err := git.GetWorktreeBranch(worktreePath)
+	if err != nil {
+		return fmt.Errorf("failed to determine branch name: %w", err)
+	}
+	base := meta.Base
+	if base == "" {
+		if base, err = git.GetDefaultBranch(bareDir); err != nil {
+			return fmt.Errorf("failed to determine the base branch: %w", err)
+		}
+	}
+	if base == branch {
+		return fmt.Errorf("branch '%s' is its own base, create a feature branch to publish", branch)
+	}
+
+	f, repo, err := forge.ForRemote(bareDir, "origin", settings.Forges)
+	if err != nil {
+		return err
+	}
+	kind := "pr"
+	if f.Kind() == forge.GitLab {
+		kind = "mr"
+	}
+
+	title, body, err := renderPRTemplate(rootDir, worktreePath, branch, settings.Datasources)
+	if err != nil {
+		return err
+	}
+	if meta.Issue != nil {
+		if title == "" {
+			title = meta.Issue.Title
+		}
+		// Close the issue when the change is merged
+		closes := fmt.Sprintf("Closes #%d", meta.Issue.Number)
+		if !strings.Contains(body, closes) {
+			body = strings.TrimSpace(body + "\n\n" + closes)
+		}
+	}
+
+	// Forges refuse pull requests without commits
+	ahead := 1
+	for _, baseRev := range []string{"refs/heads/" + base, "refs/remotes/origin/" + base} {
+		if _, err := git.ResolveCommit(bareDir, baseRev); err == nil {
+			if ahead, err = git.CountCommits(bareDir, "refs/heads/"+branch, baseRev); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if ahead == 0 {
+		if title == "" {
+			title = branch
+		}
+		ui.Info("Creating an empty commit, '%s' has no commits of its own yet", branch)
+		if err := git.CommitEmpty(worktreePath, title); err != nil {
+			return err
+		}
+	}
+	if title == "" {
+		if title, err = git.CommitSubject(bareDir, "refs/heads/"+branch); err != nil {
+			return err
+		}
+	}
+
+	ui.Info("Pushing '%s' to origin...", branch)
+	if err := git.PushUpstream(worktreePath, "origin", branch); err != nil {
+		return err
+	}
+
+	ui.Info("Opening a draft %s against '%s'...", strings.ToUpper(kind), base)
+	created, err := f.CreateDraft(repo, forge.DraftOptions{Head: branch, Base: base, Title: title, Body: body})
+	if err != nil {
+		return fmt.Errorf("failed to open a draft %s: %w", strings.ToUpper(kind), err)
+	}
+
+	change := &metadata.Change{Kind: kind, Number: created.Number, BaseRef: base, HeadRef: branch, Published: true}
+	if head, err := git.ResolveCommit(bareDir, "refs/heads/"+branch); err == nil {
+		change.Head = head
+	}
+	meta.Change = change
+	if err := metadata.Save(rootDir, worktreePath, meta); err != nil {
+		ui.Warning("Failed to record worktree metadata: %v", err)
+	}
+
+	ui.Success("✓ Opened draft %s", changeLabel(kind, created.Number))
+	if created.URL != "" {
+		ui.Info("  %s", created.URL)
+	}
+	return nil
+}
+
+// renderPRTemplate renders .worktree/pr-template.md.tmpl into a title (its
+// first line) and a body. Both are empty without a template.
+func renderPRTemplate(rootDir, worktreePath, branch string, sources map[string]config.Datasource) (title, body string, err error) {
+	path := filepath.Join(config.GetWorktreeDir(rootDir), prTemplateFile)
+	if _, err := os.Stat(path); err != nil {
+		return "", "", nil
+	}
+
+	data := template.TemplateData{
+		Branch:        branch,
+		Directory:     worktreePath,
+		RootDirectory: rootDir,
+		SparseProfile: sparseProfile(rootDir, worktreePath),
+	}
+	content, err := template.RenderFile(path, data, sources)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render %s: %w", prTemplateFile, err)
+	}
+
+	title, body, _ = strings.Cut(strings.TrimSpace(string(content)), "\n")
+	title = strings.TrimSpace(strings.TrimPrefix(title, "# "))
+	return title, strings.TrimSpace(body), nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+)
+
+// setupGitHubOrigin points origin at github.com/acme/widgets, served by a
+// local bare repository and a stub API that has issue #12 and records the
+// draft PR request. Returns the origin directory.
+func setupGitHubOrigin(t *testing.T, bareDir string, request *map[string]any) string {
+	t.Helper()
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed and would be used instead of the API")
+	}
+
+	originDir := filepath.Join(t.TempDir(), "origin.git")
+	if err := git.InitBare(originDir); err != nil {
+		t.Fatalf("Failed to init origin: %v", err)
+	}
+	runGitCmd(t, "--git-dir="+bareDir, "remote", "add", "origin", "https://github.com/acme/widgets.git")
+	runGitCmd(t, "--git-dir="+bareDir, "config", "url."+originDir+".insteadOf", "https://github.com/acme/widgets.git")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/issues/12":
+			_, _ = w.Write([]byte(`{"number": 12, "title": "Crash on start", "state": "open", "user": {"login": "hubot"}, "labels": [{"name": "bug"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/widgets/pulls":
+			_ = json.NewDecoder(r.Body).Decode(request)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 9, "state": "open", "draft": true, "html_url": "https://github.com/acme/widgets/pull/9"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("WTM_GITHUB_API_URL", server.URL)
+	return originDir
+}
+
+// TestAddCommand_DraftPR tests that add --draft-pr pushes the new branch and
+// opens a draft PR rendered from the PR template
+func TestAddCommand_DraftPR(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	var request map[string]any
+	originDir := setupGitHubOrigin(t, bareDir, &request)
+
+	template := "# Start {{ .Branch }}\n\nWork on {{ .Branch }} begins.\n"
+	if err := os.MkdirAll(config.GetWorktreeDir(rootDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config.GetWorktreeDir(rootDir), prTemplateFile), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addDraftPR, addNoHooks = true, true
+	defer func() { addDraftPR, addNoHooks = false, false }()
+
+	if err := runAdd(addCmd, []string{"main"}); err == nil || !strings.Contains(err.Error(), "needs a new branch") {
+		t.Errorf("runAdd() --draft-pr without a new branch error = %v", err)
+	}
+	if err := runAdd(addCmd, []string{"main", "feature-x"}); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+
+	want := map[string]any{"title": "Start feature-x", "body": "Work on feature-x begins.", "head": "feature-x", "base": "main", "draft": true}
+	for key, value := range want {
+		if request[key] != value {
+			t.Errorf("Draft PR request %s = %v, want %v", key, request[key], value)
+		}
+	}
+
+	// The branch got an empty commit and was pushed with upstream tracking
+	head := gitOutput(t, "--git-dir="+bareDir, "rev-parse", "refs/heads/feature-x")
+	if pushed := gitOutput(t, "--git-dir="+originDir, "rev-parse", "refs/heads/feature-x"); pushed != head {
+		t.Errorf("Origin feature-x = %s, want %s", pushed, head)
+	}
+	if subject := gitOutput(t, "--git-dir="+bareDir, "log", "-1", "--format=%s", "feature-x"); subject != "Start feature-x" {
+		t.Errorf("Empty commit subject = %q", subject)
+	}
+	if remote := gitOutput(t, "--git-dir="+bareDir, "config", "branch.feature-x.remote"); remote != "origin" {
+		t.Errorf("Upstream remote = %q, want origin", remote)
+	}
+
+	worktreePath := filepath.Join(rootDir, "feature-x")
+	meta, _ := metadata.Load(rootDir, worktreePath)
+	if meta.Base != "main" || meta.Change == nil || meta.Change.Number != 9 || meta.Change.Head != head {
+		t.Errorf("Metadata = %+v, want base main and PR #9 at %s", meta, head)
+	}
+
+	settings, _ := config.LoadSettings(rootDir)
+	if err := publishWorktree(rootDir, worktreePath, settings); err == nil || !strings.Contains(err.Error(), "already has PR #9") {
+		t.Errorf("publishWorktree() again error = %v", err)
+	}
+}
+
+// TestAddCommand_Issue tests that issue/<n> creates a branch named after the
+// issue from the default branch, and that its PR closes the issue
+func TestAddCommand_Issue(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	var request map[string]any
+	setupGitHubOrigin(t, bareDir, &request)
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addNoHooks = true
+	defer func() { addNoHooks = false }()
+
+	if err := runAdd(addCmd, []string{"issue/abc"}); err == nil || !strings.Contains(err.Error(), "invalid issue syntax") {
+		t.Errorf("runAdd() with invalid issue error = %v", err)
+	}
+	if err := runAdd(addCmd, []string{"issue/12"}); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+
+	worktreePath := filepath.Join(rootDir, "fix-12-crash-on-start")
+	branch, err := git.GetWorktreeBranch(worktreePath)
+	if err != nil || branch != "fix/12-crash-on-start" {
+		t.Fatalf("Worktree branch = %q, %v, want fix/12-crash-on-start", branch, err)
+	}
+	meta, _ := metadata.Load(rootDir, worktreePath)
+	if meta.Base != "main" || meta.Issue == nil || meta.Issue.Number != 12 {
+		t.Errorf("Metadata = %+v, want base main and issue #12", meta)
+	}
+
+	settings, _ := config.LoadSettings(rootDir)
+	if err := publishWorktree(rootDir, worktreePath, settings); err != nil {
+		t.Fatalf("publishWorktree() error = %v", err)
+	}
+	if request["title"] != "Crash on start" || request["body"] != "Closes #12" {
+		t.Errorf("Draft PR request = %v, want the issue title and Closes #12", request)
+	}
+}
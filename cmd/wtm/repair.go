@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/exclude"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Repair the links between .bare and the worktrees",
+	Long: `Repair the links between the bare repository and the worktrees with
+'git worktree repair', e.g., after the root was moved.
+
+Git records absolute paths: each worktree's .git file points to
+.bare/worktrees/<name>, which points back to the worktree. When the whole
+root is moved, both are stale and git commands fail in every worktree.
+repair detects the move, rewrites the links, and updates the other absolute
+paths wtm wrote: the exclude file setting of worktrees with generated files,
+and the shared module store of submodules. Only files inside the root are
+changed, so a copy of a root is repaired without touching the original.
+The copy of the global excludes file in worktrees with generated files is
+refreshed too, so run repair after editing it.
+
+With --relative, the links are written as relative paths and
+worktree.useRelativePaths is set, so moving the root does not break them
+again (needs git 2.48 or later).
+
+Examples:
+  mv ~/code/app ~/src/app && cd ~/src/app && wtm repair
+  wtm repair --relative`,
+	Args: cobra.NoArgs,
+	RunE: runRepair,
+}
+
+var relocateCmd = &cobra.Command{
+	Use:   "relocate <new-root>",
+	Short: "Move the root and repair its worktrees",
+	Long: `Move the whole root (.bare, .worktree and all worktrees) to a new directory,
+then repair the links between them like 'wtm repair'.
+
+The new root must not exist yet. Moving to another filesystem is not
+supported: move the root there yourself (e.g., with mv, not a copy), then
+run 'wtm repair' in it.
+
+Examples:
+  wtm relocate ~/src/app
+  wtm relocate ~/src/app --relative`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRelocate,
+}
+
+var (
+	repairRelative   bool
+	relocateRelative bool
+)
+
+func init() {
+	repairCmd.Flags().BoolVar(&repairRelative, "relative", false, "Write relative links and set worktree.useRelativePaths (git 2.48+)")
+	relocateCmd.Flags().BoolVar(&relocateRelative, "relative", false, "Write relative links and set worktree.useRelativePaths (git 2.48+)")
+	rootCmd.AddCommand(repairCmd)
+	rootCmd.AddCommand(relocateCmd)
+}
+
+func runRepair(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+	return repairRoot(rootDir, repairRelative)
+}
+
+func runRelocate(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+
+	newRoot, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", args[0], err)
+	}
+	if newRoot == rootDir || strings.HasPrefix(newRoot, rootDir+string(filepath.Separator)) {
+		return &usageError{fmt.Errorf("cannot move the root into itself")}
+	}
+	if _, err := os.Stat(newRoot); err == nil {
+		return withHint(git.ErrPathExists, "'%s' already exists, the new root must be a new directory", args[0])
+	}
+	if relocateRelative {
+		if err := checkRelativePaths(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(newRoot), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(newRoot), err)
+	}
+
+	ui.Info("Moving %s to %s...", rootDir, newRoot)
+	if err := os.Rename(rootDir, newRoot); err != nil {
+		if errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("cannot move the root to another filesystem: move it to %s yourself (e.g., with mv, not a copy), then run 'wtm repair' there", newRoot)
+		}
+		return fmt.Errorf("failed to move the root: %w", err)
+	}
+
+	if err := repairRoot(newRoot, relocateRelative); err != nil {
+		return fmt.Errorf("moved to %s, but the worktrees could not be repaired (run 'wtm repair' there): %w", newRoot, err)
+	}
+	ui.Info("  cd %s", newRoot)
+	return nil
+}
+
+// worktreeLink is a linked worktree of the root, and the state of its links
+// with the bare repository
+type worktreeLink struct {
+	record git.WorktreeRecord
+	path   string // Where the worktree is now, "" when it is missing
+	broken bool   // Its .git file or the recorded path is stale
+}
+
+// inspectWorktreeLinks finds the root's linked worktrees and checks their
+// links. A worktree recorded outside the root that exists in the root under
+// the same name moved, or was copied, with the root: oldRoot is where it was.
+// A recorded directory outside the root is only used when it links back to
+// this root, since after a copy it is the original root's worktree.
+func inspectWorktreeLinks(rootDir string) (links []worktreeLink, oldRoot string, err error) {
+	records, err := git.WorktreeRecords(config.GetBareDir(rootDir))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the worktrees of .bare: %w", err)
+	}
+
+	for _, record := range records {
+		link := worktreeLink{record: record}
+		candidate := filepath.Join(rootDir, filepath.Base(record.Path))
+		switch {
+		case isInRoot(rootDir, record.Path):
+			if _, err := os.Stat(record.Path); err == nil {
+				link.path = record.Path
+			}
+		case hasWorktreeLink(candidate, record.ID):
+			link.path = candidate
+			if oldRoot == "" {
+				oldRoot = filepath.Dir(record.Path)
+			}
+		default:
+			// A worktree kept outside the root on purpose
+			if target, err := git.WorktreeLink(record.Path); err == nil && samePath(target, record.GitDir) {
+				link.path = record.Path
+			}
+		}
+		if link.path != "" {
+			target, err := git.WorktreeLink(link.path)
+			link.broken = err != nil || !samePath(link.path, record.Path) || !samePath(target, record.GitDir)
+		}
+		links = append(links, link)
+	}
+	return links, oldRoot, nil
+}
+
+// isInRoot reports whether path is inside rootDir, which may be reached
+// through a symlink
+func isInRoot(rootDir, path string) bool {
+	if strings.HasPrefix(path, rootDir+string(filepath.Separator)) {
+		return true
+	}
+	realRoot, err := filepath.EvalSymlinks(rootDir)
+	return err == nil && strings.HasPrefix(path, realRoot+string(filepath.Separator))
+}
+
+// hasWorktreeLink reports whether dir is a linked worktree whose .git file
+// points to an administrative directory named id, of any repository
+func hasWorktreeLink(dir, id string) bool {
+	target, err := git.WorktreeLink(dir)
+	return err == nil && filepath.Base(target) == id
+}
+
+// samePath reports whether two paths name the same file, following symlinks
+// (git records real paths, e.g., /private/var for /var on macOS)
+func samePath(a, b string) bool {
+	if a == b {
+		return true
+	}
+	realA, errA := filepath.EvalSymlinks(a)
+	realB, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && realA == realB
+}
+
+// checkRelativePaths fails unless git can write relative worktree links
+func checkRelativePaths() error {
+	major, minor, err := git.Version()
+	if err != nil {
+		return err
+	}
+	if major < 2 || (major == 2 && minor < 48) {
+		return fmt.Errorf("relative worktree paths need git 2.48 or later, this is git %d.%d", major, minor)
+	}
+	return nil
+}
+
+// repairRoot repairs the links between the bare repository and the worktrees,
+// and the other absolute paths wtm wrote, after the root was moved
+func repairRoot(rootDir string, relative bool) error {
+	bareDir := config.GetBareDir(rootDir)
+	if relative {
+		if err := checkRelativePaths(); err != nil {
+			return err
+		}
+		if err := git.SetConfig(bareDir, "worktree.useRelativePaths", "true"); err != nil {
+			return err
+		}
+	}
+
+	links, oldRoot, err := inspectWorktreeLinks(rootDir)
+	if err != nil {
+		return err
+	}
+	if oldRoot != "" {
+		if _, err := os.Stat(config.GetBareDir(oldRoot)); err == nil {
+			ui.Warning("⚠ This root is a copy of %s, which still exists", oldRoot)
+			ui.Warning("  Only this root's worktrees are repaired; move roots with 'wtm relocate' or mv instead of copying them")
+		} else {
+			ui.Info("The root moved from %s to %s", oldRoot, rootDir)
+		}
+	}
+
+	var paths, broken []string
+	for _, link := range links {
+		name := filepath.Base(link.record.Path)
+		if link.path == "" {
+			if locked, _, _ := link.record.Lock(); locked {
+				ui.Warning("⚠ Worktree %s is missing (last at %s), it is locked so git keeps it", name, link.record.Path)
+			} else {
+				ui.Warning("⚠ Worktree %s is missing (last at %s), forget it with 'git worktree prune'", name, link.record.Path)
+			}
+			continue
+		}
+		paths = append(paths, link.path)
+		if link.broken {
+			broken = append(broken, name)
+		}
+	}
+
+	repaired := 0
+	if len(paths) > 0 && (len(broken) > 0 || relative) {
+		ui.Info("Repairing worktree links...")
+
+		// Point .git files at this root's records first: git would otherwise
+		// follow those of a copied root back into the original and rewrite it
+		for _, link := range links {
+			if link.path == "" {
+				continue
+			}
+			if target, err := git.WorktreeLink(link.path); err == nil && !samePath(target, link.record.GitDir) {
+				if err := git.SetWorktreeLink(link.path, link.record.GitDir); err != nil {
+					return fmt.Errorf("failed to repair %s: %w", filepath.Base(link.path), err)
+				}
+			}
+		}
+		if err := git.RepairWorktrees(bareDir, paths, relative); err != nil {
+			return fmt.Errorf("failed to repair worktrees: %w", err)
+		}
+		for _, name := range broken {
+			ui.Info("  %s", name)
+		}
+		repaired += len(broken)
+	}
+
+	// Worktrees with generated files point core.excludesFile at an absolute path
+	for _, path := range paths {
+		fixed, err := exclude.Repair(path)
+		if err != nil {
+			ui.Warning("⚠ Could not update the exclude file setting of %s: %v", filepath.Base(path), err)
+		} else if fixed {
+			ui.Info("  Updated the exclude file setting of %s", filepath.Base(path))
+			repaired++
+		}
+
+		// That setting hides the global excludes file, whose copy may be outdated
+		if refreshed, err := exclude.RefreshGlobal(path); err != nil {
+			ui.Warning("⚠ Could not refresh the global excludes of %s: %v", filepath.Base(path), err)
+		} else if refreshed {
+			ui.Info("  Refreshed the global excludes copied into %s", filepath.Base(path))
+		}
+	}
+
+	// Submodules cloned with the shared module store reference it absolutely
+	if oldRoot != "" {
+		fixed, err := repairAlternates(bareDir, oldRoot, rootDir)
+		if err != nil {
+			ui.Warning("⚠ Could not update submodules using the shared module store: %v", err)
+		} else if fixed > 0 {
+			ui.Info("  Updated %d submodule(s) using the shared module store", fixed)
+			repaired += fixed
+		}
+	}
+
+	switch {
+	case relative:
+		ui.Success("✓ Worktree links are relative now")
+	case repaired == 0:
+		ui.Success("✓ All worktree links are correct")
+	default:
+		ui.Success("✓ Repaired %d link(s)", repaired)
+	}
+	return nil
+}
+
+// repairAlternates rewrites the object alternates of the worktrees'
+// submodules that point into the old root, and returns how many it changed
+func repairAlternates(bareDir, oldRoot, newRoot string) (int, error) {
+	fixed := 0
+	err := filepath.WalkDir(filepath.Join(bareDir, "worktrees"), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || entry.Name() != "alternates" || filepath.Base(filepath.Dir(path)) != "info" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(content), "\n")
+		changed := false
+		for i, line := range lines {
+			if strings.HasPrefix(line, oldRoot+string(filepath.Separator)) {
+				lines[i] = newRoot + strings.TrimPrefix(line, oldRoot)
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		fixed++
+		return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	})
+	return fixed, err
+}
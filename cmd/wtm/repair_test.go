@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/exclude"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestRepair tests repairing the worktrees of a moved root, and relocating it
+func TestRepair(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// A generated file makes the worktree point core.excludesFile into .bare
+	filesDir := config.GetFilesDir(rootDir)
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filesDir, ".env"), []byte("APP=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.AddWorktree(bareDir, "other", filepath.Join(rootDir, "other"), "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addNoHooks = true
+	defer func() { addNoHooks = false }()
+	if err := runAdd(addCmd, []string{"main", "feature"}); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+
+	movedRoot := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(rootDir, movedRoot); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(movedRoot); err != nil {
+		t.Fatal(err)
+	}
+
+	problem, err := checkWorktreeLinks(movedRoot)
+	if err != nil || !strings.Contains(problem, "2 worktree(s) still point to "+rootDir) {
+		t.Errorf("checkWorktreeLinks() = %q, %v, want both worktrees pointing to the old root", problem, err)
+	}
+	if err := runRepair(repairCmd, nil); err != nil {
+		t.Fatalf("runRepair() error = %v", err)
+	}
+	checkRepaired := func(root string) {
+		t.Helper()
+		for _, name := range []string{"feature", "other"} {
+			if branch := gitOutput(t, "-C", filepath.Join(root, name), "rev-parse", "--abbrev-ref", "HEAD"); branch != name {
+				t.Errorf("branch of %s = %q", name, branch)
+			}
+		}
+		featurePath := filepath.Join(root, "feature")
+		excludePath, _ := exclude.Path(featurePath)
+		if current, _ := git.GetWorktreeConfig(featurePath, "core.excludesFile"); current != excludePath {
+			t.Errorf("core.excludesFile = %q, want %q", current, excludePath)
+		}
+		if problem, err := checkWorktreeLinks(root); problem != "" || err != nil {
+			t.Errorf("checkWorktreeLinks() after repair = %q, %v", problem, err)
+		}
+	}
+	checkRepaired(movedRoot)
+
+	if major, minor, _ := git.Version(); major == 2 && minor < 48 {
+		repairRelative = true
+		err := runRepair(repairCmd, nil)
+		repairRelative = false
+		if err == nil || !strings.Contains(err.Error(), "git 2.48 or later") {
+			t.Errorf("runRepair(--relative) error = %v, want a git version error", err)
+		}
+	}
+
+	relocatedRoot := filepath.Join(t.TempDir(), "src", "app")
+	if err := runRelocate(relocateCmd, []string{filepath.Join(movedRoot, "nested")}); exitCode(err) != exitUsage {
+		t.Errorf("runRelocate() into the root error = %v, want a usage error", err)
+	}
+	if err := runRelocate(relocateCmd, []string{relocatedRoot}); err != nil {
+		t.Fatalf("runRelocate() error = %v", err)
+	}
+	if _, err := os.Stat(movedRoot); !os.IsNotExist(err) {
+		t.Error("the old root should be gone")
+	}
+	checkRepaired(relocatedRoot)
+}
+
+// TestRepair_CopiedRoot tests that repairing a copy of a root leaves the
+// original untouched
+func TestRepair_CopiedRoot(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	if err := git.AddWorktree(bareDir, "other", filepath.Join(rootDir, "other"), "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	recordPath := filepath.Join(bareDir, "worktrees", "other", "gitdir")
+	record, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copiedRoot := filepath.Join(t.TempDir(), "copy")
+	if out, err := exec.Command("cp", "-a", rootDir, copiedRoot).CombinedOutput(); err != nil {
+		t.Fatalf("cp -a failed: %v: %s", err, out)
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(copiedRoot); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	if err := runRepair(repairCmd, nil); err != nil {
+		t.Fatalf("runRepair() error = %v", err)
+	}
+
+	for root, want := range map[string]string{rootDir: bareDir, copiedRoot: config.GetBareDir(copiedRoot)} {
+		worktreePath := filepath.Join(root, "other")
+		target, err := git.WorktreeLink(worktreePath)
+		if err != nil || !samePath(filepath.Dir(filepath.Dir(target)), want) {
+			t.Errorf("%s links to %q, %v, want a record in %s", worktreePath, target, err, want)
+		}
+		if branch := gitOutput(t, "-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD"); branch != "other" {
+			t.Errorf("branch of %s = %q", worktreePath, branch)
+		}
+		if problem, err := checkWorktreeLinks(root); problem != "" || err != nil {
+			t.Errorf("checkWorktreeLinks(%s) = %q, %v", root, problem, err)
+		}
+	}
+	if current, _ := os.ReadFile(recordPath); string(current) != string(record) {
+		t.Errorf("the original record changed to %q, want %q", current, record)
+	}
+}
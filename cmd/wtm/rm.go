@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,9 @@ import (
 	"github.com/vansdevcode/worktree-manager/internal/config"
 	"github.com/vansdevcode/worktree-manager/internal/git"
 	"github.com/vansdevcode/worktree-manager/internal/hook"
+	"github.com/vansdevcode/worktree-manager/internal/links"
+	"github.com/vansdevcode/worktree-manager/internal/manifest"
+	"github.com/vansdevcode/worktree-manager/internal/pr"
 	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
 
@@ -19,19 +23,20 @@ var rmCmd = &cobra.Command{
 	Long: `Remove a worktree and optionally delete its branch.
 
 The branch name is determined from the worktree itself, not the directory name.
-If the worktree is in detached HEAD state, use --force to remove anyway.`,
+If the worktree is in detached HEAD state, use --force to remove anyway.
+Locked worktrees (see 'wtm lock') are only removed with --force --force.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runRm,
 }
 
 var (
-	rmForce        bool
+	rmForce        int // Given twice, also removes locked worktrees
 	rmDeleteBranch bool
 	rmNoHooks      bool
 )
 
 func init() {
-	rmCmd.Flags().BoolVarP(&rmForce, "force", "f", false, "Force removal even with uncommitted changes")
+	rmCmd.Flags().CountVarP(&rmForce, "force", "f", "Force removal even with uncommitted changes; twice to remove a locked worktree")
 	rmCmd.Flags().BoolVarP(&rmDeleteBranch, "delete-branch", "d", false, "Also delete the branch")
 	rmCmd.Flags().BoolVar(&rmNoHooks, "no-hooks", false, "Skip running post-delete hooks")
 }
@@ -46,15 +51,19 @@ func runRm(cmd *cobra.Command, args []string) error {
 	}
 
 	bareDir := config.GetBareDir(rootDir)
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		return err
+	}
 
-	// Resolve directory path
-	var worktreePath string
-	if filepath.IsAbs(directory) {
-		worktreePath = directory
-	} else {
-		worktreePath = filepath.Join(rootDir, directory)
+	worktreeLinks, err := links.Resolve(rootDir, settings.Links)
+	if err != nil {
+		return err
 	}
 
+	// Resolve directory path
+	worktreePath := resolveWorktreePath(rootDir, directory)
+
 	// Check if directory exists
 	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
 		return fmt.Errorf("directory '%s' does not exist", directory)
@@ -78,29 +87,51 @@ func runRm(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// A lock protects the worktree even from --force
+	locked, reason, err := git.WorktreeLock(worktreePath)
+	if err != nil {
+		return fmt.Errorf("failed to check whether the worktree is locked: %w", err)
+	}
+	if locked && rmForce < 2 {
+		return withHint(git.ErrWorktreeLocked, "worktree '%s' is %s, unlock it with 'wtm unlock %s' or use --force --force to remove anyway", filepath.Base(worktreePath), lockStatus(reason), filepath.Base(worktreePath))
+	}
+
 	// Safety checks
-	if !rmForce {
+	var managedUntracked bool // Only shared links or generated files are untracked
+	if rmForce == 0 {
 		hasChanges, err := git.HasUncommittedChanges(worktreePath)
 		if err != nil {
 			return fmt.Errorf("failed to check for uncommitted changes: %w", err)
 		}
 		if hasChanges {
-			return fmt.Errorf("worktree has uncommitted changes, use --force to remove anyway")
+			return withHint(git.ErrDirtyWorktree, "worktree has uncommitted changes, use --force to remove anyway")
 		}
 
-		hasUntracked, err := git.HasUntrackedFiles(worktreePath)
+		// Generated files are hidden from git, so check for local edits here
+		generated, err := manifest.Load(rootDir, worktreePath)
+		if err != nil {
+			return err
+		}
+		if edited := generated.Modified(worktreePath); len(edited) > 0 {
+			return withHint(git.ErrDirtyWorktree, "worktree has edited generated files (%s), use --force to remove anyway", strings.Join(edited, ", "))
+		}
+
+		untracked, err := git.UntrackedFiles(worktreePath)
 		if err != nil {
 			return fmt.Errorf("failed to check for untracked files: %w", err)
 		}
-		if hasUntracked {
-			ui.Warning("⚠ Worktree has untracked files")
+
+		// Git refuses to remove them, so refuse before the hook runs
+		if own := unmanagedFiles(worktreePath, untracked, worktreeLinks, generated.Unmodified(worktreePath)); len(own) > 0 {
+			return withHint(git.ErrDirtyWorktree, "worktree has untracked files (%s), use --force to remove anyway", strings.Join(own, ", "))
 		}
+		managedUntracked = len(untracked) > 0
 	}
 
 	// Get actual branch name from worktree before removal (for hooks and branch deletion)
 	branchName, err := git.GetWorktreeBranch(worktreePath)
 	if err != nil {
-		if !rmForce {
+		if rmForce == 0 {
 			return fmt.Errorf("failed to determine branch name: %w (use --force to remove anyway)", err)
 		}
 		// With --force, proceed without hooks/branch deletion
@@ -117,25 +148,43 @@ func runRm(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Remove worktree
+	// Remove worktree. Git deletes shared links without following them, but
+	// counts them (and generated files it was not told to ignore) as untracked
+	// files: when nothing else is untracked, --force only overrides that.
 	ui.Info("Removing worktree...")
-	if rmForce {
+	if locked {
+		if err := git.RemoveLockedWorktree(bareDir, worktreePath); err != nil {
+			return fmt.Errorf("failed to remove worktree: %w", err)
+		}
+	} else if rmForce > 0 || managedUntracked {
 		if err := git.RemoveWorktreeForce(bareDir, worktreePath); err != nil {
 			return fmt.Errorf("failed to remove worktree: %w", err)
 		}
 	} else {
 		if err := git.RemoveWorktree(bareDir, worktreePath); err != nil {
+			if errors.Is(err, git.ErrDirtyWorktree) {
+				return withHint(err, "worktree '%s' has modified or untracked files, use --force to remove anyway", filepath.Base(worktreePath))
+			}
 			return fmt.Errorf("failed to remove worktree: %w", err)
 		}
 	}
 
+	// Forget the worktree's state
+	if err := os.RemoveAll(config.GetWorktreeStateDir(rootDir, filepath.Base(worktreePath))); err != nil {
+		ui.Warning("Failed to remove worktree state: %v", err)
+	}
+
 	// Delete branch if requested
 	if rmDeleteBranch && branchName != "" {
+		// Deleting the branch drops its tracking config, so read the remote first
+		remote, _ := git.GetConfig(bareDir, "branch."+branchName+".remote")
+
 		ui.Info("Deleting branch '%s'...", branchName)
 		if err := git.DeleteBranch(bareDir, branchName); err != nil {
 			ui.Warning("Failed to delete branch: %v", err)
 		} else {
 			ui.Success("✓ Branch deleted")
+			offerForkRemoval(bareDir, remote)
 		}
 	} else if rmDeleteBranch && branchName == "" {
 		ui.Warning("⚠ Cannot delete branch: branch name could not be determined")
@@ -144,3 +193,44 @@ func runRm(cmd *cobra.Command, args []string) error {
 	ui.Success("✓ Worktree removed successfully")
 	return nil
 }
+
+// unmanagedFiles returns the untracked paths that are neither a shared link
+// nor an unmodified generated file
+func unmanagedFiles(worktreePath string, untracked []string, worktreeLinks []links.Link, generated map[string]bool) []string {
+	managed := make(map[string]bool)
+	for _, link := range worktreeLinks {
+		if links.IsManaged(filepath.Join(worktreePath, link.Path), link) {
+			managed[filepath.ToSlash(link.Path)] = true
+		}
+	}
+
+	var unmanaged []string
+	for _, path := range untracked {
+		if !managed[path] && !generated[path] {
+			unmanaged = append(unmanaged, path)
+		}
+	}
+	return unmanaged
+}
+
+// offerForkRemoval offers to remove a remote added for a fork PR once no
+// local branch tracks it anymore
+func offerForkRemoval(bareDir, remote string) {
+	if remote == "" || !pr.IsForkRemote(bareDir, remote) {
+		return
+	}
+	users, err := git.BranchesTrackingRemote(bareDir, remote)
+	if err != nil || len(users) > 0 {
+		return
+	}
+
+	if !ui.Confirm("Remove fork remote '%s'? No other branch uses it.", remote) {
+		ui.Info("Keeping remote '%s' (remove it with: git remote remove %s)", remote, remote)
+		return
+	}
+	if err := git.RemoveRemote(bareDir, remote); err != nil {
+		ui.Warning("Failed to remove remote: %v", err)
+	} else {
+		ui.Success("✓ Remote '%s' removed", remote)
+	}
+}
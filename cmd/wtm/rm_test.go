@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,6 +10,9 @@ import (
 
 	"github.com/vansdevcode/worktree-manager/internal/config"
 	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/links"
+	"github.com/vansdevcode/worktree-manager/internal/manifest"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
 
 // setupTestRepo creates a temporary git repository with bare setup
@@ -230,7 +234,7 @@ func TestRmCommand_DetachedHeadWithoutForce(t *testing.T) {
 	}()
 
 	// Run rm command without --force, should fail
-	rmForce = false
+	rmForce = 0
 	err = runRm(rmCmd, []string{"test-worktree"})
 	if err == nil {
 		t.Errorf("Expected error when removing detached HEAD worktree without --force, got nil")
@@ -282,8 +286,8 @@ func TestRmCommand_DetachedHeadWithForce(t *testing.T) {
 	}()
 
 	// Run rm command with --force, should succeed
-	rmForce = true
-	defer func() { rmForce = false }()
+	rmForce = 1
+	defer func() { rmForce = 0 }()
 
 	err = runRm(rmCmd, []string{"test-worktree"})
 	if err != nil {
@@ -329,7 +333,7 @@ func TestRmCommand_UncommittedChanges(t *testing.T) {
 	}()
 
 	// Run rm command without --force, should fail
-	rmForce = false
+	rmForce = 0
 	err := runRm(rmCmd, []string{"test-worktree"})
 	if err == nil {
 		t.Errorf("Expected error when removing worktree with uncommitted changes, got nil")
@@ -377,8 +381,8 @@ func TestRmCommand_ForceWithUncommittedChanges(t *testing.T) {
 	}()
 
 	// Run rm command with --force, should succeed
-	rmForce = true
-	defer func() { rmForce = false }()
+	rmForce = 1
+	defer func() { rmForce = 0 }()
 
 	err := runRm(rmCmd, []string{"test-worktree"})
 	if err != nil {
@@ -390,3 +394,332 @@ func TestRmCommand_ForceWithUncommittedChanges(t *testing.T) {
 		t.Errorf("Worktree still exists after removal with --force")
 	}
 }
+
+// TestRmCommand_SharedLinks tests that removing a worktree keeps the targets of its shared links
+func TestRmCommand_SharedLinks(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Create worktree
+	worktreePath := filepath.Join(rootDir, "test-worktree")
+	if err := git.AddWorktree(bareDir, "main", worktreePath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	// Link a shared fixtures directory into the worktree
+	sharedDir := filepath.Join(config.GetLinksDir(rootDir), "storage")
+	if err := os.MkdirAll(sharedDir, 0755); err != nil {
+		t.Fatalf("Failed to create links directory: %v", err)
+	}
+	fixture := filepath.Join(sharedDir, "fixture.json")
+	if err := os.WriteFile(fixture, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to create fixture: %v", err)
+	}
+	worktreeLinks, err := links.Resolve(rootDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to resolve links: %v", err)
+	}
+	if err := links.Create(rootDir, worktreePath, worktreeLinks); err != nil {
+		t.Fatalf("Failed to create links: %v", err)
+	}
+
+	// Change to root directory
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldDir); err != nil {
+			t.Errorf("Failed to restore directory: %v", err)
+		}
+	}()
+
+	// Run rm command without --force, links must not block removal
+	rmForce = 0
+	if err := runRm(rmCmd, []string{"test-worktree"}); err != nil {
+		t.Errorf("runRm failed: %v", err)
+	}
+
+	// Verify worktree is removed but the link target survives
+	if _, err := os.Stat(worktreePath); !os.IsNotExist(err) {
+		t.Errorf("Worktree still exists after removal")
+	}
+	if _, err := os.Stat(fixture); err != nil {
+		t.Errorf("Link target was deleted: %v", err)
+	}
+}
+
+// TestRmCommand_SharedLinksKeptOnFailure tests that a refused removal leaves the shared links in place
+func TestRmCommand_SharedLinksKeptOnFailure(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Create worktree with a shared link and an untracked file of its own
+	worktreePath := filepath.Join(rootDir, "test-worktree")
+	if err := git.AddWorktree(bareDir, "main", worktreePath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(config.GetLinksDir(rootDir), "storage"), 0755); err != nil {
+		t.Fatalf("Failed to create links directory: %v", err)
+	}
+	worktreeLinks, err := links.Resolve(rootDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to resolve links: %v", err)
+	}
+	if err := links.Create(rootDir, worktreePath, worktreeLinks); err != nil {
+		t.Fatalf("Failed to create links: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktreePath, "scratch.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatalf("Failed to create untracked file: %v", err)
+	}
+
+	// Change to root directory
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldDir); err != nil {
+			t.Errorf("Failed to restore directory: %v", err)
+		}
+	}()
+
+	// Run rm command without --force, the untracked file blocks removal
+	rmForce = 0
+	if err := runRm(rmCmd, []string{"test-worktree"}); err == nil {
+		t.Fatal("Expected error for untracked file, got nil")
+	}
+
+	// Verify the link is still there
+	if !links.IsManaged(filepath.Join(worktreePath, "storage"), worktreeLinks[0]) {
+		t.Errorf("Shared link was removed although the worktree was kept")
+	}
+}
+
+// TestRmCommand_GeneratedFiles tests that unmodified generated files do not block removal
+func TestRmCommand_GeneratedFiles(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Create worktree
+	worktreePath := filepath.Join(rootDir, "test-worktree")
+	if err := git.AddWorktree(bareDir, "main", worktreePath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	// Generate an untracked file from .worktree/files
+	filesDir := config.GetFilesDir(rootDir)
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatalf("Failed to create files directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(filesDir, "app.conf.tmpl"), []byte("branch={{ .Branch }}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	settings, _ := config.LoadSettings(rootDir)
+	setupWorktree(rootDir, worktreePath, "main", settings)
+
+	if _, err := os.Stat(manifest.Path(rootDir, worktreePath)); err != nil {
+		t.Fatalf("Manifest was not written: %v", err)
+	}
+
+	// Change to root directory
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldDir); err != nil {
+			t.Errorf("Failed to restore directory: %v", err)
+		}
+	}()
+
+	// Run rm command without --force, generated files must not block removal
+	rmForce = 0
+	if err := runRm(rmCmd, []string{"test-worktree"}); err != nil {
+		t.Errorf("runRm failed: %v", err)
+	}
+
+	// Verify worktree and its state are removed
+	if _, err := os.Stat(worktreePath); !os.IsNotExist(err) {
+		t.Errorf("Worktree still exists after removal")
+	}
+	if _, err := os.Stat(config.GetWorktreeStateDir(rootDir, "test-worktree")); !os.IsNotExist(err) {
+		t.Errorf("Worktree state still exists after removal")
+	}
+}
+
+// TestRmCommand_EditedGeneratedFiles tests that locally edited generated files block removal
+func TestRmCommand_EditedGeneratedFiles(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Create worktree with a generated file
+	worktreePath := filepath.Join(rootDir, "test-worktree")
+	if err := git.AddWorktree(bareDir, "main", worktreePath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	filesDir := config.GetFilesDir(rootDir)
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatalf("Failed to create files directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(filesDir, ".env.tmpl"), []byte("BRANCH={{ .Branch }}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	settings, _ := config.LoadSettings(rootDir)
+	setupWorktree(rootDir, worktreePath, "main", settings)
+
+	// Generated files are hidden from git
+	untracked, err := git.UntrackedFiles(worktreePath)
+	if err != nil || len(untracked) != 0 {
+		t.Fatalf("UntrackedFiles() = %v, %v; want generated files excluded", untracked, err)
+	}
+
+	// Edit the generated file
+	if err := os.WriteFile(filepath.Join(worktreePath, ".env"), []byte("BRANCH=local\n"), 0644); err != nil {
+		t.Fatalf("Failed to edit generated file: %v", err)
+	}
+
+	// Change to root directory
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldDir); err != nil {
+			t.Errorf("Failed to restore directory: %v", err)
+		}
+	}()
+
+	// Run rm command without --force, should fail
+	rmForce = 0
+	err = runRm(rmCmd, []string{"test-worktree"})
+	if err == nil {
+		t.Fatal("Expected error for edited generated file, got nil")
+	}
+	if !strings.Contains(err.Error(), "edited generated files") {
+		t.Errorf("Expected error about edited generated files, got: %v", err)
+	}
+
+	// Verify the edit survived
+	content, _ := os.ReadFile(filepath.Join(worktreePath, ".env"))
+	if string(content) != "BRANCH=local\n" {
+		t.Errorf("Edited generated file was changed: %q", content)
+	}
+}
+
+// TestRmCommand_UntrackedFiles tests that untracked files refuse removal before generated files are touched
+func TestRmCommand_UntrackedFiles(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	// Create worktree with a generated file and an untracked file of its own
+	worktreePath := filepath.Join(rootDir, "test-worktree")
+	if err := git.AddWorktree(bareDir, "main", worktreePath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	filesDir := config.GetFilesDir(rootDir)
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatalf("Failed to create files directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(filesDir, ".env"), []byte("DEBUG=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	settings, _ := config.LoadSettings(rootDir)
+	setupWorktree(rootDir, worktreePath, "main", settings)
+	if err := os.WriteFile(filepath.Join(worktreePath, "scratch.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatalf("Failed to create untracked file: %v", err)
+	}
+
+	// Change to root directory
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldDir); err != nil {
+			t.Errorf("Failed to restore directory: %v", err)
+		}
+	}()
+
+	// Run rm command without --force, should fail
+	rmForce = 0
+	err := runRm(rmCmd, []string{"test-worktree"})
+	if !errors.Is(err, git.ErrDirtyWorktree) || !strings.Contains(err.Error(), "scratch.txt") {
+		t.Fatalf("Expected untracked files error naming scratch.txt, got: %v", err)
+	}
+
+	// Verify the worktree kept its generated file
+	if _, err := os.Stat(filepath.Join(worktreePath, ".env")); err != nil {
+		t.Errorf("Generated file was removed although the worktree was kept: %v", err)
+	}
+}
+
+// TestRmCommand_ForkRemote tests that deleting the last branch of a fork offers to remove its remote
+func TestRmCommand_ForkRemote(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     string
+		otherUser  bool
+		wantRemote bool
+	}{
+		{name: "confirmed", answer: "y\n", wantRemote: false},
+		{name: "declined", answer: "n\n", wantRemote: true},
+		{name: "closed input", answer: "", wantRemote: true},
+		{name: "still used", answer: "y\n", otherUser: true, wantRemote: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootDir, bareDir, cleanup := setupTestRepo(t)
+			defer cleanup()
+
+			// A fork remote as wtm pr adds it, tracked by the PR branch
+			branches := []string{"fix"}
+			if tt.otherUser {
+				branches = append(branches, "other")
+			}
+			runGitCmd(t, "--git-dir="+bareDir, "remote", "add", "octocat", "https://github.com/octocat/widgets.git")
+			runGitCmd(t, "--git-dir="+bareDir, "config", "remote.octocat.wtm-fork", "true")
+			for _, branch := range branches {
+				runGitCmd(t, "--git-dir="+bareDir, "update-ref", "refs/remotes/octocat/"+branch, "main")
+				runGitCmd(t, "--git-dir="+bareDir, "branch", "--track", branch, "octocat/"+branch)
+			}
+
+			worktreePath := filepath.Join(rootDir, "pr-7")
+			if err := git.AddWorktree(bareDir, "fix", worktreePath, ""); err != nil {
+				t.Fatalf("Failed to create worktree: %v", err)
+			}
+
+			oldDir, _ := os.Getwd()
+			if err := os.Chdir(rootDir); err != nil {
+				t.Fatalf("Failed to change to root directory: %v", err)
+			}
+			defer func() { _ = os.Chdir(oldDir) }()
+
+			oldInput := ui.Input
+			ui.Input = strings.NewReader(tt.answer)
+			defer func() { ui.Input = oldInput }()
+
+			rmForce = 0
+			rmDeleteBranch = true
+			defer func() { rmDeleteBranch = false }()
+			if err := runRm(rmCmd, []string{"pr-7"}); err != nil {
+				t.Fatalf("runRm failed: %v", err)
+			}
+
+			remotes, _ := git.ListRemotes(bareDir)
+			hasRemote := len(remotes) == 1 && remotes[0] == "octocat"
+			if hasRemote != tt.wantRemote {
+				t.Errorf("Remotes after rm = %v, want fork remote kept: %v", remotes, tt.wantRemote)
+			}
+		})
+	}
+}
+
+// runGitCmd runs a git command and fails the test on error
+func runGitCmd(t *testing.T, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %s", strings.Join(args, " "), output)
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/runner"
 	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
 
@@ -17,24 +22,46 @@ templates, hooks, and safety checks.
 Can be used as a standalone CLI (wtm) or as a GitHub CLI extension (gh wtm).`,
 	SilenceErrors: true,
 	SilenceUsage:  true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Run git, gh and glab under the command's context, tracing them on request
+		var trace io.Writer
+		if verbose || runner.TraceEnabled() {
+			trace = os.Stderr
+		}
+		runner.Use(cmd.Context(), &runner.Exec{Trace: trace})
+	},
 }
 
+var verbose bool
+
 // getBinaryName returns the name of the binary being executed
 func getBinaryName() string {
 	return filepath.Base(os.Args[0])
 }
 
 func init() {
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log each git, gh and glab command with its duration (or set WTM_TRACE=1)")
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(rmCmd)
 	rootCmd.AddCommand(lsCmd)
 	rootCmd.AddCommand(prCmd)
+	rootCmd.AddCommand(mrCmd)
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	// Interrupting wtm cancels the commands it is running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	markUsageErrors(rootCmd)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err}
+	})
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		ui.Error("%v", err)
-		os.Exit(1)
+		stop()
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/exclude"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/links"
+	"github.com/vansdevcode/worktree-manager/internal/manifest"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+	"github.com/vansdevcode/worktree-manager/internal/seed"
+	"github.com/vansdevcode/worktree-manager/internal/template"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+// setupWorktree populates a freshly created worktree: it checks out
+// submodules and Git LFS files as configured, processes the files directory
+// of .worktree/, records the generated files in the worktree's manifest and
+// git exclude rules, and creates the shared links.
+// Failures are reported as warnings since the worktree itself already exists.
+func setupWorktree(rootDir, worktreePath, branch string, settings *config.Settings) {
+	setupSubmodules(rootDir, worktreePath, settings.Submodules)
+	setupLFS(worktreePath, settings.LFS)
+
+	// Process files
+	filesDir := config.GetFilesDir(rootDir)
+	if _, err := os.Stat(filesDir); err == nil {
+		ui.Info("Processing files...")
+		data := template.TemplateData{
+			Branch:        branch,
+			Directory:     worktreePath,
+			RootDirectory: rootDir,
+			SparseProfile: sparseProfile(rootDir, worktreePath),
+		}
+		generated, err := template.Generate(filesDir, worktreePath, data, settings.Datasources)
+		if err != nil {
+			ui.Warning("Failed to process files: %v", err)
+		}
+
+		// Record what was generated, even after a partial failure
+		if err := recordGenerated(rootDir, worktreePath, manifest.New(branch, generated, time.Now())); err != nil {
+			ui.Warning("Failed to record generated files: %v", err)
+		}
+	}
+
+	// Create shared links
+	worktreeLinks, err := links.Resolve(rootDir, settings.Links)
+	if err != nil {
+		ui.Warning("Failed to resolve links: %v", err)
+		return
+	}
+	if len(worktreeLinks) > 0 {
+		ui.Info("Linking shared files...")
+		if err := links.Create(rootDir, worktreePath, worktreeLinks); err != nil {
+			ui.Warning("Failed to create links: %v", err)
+		}
+	}
+}
+
+// setupSubmodules checks out a new worktree's submodules as configured:
+// recursively by default, shallow, only some paths, or not at all
+func setupSubmodules(rootDir, worktreePath string, settings config.SubmoduleSettings) {
+	if !git.HasSubmodules(worktreePath) {
+		return
+	}
+
+	opts := git.SubmoduleOptions{Paths: settings.Paths}
+	switch settings.Mode {
+	case "", "recursive":
+		opts.Recursive = true
+	case "shallow":
+		opts.Recursive, opts.Depth = true, 1
+	case "off":
+		ui.Info("Skipping submodules (submodules: off)")
+		return
+	default:
+		ui.Warning("⚠ Skipping submodules: unknown mode '%s' (use off, shallow or recursive)", settings.Mode)
+		return
+	}
+
+	submodules, err := git.Submodules(worktreePath)
+	if err != nil {
+		ui.Warning("⚠ Failed to read .gitmodules: %v", err)
+		return
+	}
+	selected := selectSubmodules(submodules, settings.Paths)
+	if len(selected) == 0 {
+		return
+	}
+
+	if settings.Shared {
+		if store, ok := shareSubmodules(rootDir, worktreePath, selected, settings.Paths); ok {
+			opts.Reference = store
+		}
+	}
+
+	ui.Info("Checking out %d submodule(s)...", len(selected))
+	if err := git.UpdateSubmodules(worktreePath, opts); err != nil {
+		ui.Warning("⚠ Failed to check out submodules: %v", err)
+		ui.Warning("  Retry with 'git submodule update --init --recursive' in %s", filepath.Base(worktreePath))
+		return
+	}
+	for _, submodule := range selected {
+		ui.Info("  %s", submodule.Path)
+	}
+}
+
+// selectSubmodules returns the submodules at the configured paths, or all
+// of them without paths, warning about paths that are not submodules
+func selectSubmodules(submodules []git.Submodule, paths []string) []git.Submodule {
+	if len(paths) == 0 {
+		return submodules
+	}
+
+	var selected []git.Submodule
+	for _, path := range paths {
+		found := false
+		for _, submodule := range submodules {
+			if submodule.Path == strings.TrimSuffix(path, "/") {
+				selected = append(selected, submodule)
+				found = true
+			}
+		}
+		if !found {
+			ui.Warning("⚠ Submodule path '%s' is not declared in .gitmodules", path)
+		}
+	}
+	return selected
+}
+
+// shareSubmodules fetches the submodules into the shared module store, so
+// they borrow its objects instead of being cloned again for every worktree.
+// It returns the store, and false when there is none to use.
+func shareSubmodules(rootDir, worktreePath string, submodules []git.Submodule, paths []string) (string, bool) {
+	store := config.GetModulesDir(rootDir)
+
+	// Resolve the submodule URLs (they may be relative to origin)
+	if err := git.InitSubmodules(worktreePath, paths); err != nil {
+		ui.Warning("⚠ Not using the shared module store: %v", err)
+		return "", false
+	}
+	ui.Info("Updating the shared module store...")
+	for _, submodule := range submodules {
+		url, err := git.SubmoduleURL(worktreePath, submodule.Name)
+		if err == nil {
+			err = git.FetchModuleStore(store, submodule.Name, url)
+		}
+		if err != nil {
+			ui.Warning("⚠ Could not fetch %s into the shared module store: %v", submodule.Path, err)
+		}
+	}
+
+	if _, err := os.Stat(store); err != nil {
+		return "", false
+	}
+	return store, true
+}
+
+// setupLFS downloads a new worktree's Git LFS files, filtered by the
+// configured include and exclude patterns
+func setupLFS(worktreePath string, settings config.LFSSettings) {
+	if !git.UsesLFS(worktreePath) {
+		return
+	}
+	if settings.Skip {
+		ui.Info("Leaving Git LFS files as pointers (lfs.skip)")
+		return
+	}
+	if _, err := runner.LookPath("git-lfs"); err != nil {
+		ui.Warning("⚠ The repository uses Git LFS, but git-lfs is not installed: LFS files are left as pointers")
+		return
+	}
+
+	ui.Info("Pulling Git LFS files...")
+	if err := git.PullLFS(worktreePath, settings.Include, settings.Exclude); err != nil {
+		ui.Warning("⚠ Failed to pull Git LFS files: %v", err)
+		ui.Warning("  Retry with 'git lfs pull' in %s", filepath.Base(worktreePath))
+	}
+}
+
+// recordGenerated saves the worktree's manifest of generated files and keeps
+// its git exclude rules in sync with it
+func recordGenerated(rootDir, worktreePath string, generated *manifest.Manifest) error {
+	if err := manifest.Save(rootDir, worktreePath, generated); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(generated.Files))
+	for _, entry := range generated.Files {
+		paths = append(paths, entry.Path)
+	}
+	return exclude.Sync(config.GetBareDir(rootDir), worktreePath, paths)
+}
+
+// seedWorktree copies the configured ignored directories (vendor/, node_modules/, ...)
+// from another worktree into a new one. Failures are reported as warnings.
+func seedWorktree(rootDir, worktreePath, from string, settings config.SeedSettings) {
+	if len(settings.Paths) == 0 {
+		ui.Warning("⚠ Nothing to seed: no seed paths configured in %s", config.GetSettingsPath(rootDir))
+		return
+	}
+
+	method, err := seed.ParseMethod(settings.Method)
+	if err != nil {
+		ui.Warning("Failed to seed worktree: %v", err)
+		return
+	}
+
+	sourcePath := resolveWorktreePath(rootDir, from)
+	if _, err := os.Stat(sourcePath); err != nil {
+		ui.Warning("Failed to seed worktree: source worktree '%s' does not exist", from)
+		return
+	}
+
+	ui.Info("Seeding from %s...", from)
+	results, err := seed.Seed(sourcePath, worktreePath, settings.Paths, method)
+	for _, result := range results {
+		if result.Skipped != "" {
+			ui.Info("  %s: skipped (%s)", result.Path, result.Skipped)
+		} else {
+			ui.Info("  %s: %d files (%s)", result.Path, result.Files, result.Method)
+		}
+	}
+	if err != nil {
+		ui.Warning("Failed to seed worktree: %v", err)
+	}
+}
+
+// sparseProfile returns the sparse-checkout profile recorded for a worktree,
+// or "" for a full checkout
+func sparseProfile(rootDir, worktreePath string) string {
+	meta, err := metadata.Load(rootDir, worktreePath)
+	if err != nil {
+		return ""
+	}
+	return meta.Sparse
+}
+
+// resolveWorktreePath resolves a worktree directory argument relative to the root
+func resolveWorktreePath(rootDir, directory string) string {
+	if filepath.IsAbs(directory) {
+		return directory
+	}
+	return filepath.Join(rootDir, directory)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestSetupSubmodules tests checking out only some submodules from the shared
+// module store, and skipping them
+func TestSetupSubmodules(t *testing.T) {
+	// Submodules from local paths need the file protocol
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	mainPath := filepath.Join(rootDir, "main")
+	if err := git.AddWorktree(bareDir, "main", mainPath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	for _, name := range []string{"lib", "assets"} {
+		moduleDir := filepath.Join(t.TempDir(), name+".git")
+		if err := git.InitBare(moduleDir); err != nil {
+			t.Fatalf("Failed to init %s: %v", name, err)
+		}
+		if err := git.CreateInitialBranch(moduleDir, "main"); err != nil {
+			t.Fatalf("Failed to create initial branch: %v", err)
+		}
+		runGitCmd(t, "-C", mainPath, "submodule", "add", "--quiet", moduleDir, name)
+	}
+	runGitCmd(t, "-C", mainPath, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-m", "Add submodules")
+
+	if err := os.MkdirAll(config.GetWorktreeDir(rootDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeSettings := func(settings string) {
+		t.Helper()
+		if err := os.WriteFile(config.GetSettingsPath(rootDir), []byte(settings), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSettings("submodules:\n  paths: [lib]\n  shared: true\n")
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addNoHooks = true
+	defer func() { addNoHooks = false }()
+
+	if err := runAdd(addCmd, []string{"main", "feature"}); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+	featurePath := filepath.Join(rootDir, "feature")
+	if _, err := os.Stat(filepath.Join(featurePath, "lib", ".git")); err != nil {
+		t.Error("lib should be checked out")
+	}
+	if _, err := os.Stat(filepath.Join(featurePath, "assets", ".git")); err == nil {
+		t.Error("assets is not configured and should not be checked out")
+	}
+
+	store := config.GetModulesDir(rootDir)
+	if _, err := git.ResolveCommit(store, "refs/modules/lib/heads/main"); err != nil {
+		t.Errorf("the shared module store should have lib's main: %v", err)
+	}
+	if value, _ := git.GetConfig(store, "gc.auto"); value != "0" {
+		t.Errorf("gc.auto of the shared module store = %q, want 0 so borrowed objects are never pruned", value)
+	}
+	alternates := gitOutput(t, "-C", filepath.Join(featurePath, "lib"), "rev-parse", "--git-path", "objects/info/alternates")
+	if !filepath.IsAbs(alternates) {
+		alternates = filepath.Join(featurePath, "lib", alternates)
+	}
+	if content, err := os.ReadFile(alternates); err != nil || !strings.Contains(string(content), store) {
+		t.Errorf("lib alternates = %q, %v, want the shared module store", content, err)
+	}
+
+	writeSettings("submodules: off\n")
+	if err := runAdd(addCmd, []string{"main", "other"}); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "other", "lib", ".git")); err == nil {
+		t.Error("submodules should not be checked out with submodules: off")
+	}
+}
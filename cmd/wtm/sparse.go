@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
+	"github.com/vansdevcode/worktree-manager/pkg/ui"
+)
+
+var sparseCmd = &cobra.Command{
+	Use:   "sparse <worktree> [profile]",
+	Short: "Show or change the sparse-checkout profile of a worktree",
+	Long: `Show or change the sparse-checkout profile of a worktree.
+
+Profiles are named lists of directories in .worktree/config.yaml, checked
+out in cone mode:
+
+  sparse:
+    api:
+      - services/api
+      - libs
+
+Without a profile, shows the worktree's current profile. New worktrees
+can start sparse with 'wtm add ... --sparse <profile>'.
+
+Examples:
+  wtm sparse feature-x           # Show the profile
+  wtm sparse feature-x api       # Only check out the api directories
+  wtm sparse feature-x --disable # Check out all files again`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runSparse,
+}
+
+var sparseDisable bool
+
+func init() {
+	sparseCmd.Flags().BoolVar(&sparseDisable, "disable", false, "Check out all files again")
+	rootCmd.AddCommand(sparseCmd)
+}
+
+func runSparse(cmd *cobra.Command, args []string) error {
+	rootDir, err := config.FindRoot()
+	if err != nil {
+		return fmt.Errorf("not in a worktree-managed repository (no .bare directory found)")
+	}
+	if sparseDisable && len(args) > 1 {
+		return &usageError{fmt.Errorf("--disable does not take a profile")}
+	}
+
+	worktreePath, err := worktreeArg(rootDir, args[:1])
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(worktreePath)
+
+	switch {
+	case sparseDisable:
+		ui.Info("Checking out all files in %s...", name)
+		if err := git.DisableSparseCheckout(worktreePath); err != nil {
+			return err
+		}
+		if err := recordSparseProfile(rootDir, worktreePath, ""); err != nil {
+			return err
+		}
+		ui.Success("✓ %s checks out all files", name)
+
+	case len(args) > 1:
+		settings, err := config.LoadSettings(rootDir)
+		if err != nil {
+			return err
+		}
+		paths, err := settings.SparsePaths(args[1])
+		if err != nil {
+			return &usageError{err}
+		}
+
+		ui.Info("Applying sparse profile %s to %s...", args[1], name)
+		if err := git.SetSparseCheckout(worktreePath, paths); err != nil {
+			return err
+		}
+		if err := recordSparseProfile(rootDir, worktreePath, args[1]); err != nil {
+			return err
+		}
+		ui.Success("✓ %s checks out %s", name, strings.Join(paths, ", "))
+
+	default:
+		paths, err := git.SparseCheckoutPatterns(worktreePath)
+		if err != nil {
+			return err
+		}
+		switch profile := sparseProfile(rootDir, worktreePath); {
+		case len(paths) == 0:
+			ui.Info("%s: full checkout", name)
+		case profile == "":
+			ui.Info("%s: sparse, without profile (%s)", name, strings.Join(paths, ", "))
+		default:
+			ui.Info("%s: %s (%s)", name, profile, strings.Join(paths, ", "))
+		}
+	}
+	return nil
+}
+
+// recordSparseProfile records the sparse-checkout profile of a worktree in
+// its metadata, for templates, hooks and 'wtm sparse'
+func recordSparseProfile(rootDir, worktreePath, profile string) error {
+	meta, err := metadata.Load(rootDir, worktreePath)
+	if err != nil {
+		return err
+	}
+	meta.Sparse = profile
+	return metadata.Save(rootDir, worktreePath, meta)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestSparse tests adding a worktree with a sparse profile and changing it later
+func TestSparse(t *testing.T) {
+	rootDir, bareDir, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	mainPath := filepath.Join(rootDir, "main")
+	if err := git.AddWorktree(bareDir, "main", mainPath, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	for _, file := range []string{"README.md", "services/api/main.go", "services/web/index.html", "libs/util/util.go"} {
+		path := filepath.Join(mainPath, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGitCmd(t, "-C", mainPath, "add", ".")
+	runGitCmd(t, "-C", mainPath, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-m", "Add services")
+
+	filesDir := config.GetFilesDir(rootDir)
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	settings := "sparse:\n  api:\n    - services/api\n    - libs\n  web:\n    paths: [services/web]\n"
+	if err := os.WriteFile(config.GetSettingsPath(rootDir), []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filesDir, "profile.txt.tmpl"), []byte("{{ .SparseProfile }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(rootDir); err != nil {
+		t.Fatalf("Failed to change to root directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+
+	addNoHooks, addSparse = true, "nope"
+	defer func() { addNoHooks, addSparse = false, "" }()
+	if err := runAdd(addCmd, []string{"main", "feature"}); exitCode(err) != exitUsage {
+		t.Errorf("runAdd(--sparse nope) error = %v, want a usage error", err)
+	}
+
+	addSparse = "api"
+	if err := runAdd(addCmd, []string{"main", "feature"}); err != nil {
+		t.Fatalf("runAdd(--sparse api) error = %v", err)
+	}
+	featurePath := filepath.Join(rootDir, "feature")
+	checkFiles := func(want map[string]bool) {
+		t.Helper()
+		for file, present := range want {
+			if _, err := os.Stat(filepath.Join(featurePath, file)); (err == nil) != present {
+				t.Errorf("%s present = %v, want %v", file, err == nil, present)
+			}
+		}
+	}
+	checkFiles(map[string]bool{"README.md": true, "services/api/main.go": true, "libs/util/util.go": true, "services/web/index.html": false})
+	if content, _ := os.ReadFile(filepath.Join(featurePath, "profile.txt")); string(content) != "api" {
+		t.Errorf("profile.txt = %q, want the profile name", content)
+	}
+	if _, err := os.Stat(filepath.Join(mainPath, "services/web/index.html")); err != nil {
+		t.Error("the main worktree should keep a full checkout")
+	}
+
+	if err := runSparse(sparseCmd, []string{"feature", "web"}); err != nil {
+		t.Fatalf("runSparse(web) error = %v", err)
+	}
+	checkFiles(map[string]bool{"services/web/index.html": true, "services/api/main.go": false})
+	if profile := sparseProfile(rootDir, featurePath); profile != "web" {
+		t.Errorf("sparse profile = %q, want web", profile)
+	}
+
+	sparseDisable = true
+	defer func() { sparseDisable = false }()
+	if err := runSparse(sparseCmd, []string{"feature"}); err != nil {
+		t.Fatalf("runSparse(--disable) error = %v", err)
+	}
+	checkFiles(map[string]bool{"services/web/index.html": true, "services/api/main.go": true})
+	if patterns, err := git.SparseCheckoutPatterns(featurePath); err != nil || patterns != nil {
+		t.Errorf("SparseCheckoutPatterns() = %v, %v, want a full checkout", patterns, err)
+	}
+	if profile := sparseProfile(rootDir, featurePath); profile != "" {
+		t.Errorf("sparse profile = %q, want none", profile)
+	}
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds the configuration for the worktree manager
@@ -24,9 +29,9 @@ func FindRoot() (string, error) {
 		bareDir := filepath.Join(currentDir, ".bare")
 
 		if fi, err := os.Lstat(bareDir); err == nil {
-            if fi.Mode()&os.ModeSymlink == 0 && fi.IsDir() {
-                return currentDir, nil
-            }
+			if fi.Mode()&os.ModeSymlink == 0 && fi.IsDir() {
+				return currentDir, nil
+			}
 		}
 
 		parent := filepath.Dir(currentDir)
@@ -54,7 +59,203 @@ func GetFilesDir(rootDir string) string {
 	return filepath.Join(rootDir, ".worktree", "files")
 }
 
+// GetHooksDir returns the path to the hooks directory
+func GetHooksDir(rootDir string) string {
+	return filepath.Join(rootDir, ".worktree", "hooks")
+}
+
 // GetHookPath returns the path to a hook script
 func GetHookPath(rootDir, hookName string) string {
 	return filepath.Join(rootDir, ".worktree", "hooks", hookName)
 }
+
+// GetLinksDir returns the path to the links directory
+func GetLinksDir(rootDir string) string {
+	return filepath.Join(rootDir, ".worktree", "links")
+}
+
+// GetSharedDir returns the path to the per-root shared directory used as
+// the default target of links declared in config.yaml
+func GetSharedDir(rootDir string) string {
+	return filepath.Join(rootDir, ".worktree", "shared")
+}
+
+// GetStateDir returns the path to the state directory, where wtm records
+// per-worktree data it generates
+func GetStateDir(rootDir string) string {
+	return filepath.Join(rootDir, ".worktree", "state")
+}
+
+// GetWorktreeStateDir returns the path to the state directory of one worktree
+func GetWorktreeStateDir(rootDir, worktreeName string) string {
+	return filepath.Join(rootDir, ".worktree", "state", worktreeName)
+}
+
+// GetModulesDir returns the path to the shared module store, a bare repository
+// new worktrees' submodules borrow objects from
+func GetModulesDir(rootDir string) string {
+	return filepath.Join(rootDir, ".worktree", "modules")
+}
+
+// GetSettingsPath returns the path to the settings file
+func GetSettingsPath(rootDir string) string {
+	return filepath.Join(rootDir, ".worktree", "config.yaml")
+}
+
+// Settings holds the user configuration read from .worktree/config.yaml
+type Settings struct {
+	Links       []LinkSpec               `yaml:"links"`
+	Seed        SeedSettings             `yaml:"seed"`
+	Datasources map[string]Datasource    `yaml:"datasources"`
+	Forges      map[string]ForgeSpec     `yaml:"forges"`
+	Issues      IssueSettings            `yaml:"issues"`
+	Sparse      map[string]SparseProfile `yaml:"sparse"`
+	Submodules  SubmoduleSettings        `yaml:"submodules"`
+	LFS         LFSSettings              `yaml:"lfs"`
+}
+
+// LinkSpec declares a path in each worktree that is a symlink to shared content
+type LinkSpec struct {
+	Path   string `yaml:"path"`   // Path inside the worktree (e.g., "storage")
+	Source string `yaml:"source"` // Link target, relative to the root; defaults to .worktree/shared/<path>
+}
+
+// SeedSettings configures copying ignored artifacts from an existing worktree
+type SeedSettings struct {
+	From   string     `yaml:"from"`   // Worktree directory to seed from by default
+	Method string     `yaml:"method"` // auto, reflink, hardlink or copy (default: auto)
+	Paths  []SeedPath `yaml:"paths"`
+}
+
+// SeedPath is a directory copied into new worktrees when seeding
+type SeedPath struct {
+	Path     string `yaml:"path"`     // Path inside the worktree (e.g., "node_modules")
+	Lockfile string `yaml:"lockfile"` // Only seed when this file is identical in both worktrees
+}
+
+// Datasource declares a named gomplate-style datasource available as {{ ds "name" }}
+type Datasource struct {
+	Path string `yaml:"path"` // File or directory, relative to the root
+	Type string `yaml:"type"` // Optional MIME type or alias (json, yaml, toml, env, csv, text)
+}
+
+// UnmarshalYAML allows a datasource to be declared as a plain path
+func (d *Datasource) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		d.Path = value.Value
+		return nil
+	}
+
+	type plain Datasource
+	return value.Decode((*plain)(d))
+}
+
+// ForgeSpec overrides forge detection for a host (e.g., a self-hosted Gitea)
+type ForgeSpec struct {
+	Type   string `yaml:"type"`    // github, gitlab, gitea (also forgejo) or bitbucket
+	APIURL string `yaml:"api_url"` // Optional REST API base URL
+}
+
+// UnmarshalYAML allows a forge to be declared by its type alone
+func (f *ForgeSpec) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		f.Type = value.Value
+		return nil
+	}
+
+	type plain ForgeSpec
+	return value.Decode((*plain)(f))
+}
+
+// IssueSettings configures the branches of worktrees created from issues (issue/<n>)
+type IssueSettings struct {
+	Branch      string            `yaml:"branch"`       // Branch name template with .Type, .Number, .Title and .Labels
+	MaxLength   int               `yaml:"max_length"`   // Branch names are truncated to this length (default: 60)
+	Types       map[string]string `yaml:"types"`        // Issue label to .Type (default: bug -> fix)
+	DefaultType string            `yaml:"default_type"` // .Type of issues without a mapped label (default: feature)
+}
+
+// SparseProfile is a named set of cone-mode sparse-checkout directories
+// (e.g., services/api and libs) that worktrees can be restricted to
+type SparseProfile struct {
+	Paths []string `yaml:"paths"` // Directories to check out, relative to the repository root
+}
+
+// UnmarshalYAML allows a sparse profile to be declared as a plain list of paths
+func (p *SparseProfile) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&p.Paths)
+	}
+
+	type plain SparseProfile
+	return value.Decode((*plain)(p))
+}
+
+// SparsePaths returns the directories of the named sparse profile
+func (s *Settings) SparsePaths(name string) ([]string, error) {
+	profile, ok := s.Sparse[name]
+	if !ok {
+		names := make([]string, 0, len(s.Sparse))
+		for n := range s.Sparse {
+			names = append(names, n)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown sparse profile '%s': no profiles configured in .worktree/config.yaml", name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown sparse profile '%s' (available: %s)", name, strings.Join(names, ", "))
+	}
+	if len(profile.Paths) == 0 {
+		return nil, fmt.Errorf("sparse profile '%s' has no paths", name)
+	}
+	return profile.Paths, nil
+}
+
+// SubmoduleSettings configures how new worktrees' submodules are checked out
+type SubmoduleSettings struct {
+	Mode   string   `yaml:"mode"`   // off, shallow or recursive (default: recursive)
+	Paths  []string `yaml:"paths"`  // Only check out these submodules
+	Shared bool     `yaml:"shared"` // Borrow objects from a shared module store in .worktree/modules
+}
+
+// UnmarshalYAML allows submodules to be configured by their mode alone, or
+// as a plain list of paths
+func (s *SubmoduleSettings) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		s.Mode = value.Value
+		return nil
+	case yaml.SequenceNode:
+		return value.Decode(&s.Paths)
+	}
+
+	type plain SubmoduleSettings
+	return value.Decode((*plain)(s))
+}
+
+// LFSSettings configures downloading Git LFS files into new worktrees
+type LFSSettings struct {
+	Skip    bool     `yaml:"skip"`    // Leave LFS files as pointers
+	Include []string `yaml:"include"` // Only pull files matching these patterns
+	Exclude []string `yaml:"exclude"` // Do not pull files matching these patterns
+}
+
+// LoadSettings reads .worktree/config.yaml from the root directory.
+// A missing file yields empty settings.
+func LoadSettings(rootDir string) (*Settings, error) {
+	settings := &Settings{}
+
+	content, err := os.ReadFile(GetSettingsPath(rootDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(content, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", GetSettingsPath(rootDir), err)
+	}
+
+	return settings, nil
+}
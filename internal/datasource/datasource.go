@@ -0,0 +1,243 @@
+package datasource
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/hairyhenderson/gomplate/v4"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported content types, matching the MIME types gomplate uses
+const (
+	TypeJSON = "application/json"
+	TypeYAML = "application/yaml"
+	TypeTOML = "application/toml"
+	TypeEnv  = "application/x-env"
+	TypeCSV  = "text/csv"
+	TypeText = "text/plain"
+)
+
+// typeAliases maps short names and common MIME variants to a supported type
+var typeAliases = map[string]string{
+	"json":               TypeJSON,
+	"yaml":               TypeYAML,
+	"yml":                TypeYAML,
+	"toml":               TypeTOML,
+	"env":                TypeEnv,
+	"dotenv":             TypeEnv,
+	"csv":                TypeCSV,
+	"text":               TypeText,
+	"txt":                TypeText,
+	"application/x-yaml": TypeYAML,
+	"text/yaml":          TypeYAML,
+	"text/json":          TypeJSON,
+}
+
+// TemplateFuncs returns gomplate's functions plus the datasource functions
+// (ds, datasource, include, datasourceExists, listDatasources) for the
+// datasources declared in the root's config.yaml. Callers load the settings
+// once and reuse them for every file they render.
+func TemplateFuncs(ctx context.Context, rootDir string, sources map[string]config.Datasource) template.FuncMap {
+	funcMap := gomplate.CreateFuncs(ctx)
+	for name, fn := range NewReader(rootDir, sources).Funcs() {
+		funcMap[name] = fn
+	}
+	return funcMap
+}
+
+// Reader reads and parses the declared datasources, caching each read
+type Reader struct {
+	rootDir string
+	sources map[string]config.Datasource
+	cache   map[string]any
+}
+
+// NewReader creates a reader for datasources whose paths are relative to rootDir
+func NewReader(rootDir string, sources map[string]config.Datasource) *Reader {
+	return &Reader{
+		rootDir: rootDir,
+		sources: sources,
+		cache:   make(map[string]any),
+	}
+}
+
+// Funcs returns the template functions backed by this reader
+func (r *Reader) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"ds":               r.Datasource,
+		"datasource":       r.Datasource,
+		"include":          r.Include,
+		"datasourceExists": r.Exists,
+		"listDatasources":  r.List,
+	}
+}
+
+// Datasource reads and parses a datasource. An optional sub-path selects a
+// file inside a directory datasource.
+func (r *Reader) Datasource(alias string, args ...string) (any, error) {
+	path, mimeType, err := r.resolve(alias, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := r.cache[path]; ok {
+		return value, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("datasource %q: %w", alias, err)
+	}
+
+	value, err := Parse(mimeType, string(content))
+	if err != nil {
+		return nil, fmt.Errorf("datasource %q: %w", alias, err)
+	}
+
+	r.cache[path] = value
+	return value, nil
+}
+
+// Include returns the raw content of a datasource
+func (r *Reader) Include(alias string, args ...string) (string, error) {
+	path, _, err := r.resolve(alias, args...)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("datasource %q: %w", alias, err)
+	}
+	return string(content), nil
+}
+
+// Exists reports whether a datasource is declared
+func (r *Reader) Exists(alias string) bool {
+	_, ok := r.sources[alias]
+	return ok
+}
+
+// List returns the declared datasource names, sorted
+func (r *Reader) List() []string {
+	names := make([]string, 0, len(r.sources))
+	for name := range r.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve returns the absolute file path and content type for a datasource.
+// A sub-path must stay inside the datasource directory.
+func (r *Reader) resolve(alias string, args ...string) (string, string, error) {
+	source, ok := r.sources[alias]
+	if !ok {
+		return "", "", fmt.Errorf("undefined datasource %q", alias)
+	}
+	if source.Path == "" {
+		return "", "", fmt.Errorf("datasource %q has no path", alias)
+	}
+	if strings.Contains(source.Path, "://") {
+		return "", "", fmt.Errorf("datasource %q: only local files are supported, got %s", alias, source.Path)
+	}
+
+	path := source.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.rootDir, path)
+	}
+	if len(args) > 0 {
+		subPath := filepath.Join(args...)
+		if subPath == "" || filepath.IsAbs(subPath) || subPath == "." || subPath == ".." || strings.HasPrefix(subPath, ".."+string(filepath.Separator)) {
+			return "", "", fmt.Errorf("datasource %q: invalid sub-path %q, must be inside the datasource directory", alias, strings.Join(args, "/"))
+		}
+		path = filepath.Join(path, subPath)
+	}
+
+	mimeType, err := detectType(path, source.Type)
+	if err != nil {
+		return "", "", fmt.Errorf("datasource %q: %w", alias, err)
+	}
+
+	return path, mimeType, nil
+}
+
+// detectType returns the declared type, or guesses it from the file name
+func detectType(path, declared string) (string, error) {
+	if declared != "" {
+		declared = strings.ToLower(strings.TrimSpace(strings.Split(declared, ";")[0]))
+		if alias, ok := typeAliases[declared]; ok {
+			return alias, nil
+		}
+		switch declared {
+		case TypeJSON, TypeYAML, TypeTOML, TypeEnv, TypeCSV, TypeText:
+			return declared, nil
+		}
+		return "", fmt.Errorf("unsupported type %q", declared)
+	}
+
+	base := filepath.Base(path)
+	if base == ".env" || strings.HasPrefix(base, ".env.") {
+		return TypeEnv, nil
+	}
+
+	if alias, ok := typeAliases[strings.TrimPrefix(filepath.Ext(path), ".")]; ok {
+		return alias, nil
+	}
+	return TypeText, nil
+}
+
+// Parse converts raw content of the given type into template data.
+// Objects become map[string]any, arrays []any, CSV [][]string and text a string.
+func Parse(mimeType, content string) (any, error) {
+	switch mimeType {
+	case TypeJSON:
+		var out any
+		if err := json.Unmarshal([]byte(content), &out); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		return out, nil
+	case TypeYAML:
+		var out any
+		if err := yaml.Unmarshal([]byte(content), &out); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		return out, nil
+	case TypeTOML:
+		out := make(map[string]any)
+		if err := toml.Unmarshal([]byte(content), &out); err != nil {
+			return nil, fmt.Errorf("failed to parse TOML: %w", err)
+		}
+		return out, nil
+	case TypeEnv:
+		env, err := godotenv.Unmarshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse env file: %w", err)
+		}
+		out := make(map[string]any, len(env))
+		for k, v := range env {
+			out[k] = v
+		}
+		return out, nil
+	case TypeCSV:
+		records, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		return records, nil
+	case TypeText:
+		return content, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", mimeType)
+}
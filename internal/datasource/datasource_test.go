@@ -0,0 +1,159 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+// setupRoot creates a root directory with the given files
+func setupRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(rootDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return rootDir
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	rootDir := setupRoot(t, map[string]string{
+		".worktree/config.yaml": `datasources:
+  ports: .worktree/ports.yaml
+  shared: .env.shared
+  settings:
+    path: config/settings
+    type: json
+  hosts: hosts.csv
+  motd: motd.txt
+  tools: tools.toml
+`,
+		".worktree/ports.yaml": "web: 8080\ndb: 5432\n",
+		".env.shared":          "API_KEY=secret\n",
+		"config/settings":      `{"debug": true, "name": "app"}`,
+		"hosts.csv":            "name,ip\nweb,10.0.0.1\n",
+		"motd.txt":             "hello",
+		"tools.toml":           "[go]\nversion = \"1.25\"\n",
+	})
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{name: "yaml", template: `{{ (ds "ports").web }}`, want: "8080"},
+		{name: "datasource alias", template: `{{ (datasource "ports").db }}`, want: "5432"},
+		{name: "dotenv", template: `{{ (ds "shared").API_KEY }}`, want: "secret"},
+		{name: "declared type", template: `{{ (ds "settings").name }}`, want: "app"},
+		{name: "csv", template: `{{ index (index (ds "hosts") 1) 1 }}`, want: "10.0.0.1"},
+		{name: "text", template: `{{ ds "motd" }}`, want: "hello"},
+		{name: "toml", template: `{{ (ds "tools").go.version }}`, want: "1.25"},
+		{name: "include", template: `{{ include "ports" }}`, want: "web: 8080\ndb: 5432\n"},
+		{name: "exists", template: `{{ datasourceExists "ports" }} {{ datasourceExists "nope" }}`, want: "true false"},
+		{name: "list", template: `{{ listDatasources }}`, want: "[hosts motd ports settings shared tools]"},
+		{name: "gomplate funcs still available", template: `{{ "Feature/X" | strings.Slug }}`, want: "feature-x"},
+		{name: "undefined datasource", template: `{{ ds "missing" }}`, wantErr: true},
+	}
+
+	settings, err := config.LoadSettings(rootDir)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	funcMap := TemplateFuncs(context.Background(), rootDir, settings.Datasources)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New(tt.name).Funcs(funcMap).Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			err = tmpl.Execute(&out, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && out.String() != tt.want {
+				t.Errorf("Execute() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReader_SubPath(t *testing.T) {
+	rootDir := setupRoot(t, map[string]string{
+		"config/env/dev.yaml": "port: 8080\n",
+		"config/..hidden":     "inside",
+		"secret.txt":          "secret",
+	})
+	reader := NewReader(rootDir, map[string]config.Datasource{"env": {Path: "config/env"}, "config": {Path: "config"}})
+
+	if value, err := reader.Datasource("env", "dev.yaml"); err != nil || value.(map[string]any)["port"] != 8080 {
+		t.Errorf("Datasource(env, dev.yaml) = %v, %v", value, err)
+	}
+	if content, err := reader.Include("config", "..hidden"); err != nil || content != "inside" {
+		t.Errorf("Include(config, ..hidden) = %q, %v", content, err)
+	}
+	for _, args := range [][]string{{"../../secret.txt"}, {"..", "..", "secret.txt"}, {".."}, {filepath.Join(rootDir, "secret.txt")}} {
+		if _, err := reader.Include("env", args...); err == nil {
+			t.Errorf("Include(env, %q) expected error, got nil", args)
+		}
+	}
+}
+
+func TestReader_RemoteSourcesRejected(t *testing.T) {
+	reader := NewReader(t.TempDir(), map[string]config.Datasource{
+		"remote": {Path: "https://example.com/ports.json"},
+	})
+
+	if _, err := reader.Datasource("remote"); err == nil {
+		t.Errorf("Datasource() with remote URL expected error, got nil")
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		path     string
+		declared string
+		want     string
+		wantErr  bool
+	}{
+		{path: "ports.yaml", want: TypeYAML},
+		{path: "ports.yml", want: TypeYAML},
+		{path: "settings.json", want: TypeJSON},
+		{path: "tools.toml", want: TypeTOML},
+		{path: ".env", want: TypeEnv},
+		{path: ".env.shared", want: TypeEnv},
+		{path: "app.env", want: TypeEnv},
+		{path: "hosts.csv", want: TypeCSV},
+		{path: "README", want: TypeText},
+		{path: "data", declared: "application/json; charset=utf-8", want: TypeJSON},
+		{path: "data", declared: "yaml", want: TypeYAML},
+		{path: "data", declared: "application/xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+tt.declared, func(t *testing.T) {
+			got, err := detectType(tt.path, tt.declared)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectType(%q, %q) = %q, want %q", tt.path, tt.declared, got, tt.want)
+			}
+		})
+	}
+}
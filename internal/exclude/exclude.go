@@ -0,0 +1,222 @@
+package exclude
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// Markers delimiting the sections of info/exclude managed by wtm
+const (
+	generatedBegin = "# BEGIN wtm generated files"
+	generatedEnd   = "# END wtm generated files"
+	globalBegin    = "# BEGIN wtm global excludes"
+	globalEnd      = "# END wtm global excludes"
+)
+
+// Path returns the per-worktree exclude file (<git-dir>/info/exclude)
+func Path(worktreeDir string) (string, error) {
+	gitDir, err := git.GetGitDir(worktreeDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "info", "exclude"), nil
+}
+
+// Sync writes the generated paths (relative to the worktree) into the
+// worktree's own info/exclude so git reports them neither as untracked nor
+// picks them up with 'git add -A'.
+//
+// Git only reads the shared info/exclude of a repository, so the worktree's
+// core.excludesFile (in config.worktree, enabled with extensions.worktreeConfig)
+// is pointed at the per-worktree file. Since that setting replaces the user's
+// global excludes file, its patterns are copied in as well; RefreshGlobal
+// copies them again after they changed.
+//
+// An empty list removes the managed sections and the core.excludesFile setting.
+func Sync(bareDir, worktreeDir string, paths []string) error {
+	excludePath, err := Path(worktreeDir)
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", excludePath, err)
+	}
+	content := stripManaged(string(existing))
+
+	if len(paths) == 0 {
+		if err := writeExclude(excludePath, content, existing != nil); err != nil {
+			return err
+		}
+		if current, _ := git.GetWorktreeConfig(worktreeDir, "core.excludesFile"); current == excludePath {
+			return git.UnsetWorktreeConfig(worktreeDir, "core.excludesFile")
+		}
+		return nil
+	}
+
+	current, err := git.GetWorktreeConfig(worktreeDir, "core.excludesFile")
+	if err != nil {
+		return err
+	}
+	if current != "" && current != excludePath {
+		return fmt.Errorf("core.excludesFile of the worktree is already set to %s", current)
+	}
+
+	if err := git.EnableWorktreeConfig(bareDir); err != nil {
+		return fmt.Errorf("failed to enable worktree config: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(content)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString(generatedBegin + "\n")
+	for _, path := range paths {
+		b.WriteString(Pattern(path) + "\n")
+	}
+	b.WriteString(generatedEnd + "\n")
+	b.WriteString(globalSection(excludePath))
+
+	if err := writeExclude(excludePath, b.String(), true); err != nil {
+		return err
+	}
+	return git.SetWorktreeConfig(worktreeDir, "core.excludesFile", excludePath)
+}
+
+// RefreshGlobal copies the user's global excludes file into the worktree's
+// exclude file again, since git no longer reads it once core.excludesFile
+// points at that file. It reports whether the copy was outdated; worktrees
+// whose core.excludesFile wtm did not set are left alone.
+func RefreshGlobal(worktreeDir string) (bool, error) {
+	excludePath, err := Path(worktreeDir)
+	if err != nil {
+		return false, err
+	}
+	current, err := git.GetWorktreeConfig(worktreeDir, "core.excludesFile")
+	if err != nil || current != excludePath {
+		return false, err
+	}
+
+	existing, err := os.ReadFile(excludePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", excludePath, err)
+	}
+
+	content := stripManaged(string(existing), globalBegin)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += globalSection(excludePath)
+	if content == string(existing) {
+		return false, nil
+	}
+	return true, writeExclude(excludePath, content, true)
+}
+
+// globalSection returns the managed section holding a copy of the user's
+// global excludes file, or "" when it is missing or empty
+func globalSection(excludePath string) string {
+	global := git.GlobalExcludesFile()
+	if global == "" || global == excludePath {
+		return ""
+	}
+	globalContent, err := os.ReadFile(global)
+	if err != nil || len(globalContent) == 0 {
+		return ""
+	}
+	return globalBegin + "\n" +
+		"# Copied from " + global + "\n" +
+		strings.TrimRight(string(globalContent), "\n") + "\n" +
+		globalEnd + "\n"
+}
+
+// Repair points the worktree's core.excludesFile, an absolute path, back at
+// its info/exclude after the repository was moved. It reports whether the
+// setting was stale; settings that point elsewhere are left alone.
+func Repair(worktreeDir string) (bool, error) {
+	excludePath, err := Path(worktreeDir)
+	if err != nil {
+		return false, err
+	}
+	current, err := git.GetWorktreeConfig(worktreeDir, "core.excludesFile")
+	if err != nil || current == "" || current == excludePath {
+		return false, err
+	}
+
+	// The old path ends like the new one: worktrees/<id>/info/exclude
+	adminDir := filepath.Dir(filepath.Dir(excludePath))
+	tail := filepath.Join(filepath.Base(filepath.Dir(adminDir)), filepath.Base(adminDir), "info", "exclude")
+	if !strings.HasSuffix(current, string(filepath.Separator)+tail) {
+		return false, nil
+	}
+	return true, git.SetWorktreeConfig(worktreeDir, "core.excludesFile", excludePath)
+}
+
+// writeExclude writes the exclude file, creating info/ when needed. Nothing is
+// written when the file did not exist and there is no content.
+func writeExclude(path, content string, exists bool) error {
+	if !exists && content == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// stripManaged removes the sections written by wtm, keeping the user's own
+// patterns. Only the sections starting with the given markers are removed,
+// or all of them when none are given.
+func stripManaged(content string, begins ...string) string {
+	if len(begins) == 0 {
+		begins = []string{generatedBegin, globalBegin}
+	}
+
+	var kept []string
+	var inside string
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimRight(line, "\n")
+		switch {
+		case inside == "" && slices.Contains(begins, trimmed):
+			inside = trimmed
+		case inside == generatedBegin && trimmed == generatedEnd,
+			inside == globalBegin && trimmed == globalEnd:
+			inside = ""
+		case inside == "":
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+// Pattern returns a gitignore pattern matching exactly one path relative to
+// the worktree root
+func Pattern(path string) string {
+	var b strings.Builder
+	b.WriteString("/")
+	for _, r := range filepath.ToSlash(path) {
+		switch r {
+		case '*', '?', '[', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	// Trailing spaces are ignored unless escaped
+	pattern := b.String()
+	trimmed := strings.TrimRight(pattern, " ")
+	return trimmed + strings.Repeat("\\ ", len(pattern)-len(trimmed))
+}
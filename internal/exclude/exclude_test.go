@@ -0,0 +1,173 @@
+package exclude
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// setupWorktree creates a bare repository with a worktree for main
+func setupWorktree(t *testing.T) (bareDir, worktreeDir string) {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	bareDir = filepath.Join(rootDir, ".bare")
+	worktreeDir = filepath.Join(rootDir, "main")
+
+	if err := git.InitBare(bareDir); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	if err := git.CreateInitialBranch(bareDir, "main"); err != nil {
+		t.Fatalf("Failed to create initial branch: %v", err)
+	}
+	if err := git.AddWorktree(bareDir, "main", worktreeDir, ""); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	return bareDir, worktreeDir
+}
+
+func TestPattern(t *testing.T) {
+	tests := map[string]string{
+		".env":             "/.env",
+		"config/local.yml": "/config/local.yml",
+		"weird[1]*?.txt":   `/weird\[1]\*\?.txt`,
+		"#notes":           "/#notes",
+		"!important":       "/!important",
+		"trailing  ":       `/trailing\ \ `,
+	}
+
+	for path, want := range tests {
+		if got := Pattern(path); got != want {
+			t.Errorf("Pattern(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	bareDir, worktreeDir := setupWorktree(t)
+
+	for _, path := range []string{".env", "config/local.yml", "notes.txt"} {
+		fullPath := filepath.Join(worktreeDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keep the user's own patterns in the worktree's exclude file
+	excludePath, err := Path(worktreeDir)
+	if err != nil {
+		t.Fatalf("Path() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(excludePath, []byte("*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Sync(bareDir, worktreeDir, []string{".env", "config/local.yml"}); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	untracked, err := git.UntrackedFiles(worktreeDir)
+	if err != nil {
+		t.Fatalf("UntrackedFiles() error = %v", err)
+	}
+	if len(untracked) != 1 || untracked[0] != "notes.txt" {
+		t.Errorf("UntrackedFiles() = %v, want [notes.txt]", untracked)
+	}
+
+	// The bare repository must still be usable
+	if _, err := git.ListWorktrees(bareDir); err != nil {
+		t.Errorf("ListWorktrees() after Sync error = %v", err)
+	}
+
+	// Syncing again replaces the managed section
+	if err := Sync(bareDir, worktreeDir, []string{".env"}); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	content, _ := os.ReadFile(excludePath)
+	if strings.Count(string(content), generatedBegin) != 1 || strings.Contains(string(content), "local.yml") {
+		t.Errorf("exclude file not replaced:\n%s", content)
+	}
+
+	// An empty list removes the rules and the setting
+	if err := Sync(bareDir, worktreeDir, nil); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	content, _ = os.ReadFile(excludePath)
+	if string(content) != "*.log\n" {
+		t.Errorf("exclude file after clearing = %q, want user patterns only", content)
+	}
+	if value, _ := git.GetWorktreeConfig(worktreeDir, "core.excludesFile"); value != "" {
+		t.Errorf("core.excludesFile still set to %s", value)
+	}
+	if untracked, _ := git.UntrackedFiles(worktreeDir); len(untracked) != 3 {
+		t.Errorf("UntrackedFiles() after clearing = %v, want 3 files", untracked)
+	}
+}
+
+func TestStripManaged(t *testing.T) {
+	content := "*.log\n" + generatedBegin + "\n/.env\n" + generatedEnd + "\n" +
+		globalBegin + "\n.DS_Store\n" + globalEnd + "\n/tmp\n"
+
+	if got := stripManaged(content); got != "*.log\n/tmp\n" {
+		t.Errorf("stripManaged() = %q", got)
+	}
+}
+
+func TestRefreshGlobal(t *testing.T) {
+	bareDir, worktreeDir := setupWorktree(t)
+
+	globalPath := filepath.Join(t.TempDir(), "ignore")
+	if err := os.WriteFile(globalPath, []byte(".DS_Store\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	globalConfig := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(globalConfig, []byte("[core]\n\texcludesFile = "+globalPath+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+
+	if changed, err := RefreshGlobal(worktreeDir); err != nil || changed {
+		t.Errorf("RefreshGlobal() without core.excludesFile = %v, %v, want nothing to do", changed, err)
+	}
+
+	if err := Sync(bareDir, worktreeDir, []string{".env"}); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if changed, err := RefreshGlobal(worktreeDir); err != nil || changed {
+		t.Errorf("RefreshGlobal() right after Sync() = %v, %v, want an up-to-date copy", changed, err)
+	}
+
+	// Edits to the global file reach the worktree
+	if err := os.WriteFile(globalPath, []byte(".DS_Store\n*.swp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := RefreshGlobal(worktreeDir); err != nil || !changed {
+		t.Errorf("RefreshGlobal() after an edit = %v, %v, want the copy updated", changed, err)
+	}
+	excludePath, _ := Path(worktreeDir)
+	content, _ := os.ReadFile(excludePath)
+	if !strings.Contains(string(content), "*.swp") || !strings.Contains(string(content), "/.env") || strings.Count(string(content), globalBegin) != 1 {
+		t.Errorf("exclude file after refresh:\n%s", content)
+	}
+
+	// A removed global file removes the copy
+	if err := os.Remove(globalPath); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := RefreshGlobal(worktreeDir); err != nil || !changed {
+		t.Errorf("RefreshGlobal() after removal = %v, %v", changed, err)
+	}
+	content, _ = os.ReadFile(excludePath)
+	if strings.Contains(string(content), globalBegin) || !strings.Contains(string(content), "/.env") {
+		t.Errorf("exclude file after removal:\n%s", content)
+	}
+}
@@ -0,0 +1,214 @@
+package forge
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultBitbucketAPIURL is the REST API endpoint of bitbucket.org
+const DefaultBitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// bitbucket talks to Bitbucket Cloud. It has no pull request refs, so
+// changes are fetched by their source branch.
+type bitbucket struct {
+	host string
+	api  *apiClient
+}
+
+// NewBitbucket creates a Bitbucket Cloud forge. An empty apiURL uses
+// api.bitbucket.org/2.0.
+func NewBitbucket(host, apiURL string) Forge {
+	if host == "" {
+		host = "bitbucket.org"
+	}
+	if apiURL == "" {
+		apiURL = DefaultBitbucketAPIURL
+	}
+
+	api := newAPIClient("Bitbucket", apiURL, "BITBUCKET_TOKEN")
+	if token := os.Getenv("BITBUCKET_TOKEN"); token != "" {
+		api.header.Set("Authorization", "Bearer "+token)
+	} else if user, password := os.Getenv("BITBUCKET_USERNAME"), os.Getenv("BITBUCKET_APP_PASSWORD"); user != "" && password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+		api.header.Set("Authorization", "Basic "+credentials)
+	}
+	return &bitbucket{host: host, api: api}
+}
+
+func (b *bitbucket) Kind() string { return Bitbucket }
+
+// ResolveRepo returns workspace/repo
+func (b *bitbucket) ResolveRepo(remoteURL string) (Repo, error) {
+	return resolveOwnerRepo(remoteURL)
+}
+
+func (b *bitbucket) GetChange(repo Repo, number int) (*Change, error) {
+	var response bitbucketPull
+	if err := b.api.get(fmt.Sprintf("/repositories/%s/pullrequests/%d", repo.Path, number), &response); err != nil {
+		return nil, err
+	}
+	return response.toChange(b.host), nil
+}
+
+// FetchChange fetches the source branch, from the fork when the change comes
+// from one. It needs change.HeadRef, which only the API provides.
+func (b *bitbucket) FetchChange(bareDir, remote string, change *Change, branch string) error {
+	if change.HeadRef == "" {
+		return fmt.Errorf("bitbucket has no pull request refs, the source branch of PR #%d is required", change.Number)
+	}
+	source := remote
+	if change.HeadCloneURL != "" {
+		source = change.HeadCloneURL
+	}
+	return fetchRef(bareDir, source, "refs/heads/"+change.HeadRef, branch)
+}
+
+// ListChanges lists open PRs, filtering client-side. Bitbucket pull requests
+// have no labels, and lists omit reviewers, so those filters match nothing.
+func (b *bitbucket) ListChanges(repo Repo, opts ListOptions) ([]Change, error) {
+	opts, err := resolveMe(opts, func() (string, error) { return b.api.currentUser("/user", "nickname") })
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages link to the next one in the response body
+	var changes []Change
+	path := fmt.Sprintf("/repositories/%s/pullrequests?state=OPEN&pagelen=50", repo.Path)
+	for range maxPages {
+		var response struct {
+			Values []bitbucketPull `json:"values"`
+			Next   string          `json:"next"`
+		}
+		if err := b.api.get(path, &response); err != nil {
+			return nil, err
+		}
+		for _, pull := range response.Values {
+			changes = append(changes, *pull.toChange(b.host))
+		}
+
+		next, ok := b.api.relative(response.Next)
+		if !ok {
+			return filterChanges(changes, opts), nil
+		}
+		path = next
+	}
+	return nil, fmt.Errorf("%s API returned more than %d pages", b.api.name, maxPages)
+}
+
+func (b *bitbucket) CreateDraft(repo Repo, opts DraftOptions) (*Change, error) {
+	request := map[string]any{
+		"title":       opts.Title,
+		"description": opts.Body,
+		"draft":       true,
+		"source":      map[string]any{"branch": map[string]string{"name": opts.Head}},
+		"destination": map[string]any{"branch": map[string]string{"name": opts.Base}},
+	}
+	var response bitbucketPull
+	if err := b.api.post(fmt.Sprintf("/repositories/%s/pullrequests", repo.Path), request, &response); err != nil {
+		return nil, err
+	}
+	return response.toChange(b.host), nil
+}
+
+// GetIssue reads an issue of the repository's issue tracker. Bitbucket issues
+// have a kind (bug, enhancement, proposal or task) instead of labels.
+func (b *bitbucket) GetIssue(repo Repo, number int) (*Issue, error) {
+	var response struct {
+		ID       int    `json:"id"`
+		Title    string `json:"title"`
+		State    string `json:"state"`
+		Kind     string `json:"kind"`
+		Reporter struct {
+			Nickname string `json:"nickname"`
+		} `json:"reporter"`
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	if err := b.api.get(fmt.Sprintf("/repositories/%s/issues/%d", repo.Path, number), &response); err != nil {
+		return nil, err
+	}
+
+	// new, open and on hold issues are still open
+	state := "CLOSED"
+	switch response.State {
+	case "new", "open", "on hold":
+		state = "OPEN"
+	}
+	issue := &Issue{
+		Number: response.ID,
+		Title:  strings.TrimSpace(response.Title),
+		Author: response.Reporter.Nickname,
+		State:  state,
+		URL:    response.Links.HTML.Href,
+	}
+	if response.Kind != "" {
+		issue.Labels = []string{response.Kind}
+	}
+	return issue, nil
+}
+
+// bitbucketPull is a pull request as returned by the Bitbucket API
+type bitbucketPull struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	State       string `json:"state"`
+	Draft       bool   `json:"draft"`
+	Author      struct {
+		Nickname string `json:"nickname"`
+	} `json:"author"`
+	Source struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+		Repository *struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	} `json:"source"`
+	Destination struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+		Repository *struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	} `json:"destination"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+// toChange converts a Bitbucket pull request. DECLINED and SUPERSEDED map to CLOSED.
+func (p bitbucketPull) toChange(host string) *Change {
+	state := strings.ToUpper(p.State)
+	if state == "DECLINED" || state == "SUPERSEDED" {
+		state = "CLOSED"
+	}
+	change := &Change{
+		Number:  p.ID,
+		HeadRef: p.Source.Branch.Name,
+		BaseRef: p.Destination.Branch.Name,
+		Title:   strings.TrimSpace(p.Title),
+		Body:    strings.TrimSpace(p.Description),
+		Author:  p.Author.Nickname,
+		State:   state,
+		Draft:   p.Draft,
+		URL:     p.Links.HTML.Href,
+	}
+	if p.Source.Repository != nil {
+		change.HeadRepo = p.Source.Repository.FullName
+		// Only cross-repository changes need to be fetched from elsewhere
+		if p.Destination.Repository == nil || p.Destination.Repository.FullName != change.HeadRepo {
+			change.HeadCloneURL = (&url.URL{Scheme: "https", Host: host, Path: "/" + change.HeadRepo + ".git"}).String()
+		}
+	}
+	return change
+}
@@ -0,0 +1,206 @@
+package forge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiClient performs JSON requests against a forge's REST API
+type apiClient struct {
+	name       string      // Forge name used in errors (e.g., "GitHub")
+	baseURL    string      // API base URL without trailing slash
+	header     http.Header // Authentication and other headers sent with every request
+	tokenHint  string      // Environment variable to suggest when authentication fails
+	httpClient *http.Client
+}
+
+// newAPIClient creates a client with a request timeout
+func newAPIClient(name, baseURL, tokenHint string) *apiClient {
+	return &apiClient{
+		name:       name,
+		baseURL:    baseURL,
+		header:     make(http.Header),
+		tokenHint:  tokenHint,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// get performs a GET request and decodes the JSON response into out
+func (c *apiClient) get(path string, out any) error {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+// maxPages bounds how many pages a list request follows
+const maxPages = 50
+
+// getAll performs a GET request for a JSON array and follows the rel="next"
+// links of the Link header, as GitHub, GitLab and Gitea paginate, returning
+// the items of every page
+func getAll[T any](c *apiClient, path string) ([]T, error) {
+	var items []T
+	for range maxPages {
+		var page []T
+		header, err := c.request(http.MethodGet, path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		next, ok := c.relative(nextLink(header.Get("Link")))
+		if !ok {
+			return items, nil
+		}
+		path = next
+	}
+	return nil, fmt.Errorf("%s API returned more than %d pages", c.name, maxPages)
+}
+
+// nextLink returns the rel="next" URL of a Link header, or ""
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+		if !found || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
+}
+
+// relative turns a URL returned by the API into a path for the client. URLs
+// outside the API are refused, since requests carry the token.
+func (c *apiClient) relative(link string) (string, bool) {
+	if link == "" || !strings.HasPrefix(link, c.baseURL+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(link, c.baseURL), true
+}
+
+// post performs a POST request with a JSON body and decodes the response into out
+func (c *apiClient) post(path string, in, out any) error {
+	return c.do(http.MethodPost, path, in, out)
+}
+
+// currentUser returns a field of the authenticated user's profile (e.g.,
+// login from GET /user)
+func (c *apiClient) currentUser(path, field string) (string, error) {
+	if c.header.Get("Authorization") == "" && c.header.Get("PRIVATE-TOKEN") == "" {
+		return "", fmt.Errorf("no %s token set (check %s)", c.name, c.tokenHint)
+	}
+	var user map[string]any
+	if err := c.get(path, &user); err != nil {
+		return "", err
+	}
+	name, _ := user[field].(string)
+	if name == "" {
+		return "", fmt.Errorf("%s API returned no %s for the authenticated user", c.name, field)
+	}
+	return name, nil
+}
+
+// do performs a request. Paths may contain escaped segments (group%2Fproject).
+func (c *apiClient) do(method, path string, in, out any) error {
+	_, err := c.request(method, path, in, out)
+	return err
+}
+
+// maxResponseSize bounds how much of a response is read
+const maxResponseSize = 10 << 20
+
+// request performs a request like do and returns the response headers
+func (c *apiClient) request(method, path string, in, out any) (header http.Header, err error) {
+	var body io.Reader
+	if in != nil {
+		content, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s API request: %w", c.name, err)
+		}
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s API request failed: %w", c.name, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			header, err = nil, fmt.Errorf("failed to close %s API response: %w", c.name, closeErr)
+		}
+	}()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s API response: %w", c.name, err)
+	}
+	if len(content) > maxResponseSize {
+		return nil, fmt.Errorf("%s API response exceeds %d MiB", c.name, maxResponseSize>>20)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, c.statusError(resp, content)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return nil, fmt.Errorf("failed to parse %s API response: %w", c.name, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// statusError turns an unsuccessful response into an actionable error
+func (c *apiClient) statusError(resp *http.Response, content []byte) error {
+	message := apiErrorMessage(content)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("%s API authentication failed (check %s): %s", c.name, c.tokenHint, message)
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return fmt.Errorf("%s API rate limit exceeded, set %s to raise it", c.name, c.tokenHint)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s API: %s not found (private repositories need %s)", c.name, resp.Request.URL.Path, c.tokenHint)
+	}
+	return fmt.Errorf("%s API returned %s: %s", c.name, resp.Status, message)
+}
+
+// apiErrorMessage extracts the message of an error response, which forges
+// return as {"message": ...} or {"error": {"message": ...}}
+func apiErrorMessage(content []byte) string {
+	var apiError struct {
+		Message any `json:"message"`
+		Error   any `json:"error"`
+	}
+	if err := json.Unmarshal(content, &apiError); err == nil {
+		if apiError.Message != nil {
+			return fmt.Sprint(apiError.Message)
+		}
+		if nested, ok := apiError.Error.(map[string]any); ok && nested["message"] != nil {
+			return fmt.Sprint(nested["message"])
+		}
+		if apiError.Error != nil {
+			return fmt.Sprint(apiError.Error)
+		}
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,49 @@
+package forge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: `<https://api.example.com/pulls?page=2>; rel="next", <https://api.example.com/pulls?page=5>; rel="last"`, want: "https://api.example.com/pulls?page=2"},
+		{header: `<https://api.example.com/pulls?page=1>; rel="prev"`, want: ""},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.header); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestAPIClient_Relative(t *testing.T) {
+	c := newAPIClient("GitHub", "https://api.example.com/v3", "GH_TOKEN")
+	if got, ok := c.relative("https://api.example.com/v3/pulls?page=2"); !ok || got != "/pulls?page=2" {
+		t.Errorf("relative() = %q, %v", got, ok)
+	}
+	// Links elsewhere would receive the token
+	for _, link := range []string{"", "https://evil.example.com/v3/pulls", "https://api.example.com/v3evil/pulls"} {
+		if got, ok := c.relative(link); ok {
+			t.Errorf("relative(%q) = %q, want it refused", link, got)
+		}
+	}
+}
+
+func TestAPIClient_ResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`"` + strings.Repeat("x", maxResponseSize) + `"`))
+	}))
+	defer server.Close()
+
+	var out string
+	if err := newAPIClient("GitHub", server.URL, "GH_TOKEN").get("/huge", &out); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("get() error = %v, want a size error", err)
+	}
+}
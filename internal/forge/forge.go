@@ -0,0 +1,356 @@
+package forge
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+)
+
+// Supported forge kinds
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	Gitea     = "gitea" // Also Forgejo, which keeps Gitea's API
+	Bitbucket = "bitbucket"
+)
+
+// Repo identifies a repository on a forge
+type Repo struct {
+	Host string // Web host, with port when not the default (e.g., github.com, git.example.com:8443)
+	Path string // owner/repo, or group/subgroup/project on GitLab
+}
+
+// String returns host/path
+func (r Repo) String() string {
+	return r.Host + "/" + r.Path
+}
+
+// Change is a pull request (GitHub, Gitea, Bitbucket) or merge request (GitLab).
+// Only Number and Branch are known when a change was fetched by refspec alone.
+type Change struct {
+	Number       int
+	Branch       string // Local branch the change was fetched into
+	HeadRef      string // Branch name in the head repository
+	HeadRepo     string // Path of the head repository (a fork for cross-repository changes)
+	HeadCloneURL string // Clone URL of the head repository
+	BaseRef      string // Branch the change targets
+	Title        string
+	Body         string
+	Author       string // Login of the author
+	State        string // OPEN, CLOSED or MERGED
+	Draft        bool
+	Labels       []string
+	URL          string   // Web URL of the change
+	Reviewers    []string // Users whose review is requested
+	// MaintainerCanModify reports whether the author lets maintainers push to
+	// the head branch of a cross-repository change
+	MaintainerCanModify bool
+}
+
+// Issue is an issue of a repository's issue tracker
+type Issue struct {
+	Number int
+	Title  string
+	Author string // Login of the author
+	State  string // OPEN or CLOSED
+	Labels []string
+	URL    string // Web URL of the issue
+}
+
+// Me stands for the authenticated user in ListOptions
+const Me = "@me"
+
+// ListOptions filters open changes
+type ListOptions struct {
+	Author          string   // Only changes opened by this user (or Me)
+	ReviewRequested string   // Only changes awaiting a review from this user (or Me)
+	Labels          []string // Only changes carrying all of these labels
+}
+
+// DraftOptions describes a draft change to open
+type DraftOptions struct {
+	Head  string // Branch with the changes (pushed to the repository)
+	Base  string // Branch to merge into
+	Title string
+	Body  string
+}
+
+// Forge is a code hosting service holding changes for a repository
+type Forge interface {
+	// Kind returns the forge kind (github, gitlab, gitea or bitbucket)
+	Kind() string
+	// ResolveRepo extracts the repository from a remote URL
+	ResolveRepo(remoteURL string) (Repo, error)
+	// GetChange returns the metadata of a change
+	GetChange(repo Repo, number int) (*Change, error)
+	// FetchChange fetches the head of a change from a remote into a local branch.
+	// Only change.Number is required; forges without change refs also need HeadRef.
+	FetchChange(bareDir, remote string, change *Change, branch string) error
+	// ListChanges returns the open changes of a repository
+	ListChanges(repo Repo, opts ListOptions) ([]Change, error)
+	// CreateDraft opens a draft change
+	CreateDraft(repo Repo, opts DraftOptions) (*Change, error)
+	// GetIssue returns an issue of the repository's issue tracker
+	GetIssue(repo Repo, number int) (*Issue, error)
+}
+
+// New returns the forge of the given kind for a host. An empty apiURL derives
+// the API URL from the host; WTM_<KIND>_API_URL overrides both.
+func New(kind, host, apiURL string) (Forge, error) {
+	if override := os.Getenv(APIURLEnv(kind)); override != "" {
+		apiURL = override
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/")
+
+	switch kind {
+	case GitHub:
+		return NewGitHub(host, apiURL), nil
+	case GitLab:
+		return NewGitLab(host, apiURL), nil
+	case Gitea, "forgejo":
+		return NewGitea(host, apiURL), nil
+	case Bitbucket:
+		return NewBitbucket(host, apiURL), nil
+	}
+	return nil, fmt.Errorf("unsupported forge type %q (use github, gitlab, gitea, forgejo or bitbucket)", kind)
+}
+
+// APIURLEnv returns the environment variable overriding a forge's API URL
+// (e.g., WTM_GITHUB_API_URL)
+func APIURLEnv(kind string) string {
+	if kind == "forgejo" {
+		kind = Gitea
+	}
+	return "WTM_" + strings.ToUpper(kind) + "_API_URL"
+}
+
+// DetectKind returns the forge kind of a well-known host, or "" for any
+// other host: a name merely containing "github" is no proof, and guessing
+// wrong would send tokens to it
+func DetectKind(host string) string {
+	hostname := strings.ToLower(host)
+	if i := strings.LastIndex(hostname, ":"); i >= 0 {
+		hostname = hostname[:i]
+	}
+
+	switch hostname {
+	case "github.com":
+		return GitHub
+	case "gitlab.com":
+		return GitLab
+	case "bitbucket.org":
+		return Bitbucket
+	case "codeberg.org", "gitea.com":
+		return Gitea
+	}
+	return ""
+}
+
+// ForRemote resolves the forge and repository of a remote of the bare repository.
+// Detection uses overrides (keyed by host) first, then the well-known hosts
+// (see DetectKind); other hosts must be declared in overrides.
+func ForRemote(bareDir, remote string, overrides map[string]config.ForgeSpec) (Forge, Repo, error) {
+	remoteURL, err := RemoteURL(bareDir, remote)
+	if err != nil {
+		return nil, Repo{}, err
+	}
+
+	host, _, err := ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, Repo{}, err
+	}
+
+	spec := overrides[host]
+	kind := spec.Type
+	if kind == "" {
+		kind = DetectKind(host)
+	}
+	if kind == "" {
+		return nil, Repo{}, fmt.Errorf("cannot detect the forge of %s, declare it under 'forges' in .worktree/config.yaml", host)
+	}
+
+	f, err := New(kind, host, spec.APIURL)
+	if err != nil {
+		return nil, Repo{}, err
+	}
+	repo, err := f.ResolveRepo(remoteURL)
+	if err != nil {
+		return nil, Repo{}, err
+	}
+	return f, repo, nil
+}
+
+// RemoteURL returns the configured URL of a remote, before any
+// url.<base>.insteadOf rewriting
+func RemoteURL(bareDir, remote string) (string, error) {
+	output, err := runner.Run("git", "--git-dir="+bareDir, "config", "--get", "remote."+remote+".url")
+	if err != nil {
+		return "", fmt.Errorf("failed to get URL of remote '%s': %w", remote, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ParseRemoteURL splits a git remote URL into host and repository path
+// (owner/repo, or group/subgroup/repo). Supported forms:
+//   - https://host[:port]/owner/repo(.git)
+//   - ssh://[user@]host[:port]/owner/repo(.git)
+//   - [user@]host:owner/repo(.git)
+func ParseRemoteURL(remoteURL string) (host, path string, err error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", fmt.Errorf("unsupported remote URL format: %s", remoteURL)
+		}
+		switch u.Scheme {
+		case "https", "http":
+			host = u.Host
+		case "ssh", "git", "git+ssh", "ssh+git":
+			// The SSH port says nothing about the web/API host
+			host = u.Hostname()
+		default:
+			return "", "", fmt.Errorf("unsupported remote URL format: %s", remoteURL)
+		}
+		path = u.Path
+	} else {
+		// scp-like syntax: [user@]host:path
+		colon := strings.Index(remoteURL, ":")
+		if colon <= 0 {
+			return "", "", fmt.Errorf("unsupported remote URL format: %s", remoteURL)
+		}
+		host = remoteURL[:colon]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path = remoteURL[colon+1:]
+	}
+
+	// GitHub's SSH-over-HTTPS endpoint
+	if host == "ssh.github.com" {
+		host = "github.com"
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return "", "", fmt.Errorf("unsupported remote URL format: %s", remoteURL)
+	}
+	return host, path, nil
+}
+
+// SiblingURL returns the URL of another repository on the same host, in the
+// same form as remoteURL (e.g., git@host:fork/repo.git for an SSH origin)
+func SiblingURL(remoteURL, path string) (string, error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	if _, _, err := ParseRemoteURL(remoteURL); err != nil {
+		return "", err
+	}
+
+	suffix := ""
+	if strings.HasSuffix(strings.TrimSuffix(remoteURL, "/"), ".git") {
+		suffix = ".git"
+	}
+
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", err
+		}
+		u.Path = "/" + path + suffix
+		return u.String(), nil
+	}
+
+	colon := strings.Index(remoteURL, ":")
+	return remoteURL[:colon+1] + path + suffix, nil
+}
+
+// resolveOwnerRepo parses a remote URL whose path must be exactly owner/repo
+func resolveOwnerRepo(remoteURL string) (Repo, error) {
+	host, path, err := ParseRemoteURL(remoteURL)
+	if err != nil {
+		return Repo{}, err
+	}
+	if strings.Count(path, "/") != 1 {
+		return Repo{}, fmt.Errorf("expected owner/repo in remote URL: %s", remoteURL)
+	}
+	return Repo{Host: host, Path: path}, nil
+}
+
+// fetchRef fetches a ref from a remote (name or URL) into a local branch.
+// The branch is only fast-forwarded, never reset.
+func fetchRef(bareDir, remote, ref, branch string) error {
+	refSpec := ref + ":refs/heads/" + branch
+	_, err := runner.Run("git", "--git-dir="+bareDir, "fetch", remote, refSpec)
+	return err
+}
+
+// firstEnv returns the first non-empty environment variable
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// filterChanges keeps the changes matching the author, reviewer and label
+// filters. Me must already be resolved.
+func filterChanges(changes []Change, opts ListOptions) []Change {
+	var matched []Change
+	for _, change := range changes {
+		if opts.Author != "" && !strings.EqualFold(change.Author, opts.Author) {
+			continue
+		}
+		if opts.ReviewRequested != "" && !containsFold(change.Reviewers, opts.ReviewRequested) {
+			continue
+		}
+		if !hasLabels(change.Labels, opts.Labels) {
+			continue
+		}
+		matched = append(matched, change)
+	}
+	return matched
+}
+
+// resolveMe replaces Me in the options with the authenticated user
+func resolveMe(opts ListOptions, currentUser func() (string, error)) (ListOptions, error) {
+	if opts.Author != Me && opts.ReviewRequested != Me {
+		return opts, nil
+	}
+	user, err := currentUser()
+	if err != nil {
+		return opts, fmt.Errorf("failed to determine the authenticated user: %w", err)
+	}
+	if opts.Author == Me {
+		opts.Author = user
+	}
+	if opts.ReviewRequested == Me {
+		opts.ReviewRequested = user
+	}
+	return opts, nil
+}
+
+// containsFold reports whether a list contains a value, ignoring case
+func containsFold(values []string, want string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLabels reports whether all wanted labels are present
+func hasLabels(labels, wanted []string) bool {
+	for _, want := range wanted {
+		if !containsFold(labels, want) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package forge
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+func TestDetectKind(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "github.com", want: GitHub},
+		{host: "GitLab.com", want: GitLab},
+		{host: "bitbucket.org", want: Bitbucket},
+		{host: "codeberg.org", want: Gitea},
+		{host: "github.example.com:8443", want: ""},
+		{host: "gitlab.internal", want: ""},
+		{host: "forgejo.example.org", want: ""},
+		{host: "git.example.com", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := DetectKind(tt.host); got != tt.want {
+			t.Errorf("DetectKind(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, kind := range []string{GitHub, GitLab, Gitea, "forgejo", Bitbucket} {
+		f, err := New(kind, "example.com", "")
+		if err != nil {
+			t.Fatalf("New(%q) error = %v", kind, err)
+		}
+		if want := strings.Replace(kind, "forgejo", Gitea, 1); f.Kind() != want {
+			t.Errorf("New(%q).Kind() = %q, want %q", kind, f.Kind(), want)
+		}
+	}
+
+	if _, err := New("sourcehut", "git.sr.ht", ""); err == nil {
+		t.Errorf("New(sourcehut) expected error, got nil")
+	}
+}
+
+func TestAPIURLEnv(t *testing.T) {
+	if got := APIURLEnv(GitHub); got != "WTM_GITHUB_API_URL" {
+		t.Errorf("APIURLEnv(github) = %q", got)
+	}
+	if got := APIURLEnv("forgejo"); got != "WTM_GITEA_API_URL" {
+		t.Errorf("APIURLEnv(forgejo) = %q", got)
+	}
+}
+
+func TestForRemote(t *testing.T) {
+	tests := []struct {
+		name      string
+		remoteURL string
+		overrides map[string]config.ForgeSpec
+		wantKind  string
+		wantPath  string
+		wantErr   bool
+	}{
+		{name: "detected", remoteURL: "git@github.com:acme/widgets.git", wantKind: GitHub, wantPath: "acme/widgets"},
+		{name: "nested group", remoteURL: "https://gitlab.com/group/sub/app.git", wantKind: GitLab, wantPath: "group/sub/app"},
+		{
+			name:      "override",
+			remoteURL: "https://git.example.com/acme/widgets.git",
+			overrides: map[string]config.ForgeSpec{"git.example.com": {Type: "forgejo"}},
+			wantKind:  Gitea,
+			wantPath:  "acme/widgets",
+		},
+		{name: "unknown host", remoteURL: "git@git.example.com:acme/widgets.git", wantErr: true},
+		{name: "host named after a forge", remoteURL: "https://github.example.com/acme/widgets.git", wantErr: true},
+		{name: "nested path on github", remoteURL: "https://github.com/a/b/c.git", wantErr: true},
+		{name: "local path", remoteURL: "/srv/git/widgets.git", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bareDir := filepath.Join(t.TempDir(), ".bare")
+			if err := git.InitBare(bareDir); err != nil {
+				t.Fatalf("Failed to init bare repo: %v", err)
+			}
+			if output, err := exec.Command("git", "--git-dir="+bareDir, "remote", "add", "origin", tt.remoteURL).CombinedOutput(); err != nil {
+				t.Fatalf("git remote add failed: %s", output)
+			}
+
+			f, repo, err := ForRemote(bareDir, "origin", tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ForRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if f.Kind() != tt.wantKind || repo.Path != tt.wantPath {
+				t.Errorf("ForRemote() = (%s, %+v), want (%s, %s)", f.Kind(), repo, tt.wantKind, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		wantHost string
+		wantPath string
+		wantErr  bool
+	}{
+		{url: "https://github.com/owner/repo.git", wantHost: "github.com", wantPath: "owner/repo"},
+		{url: "https://github.com/owner/repo", wantHost: "github.com", wantPath: "owner/repo"},
+		{url: "http://github.com/owner/repo/", wantHost: "github.com", wantPath: "owner/repo"},
+		{url: "git@github.com:owner/repo.git", wantHost: "github.com", wantPath: "owner/repo"},
+		{url: "ssh://git@ssh.github.com:443/owner/repo.git", wantHost: "github.com", wantPath: "owner/repo"},
+		{url: "https://ghe.example.com:8443/team/app.git", wantHost: "ghe.example.com:8443", wantPath: "team/app"},
+		{url: "git@gitlab.example.com:group/sub/app.git\n", wantHost: "gitlab.example.com", wantPath: "group/sub/app"},
+		{url: "/srv/git/repo.git", wantErr: true},
+		{url: "file:///srv/git/repo.git", wantErr: true},
+		{url: "https://github.com/owner", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			host, path, err := ParseRemoteURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRemoteURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if host != tt.wantHost || path != tt.wantPath {
+				t.Errorf("ParseRemoteURL() = (%q, %q), want (%q, %q)", host, path, tt.wantHost, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestSiblingURL(t *testing.T) {
+	tests := []struct {
+		remoteURL string
+		want      string
+	}{
+		{remoteURL: "https://github.com/acme/widgets.git", want: "https://github.com/octocat/widgets.git"},
+		{remoteURL: "https://github.com/acme/widgets", want: "https://github.com/octocat/widgets"},
+		{remoteURL: "git@github.com:acme/widgets.git", want: "git@github.com:octocat/widgets.git"},
+		{remoteURL: "ssh://git@ghe.example.com:2222/acme/widgets.git", want: "ssh://git@ghe.example.com:2222/octocat/widgets.git"},
+	}
+
+	for _, tt := range tests {
+		got, err := SiblingURL(tt.remoteURL, "octocat/widgets")
+		if err != nil {
+			t.Fatalf("SiblingURL(%q) error = %v", tt.remoteURL, err)
+		}
+		if got != tt.want {
+			t.Errorf("SiblingURL(%q) = %q, want %q", tt.remoteURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package forge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gitea talks to Gitea and Forgejo (including Codeberg) through the REST API,
+// whose pull request endpoints mirror GitHub's
+type gitea struct {
+	host string
+	api  *apiClient
+}
+
+// NewGitea creates a Gitea/Forgejo forge for a host. An empty apiURL uses
+// https://<host>/api/v1.
+func NewGitea(host, apiURL string) Forge {
+	if apiURL == "" {
+		apiURL = "https://" + host + "/api/v1"
+	}
+
+	api := newAPIClient("Gitea", apiURL, "GITEA_TOKEN")
+	if token := firstEnv("GITEA_TOKEN", "FORGEJO_TOKEN"); token != "" {
+		api.header.Set("Authorization", "token "+token)
+	}
+	return &gitea{host: host, api: api}
+}
+
+func (g *gitea) Kind() string { return Gitea }
+
+func (g *gitea) ResolveRepo(remoteURL string) (Repo, error) {
+	return resolveOwnerRepo(remoteURL)
+}
+
+func (g *gitea) GetChange(repo Repo, number int) (*Change, error) {
+	var response giteaPull
+	if err := g.api.get(fmt.Sprintf("/repos/%s/pulls/%d", repo.Path, number), &response); err != nil {
+		return nil, err
+	}
+	return response.toChange(), nil
+}
+
+// FetchChange fetches refs/pull/N/head, which Gitea maintains like GitHub
+func (g *gitea) FetchChange(bareDir, remote string, change *Change, branch string) error {
+	return fetchRef(bareDir, remote, fmt.Sprintf("refs/pull/%d/head", change.Number), branch)
+}
+
+// ListChanges lists open PRs across all pages; the API filters by labels only
+// when given IDs, so all filters apply client-side
+func (g *gitea) ListChanges(repo Repo, opts ListOptions) ([]Change, error) {
+	opts, err := resolveMe(opts, func() (string, error) { return g.api.currentUser("/user", "login") })
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := getAll[giteaPull](g.api, fmt.Sprintf("/repos/%s/pulls?state=open&limit=50", repo.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0, len(response))
+	for _, pull := range response {
+		changes = append(changes, *pull.toChange())
+	}
+	return filterChanges(changes, opts), nil
+}
+
+// CreateDraft opens a PR marked as work in progress by its title prefix
+func (g *gitea) CreateDraft(repo Repo, opts DraftOptions) (*Change, error) {
+	request := map[string]any{
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"title": "WIP: " + opts.Title,
+		"body":  opts.Body,
+	}
+	var response giteaPull
+	if err := g.api.post(fmt.Sprintf("/repos/%s/pulls", repo.Path), request, &response); err != nil {
+		return nil, err
+	}
+	return response.toChange(), nil
+}
+
+// GetIssue reads an issue; the Gitea issue API matches GitHub's
+func (g *gitea) GetIssue(repo Repo, number int) (*Issue, error) {
+	var response gitHubIssue
+	if err := g.api.get(fmt.Sprintf("/repos/%s/issues/%d", repo.Path, number), &response); err != nil {
+		return nil, err
+	}
+	if response.PullRequest != nil {
+		return nil, fmt.Errorf("#%d is a pull request, not an issue", number)
+	}
+	return response.toIssue(), nil
+}
+
+// giteaPull is a pull request as returned by the Gitea API
+type giteaPull struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	Draft   bool   `json:"draft"`
+	HTMLURL string `json:"html_url"`
+	// AllowMaintainerEdit mirrors GitHub's maintainer_can_modify
+	AllowMaintainerEdit bool `json:"allow_maintainer_edit"`
+	User                struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	RequestedReviewers []struct {
+		Login string `json:"login"`
+	} `json:"requested_reviewers"`
+	Head struct {
+		Ref  string `json:"ref"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+			CloneURL string `json:"clone_url"`
+		} `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// toChange converts a Gitea pull request. Gitea reports drafts through the
+// WIP title prefix on older versions.
+func (p giteaPull) toChange() *Change {
+	state := strings.ToUpper(p.State)
+	if p.Merged {
+		state = "MERGED"
+	}
+	title := strings.TrimSpace(p.Title)
+	change := &Change{
+		Number:  p.Number,
+		HeadRef: p.Head.Ref,
+		BaseRef: p.Base.Ref,
+		Title:   title,
+		Body:    strings.TrimSpace(p.Body),
+		Author:  p.User.Login,
+		State:   state,
+		Draft:   p.Draft || strings.HasPrefix(strings.ToUpper(title), "WIP:"),
+		URL:     p.HTMLURL,
+
+		MaintainerCanModify: p.AllowMaintainerEdit,
+	}
+	for _, label := range p.Labels {
+		change.Labels = append(change.Labels, label.Name)
+	}
+	for _, reviewer := range p.RequestedReviewers {
+		change.Reviewers = append(change.Reviewers, reviewer.Login)
+	}
+	if p.Head.Repo != nil {
+		change.HeadRepo = p.Head.Repo.FullName
+		change.HeadCloneURL = p.Head.Repo.CloneURL
+	}
+	return change
+}
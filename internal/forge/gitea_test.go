@@ -0,0 +1,64 @@
+package forge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitea(t *testing.T) {
+	t.Setenv("GITEA_TOKEN", "")
+	t.Setenv("FORGEJO_TOKEN", "forgejo-secret")
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls/3":
+			_, _ = w.Write([]byte(`{"number": 3, "title": "WIP: Fix it", "state": "closed", "merged": true,
+				"user": {"login": "frog"}, "head": {"ref": "fix"}, "base": {"ref": "main"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls" && r.URL.Query().Get("page") == "":
+			w.Header().Set("Link", `<http://`+r.Host+`/repos/acme/widgets/pulls?state=open&limit=50&page=2>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"number": 5, "state": "open", "user": {"login": "toad"}}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls":
+			_, _ = w.Write([]byte(`[{"number": 4, "state": "open", "user": {"login": "frog"}, "labels": [{"name": "docs"}]}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/widgets/pulls":
+			body := readBody(t, r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 6, "title": "` + body["title"].(string) + `", "state": "open"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	gitea := NewGitea("codeberg.org", server.URL)
+	repo := Repo{Host: "codeberg.org", Path: "acme/widgets"}
+
+	change, err := gitea.GetChange(repo, 3)
+	if err != nil {
+		t.Fatalf("GetChange() error = %v", err)
+	}
+	if change.State != "MERGED" || !change.Draft || change.HeadRef != "fix" || change.Author != "frog" {
+		t.Errorf("GetChange() = %+v", change)
+	}
+	if authorization != "token forgejo-secret" {
+		t.Errorf("Authorization header = %q", authorization)
+	}
+
+	changes, err := gitea.ListChanges(repo, ListOptions{Author: "frog", Labels: []string{"docs"}})
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Number != 4 {
+		t.Errorf("ListChanges() = %+v", changes)
+	}
+
+	draft, err := gitea.CreateDraft(repo, DraftOptions{Head: "feature", Base: "main", Title: "Add it"})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+	if draft.Number != 6 || !draft.Draft || draft.Title != "WIP: Add it" {
+		t.Errorf("CreateDraft() = %+v", draft)
+	}
+}
@@ -0,0 +1,440 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultGitHubAPIURL is the REST API endpoint of github.com
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// gitHub talks to github.com or a GitHub Enterprise server, preferring the gh
+// CLI for metadata and falling back to the REST API
+type gitHub struct {
+	host string
+	api  *apiClient
+}
+
+// NewGitHub creates a GitHub forge for a host. An empty apiURL uses
+// api.github.com, or https://<host>/api/v3 for GitHub Enterprise.
+func NewGitHub(host, apiURL string) Forge {
+	if host == "" {
+		host = "github.com"
+	}
+	if apiURL == "" {
+		apiURL = gitHubAPIURL(host)
+	}
+
+	api := newAPIClient("GitHub", apiURL, "GH_TOKEN")
+	api.header.Set("Accept", "application/vnd.github+json")
+	api.header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token := gitHubToken(host); token != "" {
+		api.header.Set("Authorization", "Bearer "+token)
+	}
+	return &gitHub{host: host, api: api}
+}
+
+// gitHubAPIURL returns the default API base URL for a host
+func gitHubAPIURL(host string) string {
+	if host == "github.com" {
+		return DefaultGitHubAPIURL
+	}
+	// GitHub Enterprise Server serves the REST API under /api/v3
+	return "https://" + host + "/api/v3"
+}
+
+func (g *gitHub) Kind() string { return GitHub }
+
+func (g *gitHub) ResolveRepo(remoteURL string) (Repo, error) {
+	return resolveOwnerRepo(remoteURL)
+}
+
+// GetChange reads PR metadata with the gh CLI, then the REST API
+func (g *gitHub) GetChange(repo Repo, number int) (*Change, error) {
+	if change, err := g.viewWithGH(repo, number); err == nil {
+		return change, nil
+	}
+
+	var response gitHubPull
+	if err := g.api.get(fmt.Sprintf("/repos/%s/pulls/%d", repo.Path, number), &response); err != nil {
+		return nil, err
+	}
+	return response.toChange(), nil
+}
+
+// FetchChange fetches refs/pull/N/head, which also covers fork PRs
+func (g *gitHub) FetchChange(bareDir, remote string, change *Change, branch string) error {
+	return fetchRef(bareDir, remote, fmt.Sprintf("refs/pull/%d/head", change.Number), branch)
+}
+
+// ListChanges lists open PRs with the gh CLI, then the REST API, which reads
+// every page before filtering client-side
+func (g *gitHub) ListChanges(repo Repo, opts ListOptions) ([]Change, error) {
+	if changes, err := g.listWithGH(repo, opts); err == nil {
+		return changes, nil
+	}
+
+	opts, err := resolveMe(opts, func() (string, error) { return g.api.currentUser("/user", "login") })
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := getAll[gitHubPull](g.api, fmt.Sprintf("/repos/%s/pulls?state=open&per_page=100", repo.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0, len(response))
+	for _, pull := range response {
+		changes = append(changes, *pull.toChange())
+	}
+	return filterChanges(changes, opts), nil
+}
+
+// CreateDraft opens a draft PR
+func (g *gitHub) CreateDraft(repo Repo, opts DraftOptions) (*Change, error) {
+	request := map[string]any{
+		"title": opts.Title,
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"body":  opts.Body,
+		"draft": true,
+	}
+	var response gitHubPull
+	if err := g.api.post(fmt.Sprintf("/repos/%s/pulls", repo.Path), request, &response); err != nil {
+		return nil, err
+	}
+	return response.toChange(), nil
+}
+
+// GetIssue reads an issue with the gh CLI, then the REST API
+func (g *gitHub) GetIssue(repo Repo, number int) (*Issue, error) {
+	if _, err := runner.LookPath("gh"); err == nil {
+		if output, err := runner.Run("gh", "issue", "view", fmt.Sprintf("%d", number), "--repo", ghSlug(repo), "--json", "number,title,author,state,labels,url"); err == nil {
+			var info gitHubIssue
+			if err := json.Unmarshal(output, &info); err == nil {
+				return info.toIssue(), nil
+			}
+		}
+	}
+
+	var response gitHubIssue
+	if err := g.api.get(fmt.Sprintf("/repos/%s/issues/%d", repo.Path, number), &response); err != nil {
+		return nil, err
+	}
+	if response.PullRequest != nil {
+		return nil, fmt.Errorf("#%d is a pull request, not an issue", number)
+	}
+	return response.toIssue(), nil
+}
+
+// ghFields are the fields requested from gh pr view and gh pr list
+const ghFields = "number,headRefName,headRepository,headRepositoryOwner,baseRefName,title,body,author,state,isDraft,labels,url,maintainerCanModify,reviewRequests"
+
+// ghSlug returns the repository as gh expects it: owner/repo on github.com
+// and host/owner/repo on GitHub Enterprise
+func ghSlug(repo Repo) string {
+	if repo.Host == "github.com" {
+		return repo.Path
+	}
+	return repo.String()
+}
+
+// viewWithGH reads PR metadata with the gh CLI
+func (g *gitHub) viewWithGH(repo Repo, number int) (*Change, error) {
+	if _, err := runner.LookPath("gh"); err != nil {
+		return nil, fmt.Errorf("gh CLI not found")
+	}
+
+	output, err := runner.Run("gh", "pr", "view", fmt.Sprintf("%d", number), "--repo", ghSlug(repo), "--json", ghFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var info ghPull
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse PR info: %w", err)
+	}
+	return info.toChange(repo.Host), nil
+}
+
+// listWithGH lists open PRs with the gh CLI, which resolves @me itself
+func (g *gitHub) listWithGH(repo Repo, opts ListOptions) ([]Change, error) {
+	if _, err := runner.LookPath("gh"); err != nil {
+		return nil, fmt.Errorf("gh CLI not found")
+	}
+
+	args := []string{"pr", "list", "--repo", ghSlug(repo), "--state", "open", "--limit", "100", "--json", ghFields}
+	if opts.Author != "" {
+		args = append(args, "--author", opts.Author)
+	}
+	if opts.ReviewRequested != "" {
+		args = append(args, "--search", "review-requested:"+opts.ReviewRequested)
+	}
+	for _, label := range opts.Labels {
+		args = append(args, "--label", label)
+	}
+
+	output, err := runner.Run("gh", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []ghPull
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse PR list: %w", err)
+	}
+	changes := make([]Change, 0, len(response))
+	for _, info := range response {
+		changes = append(changes, *info.toChange(repo.Host))
+	}
+	return changes, nil
+}
+
+// ghPull is a pull request as returned by gh pr view/list --json
+type ghPull struct {
+	Number              int    `json:"number"`
+	HeadRefName         string `json:"headRefName"`
+	BaseRefName         string `json:"baseRefName"`
+	Title               string `json:"title"`
+	Body                string `json:"body"`
+	State               string `json:"state"`
+	IsDraft             bool   `json:"isDraft"`
+	URL                 string `json:"url"`
+	MaintainerCanModify bool   `json:"maintainerCanModify"`
+	HeadRepository      struct {
+		Name string `json:"name"`
+	} `json:"headRepository"`
+	HeadRepositoryOwner struct {
+		Login string `json:"login"`
+	} `json:"headRepositoryOwner"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	// Review requests name a user (login) or a team (slug)
+	ReviewRequests []struct {
+		Login string `json:"login"`
+		Slug  string `json:"slug"`
+	} `json:"reviewRequests"`
+}
+
+// toChange converts a gh pull request
+func (p ghPull) toChange(host string) *Change {
+	change := &Change{
+		Number:  p.Number,
+		HeadRef: p.HeadRefName,
+		BaseRef: p.BaseRefName,
+		Title:   strings.TrimSpace(p.Title),
+		Body:    strings.TrimSpace(p.Body),
+		Author:  p.Author.Login,
+		State:   p.State,
+		Draft:   p.IsDraft,
+		URL:     p.URL,
+
+		MaintainerCanModify: p.MaintainerCanModify,
+	}
+	for _, label := range p.Labels {
+		change.Labels = append(change.Labels, label.Name)
+	}
+	for _, request := range p.ReviewRequests {
+		if request.Login != "" {
+			change.Reviewers = append(change.Reviewers, request.Login)
+		} else if request.Slug != "" {
+			change.Reviewers = append(change.Reviewers, request.Slug)
+		}
+	}
+	if p.HeadRepositoryOwner.Login != "" && p.HeadRepository.Name != "" {
+		change.HeadRepo = p.HeadRepositoryOwner.Login + "/" + p.HeadRepository.Name
+		change.HeadCloneURL = (&url.URL{Scheme: "https", Host: host, Path: "/" + change.HeadRepo + ".git"}).String()
+	}
+	return change
+}
+
+// gitHubPull is a pull request as returned by the REST API
+type gitHubPull struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	Draft   bool   `json:"draft"`
+	HTMLURL string `json:"html_url"`
+	Merged  bool   `json:"merged"`
+	// MaintainerCanModify is only reported for cross-repository PRs
+	MaintainerCanModify bool `json:"maintainer_can_modify"`
+	User                struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	RequestedReviewers []struct {
+		Login string `json:"login"`
+	} `json:"requested_reviewers"`
+	Head struct {
+		Ref  string `json:"ref"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+			CloneURL string `json:"clone_url"`
+		} `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// toChange converts a REST API pull request
+func (p gitHubPull) toChange() *Change {
+	state := strings.ToUpper(p.State)
+	if p.Merged {
+		state = "MERGED"
+	}
+	change := &Change{
+		Number:  p.Number,
+		HeadRef: p.Head.Ref,
+		BaseRef: p.Base.Ref,
+		Title:   strings.TrimSpace(p.Title),
+		Body:    strings.TrimSpace(p.Body),
+		Author:  p.User.Login,
+		State:   state,
+		Draft:   p.Draft,
+		URL:     p.HTMLURL,
+
+		MaintainerCanModify: p.MaintainerCanModify,
+	}
+	for _, label := range p.Labels {
+		change.Labels = append(change.Labels, label.Name)
+	}
+	for _, reviewer := range p.RequestedReviewers {
+		change.Reviewers = append(change.Reviewers, reviewer.Login)
+	}
+	// The head repository is null when the fork was deleted
+	if p.Head.Repo != nil {
+		change.HeadRepo = p.Head.Repo.FullName
+		change.HeadCloneURL = p.Head.Repo.CloneURL
+	}
+	return change
+}
+
+// gitHubToken returns a token for the host from GH_TOKEN or GITHUB_TOKEN
+// (GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN for other hosts) or the gh
+// hosts file. github.com tokens are never sent to other hosts.
+func gitHubToken(host string) string {
+	envVars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != "github.com" {
+		envVars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	if token := firstEnv(envVars...); token != "" {
+		return token
+	}
+
+	token, _ := tokenFromHostsFile(ghHostsFile(), host)
+	return token
+}
+
+// ghHostsFile returns the path of gh's hosts.yml
+func ghHostsFile() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// tokenFromHostsFile reads the oauth_token of a host from gh's hosts.yml.
+// Tokens stored in the system keyring are not visible here.
+func tokenFromHostsFile(path, host string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+		User       string `yaml:"user"`
+		Users      map[string]struct {
+			OAuthToken string `yaml:"oauth_token"`
+		} `yaml:"users"`
+	}
+	if err := yaml.Unmarshal(content, &hosts); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	entry, ok := hosts[host]
+	if !ok {
+		return "", nil
+	}
+	if entry.OAuthToken != "" {
+		return entry.OAuthToken, nil
+	}
+	// Multi-account layout: the active user's token
+	return entry.Users[entry.User].OAuthToken, nil
+}
+
+// gitHubIssue is an issue as returned by the GitHub API, the Gitea API and
+// gh issue view --json (which names the author and web URL differently)
+type gitHubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	URL     string `json:"url"`
+	User    struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest any `json:"pull_request"`
+}
+
+// toIssue converts a GitHub or Gitea issue
+func (i gitHubIssue) toIssue() *Issue {
+	issue := &Issue{
+		Number: i.Number,
+		Title:  strings.TrimSpace(i.Title),
+		Author: i.User.Login,
+		State:  strings.ToUpper(i.State),
+		URL:    i.HTMLURL,
+	}
+	if issue.Author == "" {
+		issue.Author = i.Author.Login
+	}
+	if issue.URL == "" {
+		issue.URL = i.URL
+	}
+	for _, label := range i.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	return issue
+}
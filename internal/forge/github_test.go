@@ -0,0 +1,299 @@
+package forge
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pullRequestJSON is a trimmed GitHub API response for a fork PR
+const pullRequestJSON = `{
+  "number": 7,
+  "title": "Add widgets ",
+  "body": "Closes #3",
+  "state": "open",
+  "draft": true,
+  "html_url": "https://github.com/acme/widgets/pull/7",
+  "user": {"login": "octocat"},
+  "labels": [{"name": "bug"}],
+  "head": {"ref": "feature/widgets", "repo": {"full_name": "octocat/widgets", "clone_url": "https://github.com/octocat/widgets.git"}},
+  "base": {"ref": "main"}
+}`
+
+// newGitHubServer serves pullRequestJSON for acme/widgets PR 7, lists it with
+// a second PR and echoes created PRs. It records the Authorization header.
+func newGitHubServer(t *testing.T, authorization *string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization != nil {
+			*authorization = r.Header.Get("Authorization")
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls/7":
+			_, _ = w.Write([]byte(pullRequestJSON))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls" && r.URL.Query().Get("page") == "":
+			// The list is paginated: the second page holds PR 8
+			w.Header().Set("Link", `<http://`+r.Host+`/repos/acme/widgets/pulls?state=open&page=2>; rel="next", <http://`+r.Host+`/repos/acme/widgets/pulls?state=open&page=2>; rel="last"`)
+			_, _ = w.Write([]byte(`[` + pullRequestJSON + `]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls":
+			_, _ = w.Write([]byte(`[{"number": 8, "state": "open", "user": {"login": "hubot"}, "requested_reviewers": [{"login": "octocat"}], "head": {"ref": "fix"}, "base": {"ref": "main"}}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/issues/3":
+			_, _ = w.Write([]byte(`{"number": 3, "title": "Widgets crash ", "state": "open", "html_url": "https://github.com/acme/widgets/issues/3", "user": {"login": "hubot"}, "labels": [{"name": "bug"}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/issues/7":
+			_, _ = w.Write([]byte(`{"number": 7, "title": "Add widgets", "state": "open", "pull_request": {}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/user":
+			_, _ = w.Write([]byte(`{"login": "octocat"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/widgets/pulls":
+			var request struct {
+				Title string `json:"title"`
+				Head  string `json:"head"`
+				Base  string `json:"base"`
+				Draft bool   `json:"draft"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"number": 9, "title": request.Title, "state": "open", "draft": request.Draft,
+				"head": map[string]any{"ref": request.Head}, "base": map[string]any{"ref": request.Base},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+var widgets = Repo{Host: "github.com", Path: "acme/widgets"}
+
+func TestGitHub_GetChange(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed and would be used instead of the API")
+	}
+
+	t.Setenv("GH_TOKEN", "secret")
+	var authorization string
+	server := newGitHubServer(t, &authorization)
+
+	change, err := NewGitHub("github.com", server.URL).GetChange(widgets, 7)
+	if err != nil {
+		t.Fatalf("GetChange() error = %v", err)
+	}
+
+	want := &Change{
+		Number:       7,
+		HeadRef:      "feature/widgets",
+		HeadRepo:     "octocat/widgets",
+		HeadCloneURL: "https://github.com/octocat/widgets.git",
+		BaseRef:      "main",
+		Title:        "Add widgets",
+		Body:         "Closes #3",
+		Author:       "octocat",
+		State:        "OPEN",
+		Draft:        true,
+		Labels:       []string{"bug"},
+		URL:          "https://github.com/acme/widgets/pull/7",
+	}
+	if !reflect.DeepEqual(change, want) {
+		t.Errorf("GetChange() = %+v, want %+v", change, want)
+	}
+	if authorization != "Bearer secret" {
+		t.Errorf("Authorization header = %q, want %q", authorization, "Bearer secret")
+	}
+}
+
+func TestGitHub_ListChanges(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed and would be used instead of the API")
+	}
+
+	t.Setenv("GH_TOKEN", "secret")
+	server := newGitHubServer(t, nil)
+	github := NewGitHub("github.com", server.URL)
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []int
+	}{
+		{name: "all", want: []int{7, 8}},
+		{name: "author", opts: ListOptions{Author: "HUBOT"}, want: []int{8}},
+		{name: "label", opts: ListOptions{Labels: []string{"bug"}}, want: []int{7}},
+		{name: "missing label", opts: ListOptions{Labels: []string{"bug", "docs"}}, want: nil},
+		{name: "mine", opts: ListOptions{Author: Me}, want: []int{7}},
+		{name: "review requested", opts: ListOptions{ReviewRequested: Me}, want: []int{8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := github.ListChanges(widgets, tt.opts)
+			if err != nil {
+				t.Fatalf("ListChanges() error = %v", err)
+			}
+			var got []int
+			for _, change := range changes {
+				got = append(got, change.Number)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub_GetIssue(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed and would be used instead of the API")
+	}
+
+	github := NewGitHub("github.com", newGitHubServer(t, nil).URL)
+
+	issue, err := github.GetIssue(widgets, 3)
+	if err != nil {
+		t.Fatalf("GetIssue() error = %v", err)
+	}
+	want := &Issue{Number: 3, Title: "Widgets crash", Author: "hubot", State: "OPEN", Labels: []string{"bug"}, URL: "https://github.com/acme/widgets/issues/3"}
+	if !reflect.DeepEqual(issue, want) {
+		t.Errorf("GetIssue() = %+v, want %+v", issue, want)
+	}
+
+	if _, err := github.GetIssue(widgets, 7); err == nil || !strings.Contains(err.Error(), "is a pull request") {
+		t.Errorf("GetIssue() for a PR error = %v", err)
+	}
+}
+
+func TestGitHub_CreateDraft(t *testing.T) {
+	server := newGitHubServer(t, nil)
+
+	change, err := NewGitHub("github.com", server.URL).CreateDraft(widgets, DraftOptions{Head: "feature/x", Base: "main", Title: "Add x"})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+	if change.Number != 9 || !change.Draft || change.HeadRef != "feature/x" || change.BaseRef != "main" {
+		t.Errorf("CreateDraft() = %+v", change)
+	}
+}
+
+func TestAPIClient_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		want    string
+	}{
+		{name: "not found", status: http.StatusNotFound, want: "not found"},
+		{name: "unauthorized", status: http.StatusUnauthorized, want: "authentication failed (check GH_TOKEN)"},
+		{name: "rate limited", status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0"}, want: "rate limit"},
+		{name: "server error", status: http.StatusInternalServerError, want: "500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"message": "nope"}`))
+			}))
+			defer server.Close()
+
+			err := newAPIClient("GitHub", server.URL, "GH_TOKEN").get("/repos/acme/widgets/pulls/7", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("get() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: `{"message": "Not Found"}`, want: "Not Found"},
+		{body: `{"error": {"message": "Bad credentials"}}`, want: "Bad credentials"},
+		{body: `{"error": "invalid_token"}`, want: "invalid_token"},
+		{body: "plain text\n", want: "plain text"},
+	}
+
+	for _, tt := range tests {
+		if got := apiErrorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("apiErrorMessage(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubAPIURL(t *testing.T) {
+	if got := gitHubAPIURL("github.com"); got != DefaultGitHubAPIURL {
+		t.Errorf("gitHubAPIURL(github.com) = %q", got)
+	}
+	if got := gitHubAPIURL("ghe.example.com"); got != "https://ghe.example.com/api/v3" {
+		t.Errorf("gitHubAPIURL(ghe.example.com) = %q", got)
+	}
+}
+
+func TestGitHubToken(t *testing.T) {
+	configDir := t.TempDir()
+	hosts := `github.com:
+    oauth_token: from-file
+    user: octocat
+ghe.example.com:
+    user: hubot
+    users:
+        hubot:
+            oauth_token: from-users
+`
+	if err := os.WriteFile(filepath.Join(configDir, "hosts.yml"), []byte(hosts), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GH_CONFIG_DIR", configDir)
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(name, "")
+	}
+
+	if got := gitHubToken("github.com"); got != "from-file" {
+		t.Errorf("gitHubToken() from hosts file = %q", got)
+	}
+	if got := gitHubToken("ghe.example.com"); got != "from-users" {
+		t.Errorf("gitHubToken() from multi-account hosts file = %q", got)
+	}
+	if got := gitHubToken("unknown.example.com"); got != "" {
+		t.Errorf("gitHubToken() for unknown host = %q", got)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "from-github-token")
+	if got := gitHubToken("github.com"); got != "from-github-token" {
+		t.Errorf("gitHubToken() with GITHUB_TOKEN = %q", got)
+	}
+	t.Setenv("GH_TOKEN", "from-gh-token")
+	if got := gitHubToken("github.com"); got != "from-gh-token" {
+		t.Errorf("gitHubToken() with GH_TOKEN = %q", got)
+	}
+	if got := gitHubToken("unknown.example.com"); got != "" {
+		t.Errorf("gitHubToken() sent the github.com token to another host: %q", got)
+	}
+	t.Setenv("GH_ENTERPRISE_TOKEN", "from-enterprise")
+	if got := gitHubToken("ghe.example.com"); got != "from-enterprise" {
+		t.Errorf("gitHubToken() with GH_ENTERPRISE_TOKEN = %q", got)
+	}
+}
+
+// readBody decodes a JSON request body, failing the test on error
+func readBody(t *testing.T, r io.Reader) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		t.Errorf("failed to decode request body: %v", err)
+	}
+	return body
+}
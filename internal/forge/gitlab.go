@@ -0,0 +1,280 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// gitLab talks to gitlab.com or a self-hosted GitLab, preferring the glab CLI
+// for metadata and falling back to the REST API. Changes are merge requests,
+// numbered by their project-scoped IID.
+type gitLab struct {
+	host string
+	api  *apiClient
+}
+
+// NewGitLab creates a GitLab forge for a host. An empty apiURL uses
+// https://<host>/api/v4.
+func NewGitLab(host, apiURL string) Forge {
+	if host == "" {
+		host = "gitlab.com"
+	}
+	if apiURL == "" {
+		apiURL = "https://" + host + "/api/v4"
+	}
+
+	api := newAPIClient("GitLab", apiURL, "GITLAB_TOKEN")
+	if token := gitLabToken(host); token != "" {
+		api.header.Set("PRIVATE-TOKEN", token)
+	}
+	return &gitLab{host: host, api: api}
+}
+
+func (g *gitLab) Kind() string { return GitLab }
+
+// ResolveRepo keeps the full project path, nested groups included
+func (g *gitLab) ResolveRepo(remoteURL string) (Repo, error) {
+	host, path, err := ParseRemoteURL(remoteURL)
+	if err != nil {
+		return Repo{}, err
+	}
+	return Repo{Host: host, Path: path}, nil
+}
+
+// GetChange reads MR metadata with the glab CLI, then the REST API
+func (g *gitLab) GetChange(repo Repo, number int) (*Change, error) {
+	if change, err := g.viewWithGlab(repo, number); err == nil {
+		return change, nil
+	}
+
+	var mr gitLabMR
+	if err := g.api.get(fmt.Sprintf("%s/merge_requests/%d", projectPath(repo), number), &mr); err != nil {
+		return nil, err
+	}
+	return mr.toChange(), nil
+}
+
+// FetchChange fetches refs/merge-requests/N/head, which also covers fork MRs
+func (g *gitLab) FetchChange(bareDir, remote string, change *Change, branch string) error {
+	return fetchRef(bareDir, remote, fmt.Sprintf("refs/merge-requests/%d/head", change.Number), branch)
+}
+
+// ListChanges lists open MRs; the API filters by author, reviewer and labels
+func (g *gitLab) ListChanges(repo Repo, opts ListOptions) ([]Change, error) {
+	opts, err := resolveMe(opts, func() (string, error) { return g.api.currentUser("/user", "username") })
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"state": {"opened"}, "per_page": {"100"}}
+	if opts.Author != "" {
+		query.Set("author_username", opts.Author)
+	}
+	if opts.ReviewRequested != "" {
+		query.Set("reviewer_username", opts.ReviewRequested)
+	}
+	if len(opts.Labels) > 0 {
+		query.Set("labels", strings.Join(opts.Labels, ","))
+	}
+
+	response, err := getAll[gitLabMR](g.api, projectPath(repo)+"/merge_requests?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0, len(response))
+	for _, mr := range response {
+		changes = append(changes, *mr.toChange())
+	}
+	return changes, nil
+}
+
+// CreateDraft opens a merge request marked as draft by its title prefix
+func (g *gitLab) CreateDraft(repo Repo, opts DraftOptions) (*Change, error) {
+	request := map[string]any{
+		"source_branch": opts.Head,
+		"target_branch": opts.Base,
+		"title":         "Draft: " + opts.Title,
+		"description":   opts.Body,
+	}
+	var mr gitLabMR
+	if err := g.api.post(projectPath(repo)+"/merge_requests", request, &mr); err != nil {
+		return nil, err
+	}
+	return mr.toChange(), nil
+}
+
+// GetIssue reads an issue with the glab CLI, then the REST API
+func (g *gitLab) GetIssue(repo Repo, number int) (*Issue, error) {
+	if _, err := runner.LookPath("glab"); err == nil {
+		if output, err := runner.Run("glab", "issue", "view", fmt.Sprintf("%d", number), "--repo", "https://"+repo.String(), "--output", "json"); err == nil {
+			var issue gitLabIssue
+			if err := json.Unmarshal(output, &issue); err == nil {
+				return issue.toIssue(), nil
+			}
+		}
+	}
+
+	var issue gitLabIssue
+	if err := g.api.get(fmt.Sprintf("%s/issues/%d", projectPath(repo), number), &issue); err != nil {
+		return nil, err
+	}
+	return issue.toIssue(), nil
+}
+
+// viewWithGlab reads MR metadata with the glab CLI
+func (g *gitLab) viewWithGlab(repo Repo, number int) (*Change, error) {
+	if _, err := runner.LookPath("glab"); err != nil {
+		return nil, fmt.Errorf("glab CLI not found")
+	}
+
+	// A full URL selects the host as well as the (possibly nested) project path
+	output, err := runner.Run("glab", "mr", "view", fmt.Sprintf("%d", number), "--repo", "https://"+repo.String(), "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var mr gitLabMR
+	if err := json.Unmarshal(output, &mr); err != nil {
+		return nil, fmt.Errorf("failed to parse MR info: %w", err)
+	}
+	return mr.toChange(), nil
+}
+
+// projectPath returns the API path of a project (group%2Fproject)
+func projectPath(repo Repo) string {
+	return "/projects/" + url.PathEscape(repo.Path)
+}
+
+// gitLabMR is the merge request representation shared by glab and the API
+type gitLabMR struct {
+	IID          int      `json:"iid"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	State        string   `json:"state"`
+	Draft        bool     `json:"draft"`
+	WebURL       string   `json:"web_url"`
+	Labels       []string `json:"labels"`
+	SourceBranch string   `json:"source_branch"`
+	TargetBranch string   `json:"target_branch"`
+	Author       struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	Reviewers []struct {
+		Username string `json:"username"`
+	} `json:"reviewers"`
+}
+
+// toChange converts a GitLab merge request
+func (mr gitLabMR) toChange() *Change {
+	state := strings.ToUpper(mr.State)
+	if state == "OPENED" {
+		state = "OPEN"
+	}
+	change := &Change{
+		Number:  mr.IID,
+		HeadRef: mr.SourceBranch,
+		BaseRef: mr.TargetBranch,
+		Title:   strings.TrimSpace(mr.Title),
+		Body:    strings.TrimSpace(mr.Description),
+		Author:  mr.Author.Username,
+		State:   state,
+		Draft:   mr.Draft,
+		Labels:  mr.Labels,
+		URL:     mr.WebURL,
+	}
+	for _, reviewer := range mr.Reviewers {
+		change.Reviewers = append(change.Reviewers, reviewer.Username)
+	}
+	return change
+}
+
+// gitLabIssue is the issue representation shared by glab and the API
+type gitLabIssue struct {
+	IID    int      `json:"iid"`
+	Title  string   `json:"title"`
+	State  string   `json:"state"`
+	WebURL string   `json:"web_url"`
+	Labels []string `json:"labels"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// toIssue converts a GitLab issue
+func (i gitLabIssue) toIssue() *Issue {
+	state := strings.ToUpper(i.State)
+	if state == "OPENED" {
+		state = "OPEN"
+	}
+	return &Issue{
+		Number: i.IID,
+		Title:  strings.TrimSpace(i.Title),
+		Author: i.Author.Username,
+		State:  state,
+		Labels: i.Labels,
+		URL:    i.WebURL,
+	}
+}
+
+// gitLabToken returns a token for the host from GITLAB_TOKEN, GITLAB_ACCESS_TOKEN
+// or glab's config file
+func gitLabToken(host string) string {
+	if token := firstEnv("GITLAB_TOKEN", "GITLAB_ACCESS_TOKEN"); token != "" {
+		return token
+	}
+	token, _ := tokenFromGlabConfig(glabConfigFile(), host)
+	return token
+}
+
+// glabConfigFile returns the path of glab's config.yml
+func glabConfigFile() string {
+	if dir := os.Getenv("GLAB_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "config.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "glab-cli", "config.yml")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "glab-cli", "config.yml")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "glab-cli", "config.yml")
+}
+
+// tokenFromGlabConfig reads the token of a host from glab's config.yml
+func tokenFromGlabConfig(path, host string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var cfg struct {
+		Hosts map[string]struct {
+			Token string `yaml:"token"`
+		} `yaml:"hosts"`
+	}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg.Hosts[host].Token, nil
+}
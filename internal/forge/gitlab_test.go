@@ -0,0 +1,184 @@
+package forge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mergeRequestJSON is a trimmed GitLab API response
+const mergeRequestJSON = `{
+  "iid": 45,
+  "title": "Speed up widgets",
+  "description": "Closes #12",
+  "state": "opened",
+  "labels": ["perf"],
+  "web_url": "https://git.example.com/group/sub/app/-/merge_requests/45",
+  "source_branch": "perf/widgets",
+  "target_branch": "develop",
+  "author": {"username": "tanuki"}
+}`
+
+var app = Repo{Host: "git.example.com", Path: "group/sub/app"}
+
+func TestGitLab_GetChange(t *testing.T) {
+	if _, err := exec.LookPath("glab"); err == nil {
+		t.Skip("glab CLI is installed and would be used instead of the API")
+	}
+
+	t.Setenv("GITLAB_TOKEN", "glpat-secret")
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("PRIVATE-TOKEN")
+		if r.URL.EscapedPath() != "/projects/group%2Fsub%2Fapp/merge_requests/45" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "404 Not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(mergeRequestJSON))
+	}))
+	defer server.Close()
+
+	gitlab := NewGitLab(app.Host, server.URL)
+	change, err := gitlab.GetChange(app, 45)
+	if err != nil {
+		t.Fatalf("GetChange() error = %v", err)
+	}
+
+	want := &Change{
+		Number:  45,
+		HeadRef: "perf/widgets",
+		BaseRef: "develop",
+		Title:   "Speed up widgets",
+		Body:    "Closes #12",
+		Author:  "tanuki",
+		State:   "OPEN",
+		Labels:  []string{"perf"},
+		URL:     "https://git.example.com/group/sub/app/-/merge_requests/45",
+	}
+	if !reflect.DeepEqual(change, want) {
+		t.Errorf("GetChange() = %+v, want %+v", change, want)
+	}
+	if token != "glpat-secret" {
+		t.Errorf("PRIVATE-TOKEN header = %q", token)
+	}
+
+	if _, err := gitlab.GetChange(app, 46); err == nil {
+		t.Errorf("GetChange() for unknown MR expected error, got nil")
+	}
+}
+
+func TestGitLab_ListChanges(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(`[` + mergeRequestJSON + `]`))
+	}))
+	defer server.Close()
+
+	changes, err := NewGitLab(app.Host, server.URL).ListChanges(app, ListOptions{Author: "tanuki", Labels: []string{"perf", "ui"}})
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Number != 45 {
+		t.Errorf("ListChanges() = %+v", changes)
+	}
+	if want := "author_username=tanuki&labels=perf%2Cui&per_page=100&state=opened"; query != want {
+		t.Errorf("ListChanges() query = %q, want %q", query, want)
+	}
+
+	// Me needs a token to look up the authenticated user
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("GITLAB_ACCESS_TOKEN", "")
+	t.Setenv("GLAB_CONFIG_DIR", t.TempDir())
+	if _, err := NewGitLab(app.Host, server.URL).ListChanges(app, ListOptions{ReviewRequested: Me}); err == nil || !strings.Contains(err.Error(), "GITLAB_TOKEN") {
+		t.Errorf("ListChanges() for @me without token error = %v", err)
+	}
+}
+
+func TestGitLab_GetIssue(t *testing.T) {
+	if _, err := exec.LookPath("glab"); err == nil {
+		t.Skip("glab CLI is installed and would be used instead of the API")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/group%2Fsub%2Fapp/issues/12" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"iid": 12, "title": "Slow start", "state": "opened", "labels": ["perf"], "author": {"username": "tanuki"}, "web_url": "https://git.example.com/group/sub/app/-/issues/12"}`))
+	}))
+	defer server.Close()
+
+	issue, err := NewGitLab(app.Host, server.URL).GetIssue(app, 12)
+	if err != nil {
+		t.Fatalf("GetIssue() error = %v", err)
+	}
+	want := &Issue{Number: 12, Title: "Slow start", Author: "tanuki", State: "OPEN", Labels: []string{"perf"}, URL: "https://git.example.com/group/sub/app/-/issues/12"}
+	if !reflect.DeepEqual(issue, want) {
+		t.Errorf("GetIssue() = %+v, want %+v", issue, want)
+	}
+}
+
+func TestGitLab_CreateDraft(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := readBody(t, r.Body)
+		if r.Method != http.MethodPost || r.URL.EscapedPath() != "/projects/group%2Fsub%2Fapp/merge_requests" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		if body["title"] != "Draft: Speed up" || body["source_branch"] != "perf/widgets" || body["target_branch"] != "develop" {
+			t.Errorf("unexpected request body %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"iid": 46, "title": "Draft: Speed up", "draft": true, "state": "opened"}`))
+	}))
+	defer server.Close()
+
+	change, err := NewGitLab(app.Host, server.URL).CreateDraft(app, DraftOptions{Head: "perf/widgets", Base: "develop", Title: "Speed up"})
+	if err != nil {
+		t.Fatalf("CreateDraft() error = %v", err)
+	}
+	if change.Number != 46 || !change.Draft {
+		t.Errorf("CreateDraft() = %+v", change)
+	}
+}
+
+func TestGitLab_ResolveRepo(t *testing.T) {
+	repo, err := NewGitLab("", "").ResolveRepo("git@git.example.com:group/sub/app.git")
+	if err != nil {
+		t.Fatalf("ResolveRepo() error = %v", err)
+	}
+	if repo != app {
+		t.Errorf("ResolveRepo() = %+v, want %+v", repo, app)
+	}
+}
+
+func TestGitLabToken(t *testing.T) {
+	configDir := t.TempDir()
+	config := `hosts:
+    gitlab.com:
+        token: from-glab
+    git.example.com:
+        token: from-self-hosted
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GLAB_CONFIG_DIR", configDir)
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("GITLAB_ACCESS_TOKEN", "")
+
+	if got := gitLabToken("git.example.com"); got != "from-self-hosted" {
+		t.Errorf("gitLabToken() from glab config = %q", got)
+	}
+
+	t.Setenv("GITLAB_TOKEN", "from-env")
+	if got := gitLabToken("gitlab.com"); got != "from-env" {
+		t.Errorf("gitLabToken() with GITLAB_TOKEN = %q", got)
+	}
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+)
+
+// Classes of git failures, matched with errors.Is
+var (
+	ErrBranchExists     = errors.New("branch already exists")
+	ErrBranchCheckedOut = errors.New("branch is already checked out in another worktree")
+	ErrPathExists       = errors.New("path already exists")
+	ErrInvalidRef       = errors.New("invalid reference")
+	ErrDirtyWorktree    = errors.New("worktree has local changes")
+	ErrWorktreeLocked   = errors.New("worktree is locked")
+	ErrRemoteNotFound   = errors.New("remote repository not found")
+	ErrAuth             = errors.New("authentication failed")
+	ErrNetwork          = errors.New("cannot reach the remote")
+)
+
+// Error is a git command that failed in a way git reports recognisably. It
+// matches its class with errors.Is and the *runner.Error with errors.As.
+type Error struct {
+	Kind   error  // One of the Err* classes
+	Branch string // Branch git names in the message, if any
+	Path   string // Path git names in the message, if any
+	Err    *runner.Error
+}
+
+// Error returns git's own message, e.g., "git worktree add failed: fatal: ..."
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+var (
+	checkedOutPattern   = regexp.MustCompile(`'([^']+)' is already (?:checked out|used by worktree) at '([^']+)'`)
+	branchExistsPattern = regexp.MustCompile(`(?i)a branch named '([^']+)' already exists`)
+	pathExistsPattern   = regexp.MustCompile(`(?m)^fatal: '([^']+)' already exists`)
+)
+
+// messageClasses map fragments of git's stderr (lowercased) to error classes.
+// Earlier entries win: a missing repository also reports that the remote
+// cannot be read.
+var messageClasses = []struct {
+	fragment string
+	kind     error
+}{
+	{"contains modified or untracked files", ErrDirtyWorktree},
+	{"locked working tree", ErrWorktreeLocked},
+	{"invalid reference", ErrInvalidRef},
+	{"not a valid object name", ErrInvalidRef},
+	{"unknown revision", ErrInvalidRef},
+	{"bad revision", ErrInvalidRef},
+	{"couldn't find remote ref", ErrInvalidRef},
+	{"is not a valid branch name", ErrInvalidRef},
+	{"is not a commit", ErrInvalidRef},
+	{"repository not found", ErrRemoteNotFound},
+	{"fatal: repository '", ErrRemoteNotFound}, // ... not found, or ... does not exist
+	{"does not appear to be a git repository", ErrRemoteNotFound},
+	{"authentication failed", ErrAuth},
+	{"permission denied (publickey", ErrAuth},
+	{"could not read username", ErrAuth},
+	{"terminal prompts disabled", ErrAuth},
+	{"could not resolve host", ErrNetwork},
+	{"connection refused", ErrNetwork},
+	{"connection timed out", ErrNetwork},
+	{"operation timed out", ErrNetwork},
+	{"network is unreachable", ErrNetwork},
+	{"unable to access", ErrNetwork},
+	{"could not read from remote repository", ErrNetwork},
+}
+
+// classify turns a failed git command into an *Error when its stderr is
+// recognised, and returns other errors unchanged
+func classify(err error) error {
+	var runErr *runner.Error
+	if !errors.As(err, &runErr) || runErr.Stderr == "" {
+		return err
+	}
+	stderr := runErr.Stderr
+
+	if m := checkedOutPattern.FindStringSubmatch(stderr); m != nil {
+		return &Error{Kind: ErrBranchCheckedOut, Branch: m[1], Path: m[2], Err: runErr}
+	}
+	if m := branchExistsPattern.FindStringSubmatch(stderr); m != nil {
+		return &Error{Kind: ErrBranchExists, Branch: m[1], Err: runErr}
+	}
+	if m := pathExistsPattern.FindStringSubmatch(stderr); m != nil {
+		return &Error{Kind: ErrPathExists, Path: m[1], Err: runErr}
+	}
+
+	lower := strings.ToLower(stderr)
+	for _, class := range messageClasses {
+		if strings.Contains(lower, class.fragment) {
+			return &Error{Kind: class.kind, Err: runErr}
+		}
+	}
+	return err
+}
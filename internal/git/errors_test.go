@@ -0,0 +1,111 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		stderr     string
+		wantKind   error
+		wantBranch string
+		wantPath   string
+	}{
+		{
+			name:       "checked out",
+			stderr:     "fatal: 'main' is already checked out at '/src/app/main'\n",
+			wantKind:   ErrBranchCheckedOut,
+			wantBranch: "main",
+			wantPath:   "/src/app/main",
+		},
+		{
+			name:       "used by worktree (git 2.42+)",
+			stderr:     "fatal: 'main' is already used by worktree at '/src/app/main'\n",
+			wantKind:   ErrBranchCheckedOut,
+			wantBranch: "main",
+			wantPath:   "/src/app/main",
+		},
+		{
+			name:       "branch exists",
+			stderr:     "fatal: a branch named 'feature-x' already exists\n",
+			wantKind:   ErrBranchExists,
+			wantBranch: "feature-x",
+		},
+		{
+			name:     "locked worktree",
+			stderr:   "fatal: cannot remove a locked working tree, lock reason: on the USB drive\nuse 'remove -f -f' to override or unlock first\n",
+			wantKind: ErrWorktreeLocked,
+		},
+		{
+			name:     "path exists",
+			stderr:   "fatal: '/src/app/feature-x' already exists\n",
+			wantKind: ErrPathExists,
+			wantPath: "/src/app/feature-x",
+		},
+		{
+			name:     "invalid reference",
+			stderr:   "fatal: invalid reference: nope\n",
+			wantKind: ErrInvalidRef,
+		},
+		{
+			name:     "missing remote ref",
+			stderr:   "fatal: couldn't find remote ref refs/heads/nope\n",
+			wantKind: ErrInvalidRef,
+		},
+		{
+			name:     "dirty worktree",
+			stderr:   "fatal: '/src/app/x' contains modified or untracked files, use --force to delete it\n",
+			wantKind: ErrDirtyWorktree,
+		},
+		{
+			name:     "repository not found",
+			stderr:   "remote: Repository not found.\nfatal: repository 'https://github.com/acme/nope.git/' not found\n",
+			wantKind: ErrRemoteNotFound,
+		},
+		{
+			name:     "ssh key rejected",
+			stderr:   "git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.\n",
+			wantKind: ErrAuth,
+		},
+		{
+			name:     "host unreachable",
+			stderr:   "fatal: unable to access 'https://example.invalid/x.git/': Could not resolve host: example.invalid\n",
+			wantKind: ErrNetwork,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runErr := &runner.Error{Command: runner.Command{Name: "git", Args: []string{"fetch"}}, ExitCode: 128, Stderr: tt.stderr}
+			err := classify(runErr)
+
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("classify() = %v, want %v", err, tt.wantKind)
+			}
+			var gitErr *Error
+			if !errors.As(err, &gitErr) {
+				t.Fatalf("classify() = %T, want *Error", err)
+			}
+			if gitErr.Branch != tt.wantBranch || gitErr.Path != tt.wantPath {
+				t.Errorf("Branch, Path = %q, %q, want %q, %q", gitErr.Branch, gitErr.Path, tt.wantBranch, tt.wantPath)
+			}
+			var asRunErr *runner.Error
+			if !errors.As(err, &asRunErr) || runner.ExitCode(err) != 128 {
+				t.Errorf("classify() should keep the *runner.Error")
+			}
+			if err.Error() != runErr.Error() {
+				t.Errorf("Error() = %q, want git's message %q", err.Error(), runErr.Error())
+			}
+		})
+	}
+
+	// Unrecognised failures are returned unchanged
+	runErr := &runner.Error{Command: runner.Command{Name: "git"}, ExitCode: 1, Stderr: "error: something else\n"}
+	if err := classify(runErr); err != error(runErr) {
+		t.Errorf("classify() = %v, want the original error", err)
+	}
+}
@@ -2,10 +2,31 @@ package git
 
 import (
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
 )
 
+// run runs git with the current runner and returns its stdout. Failures are
+// *runner.Error values reporting git's stderr (e.g., "git fetch failed: ..."),
+// wrapped in an *Error when git's message is recognised.
+func run(args ...string) (string, error) {
+	output, err := runner.Run("git", args...)
+	return string(output), classify(err)
+}
+
+// runNoSmudge runs git like run, but leaves Git LFS files as pointers, so
+// they can be downloaded afterwards with PullLFS and its filters
+func runNoSmudge(args ...string) (string, error) {
+	output, err := runner.RunCommand(runner.Command{Name: "git", Args: args, Env: []string{"GIT_LFS_SKIP_SMUDGE=1"}})
+	return string(output), classify(err)
+}
+
 // ConvertGitHubFormat converts GitHub shorthand to git URL
 func ConvertGitHubFormat(repo string) string {
 	// If already a full URL, return as-is
@@ -23,37 +44,78 @@ func ConvertGitHubFormat(repo string) string {
 	return repo
 }
 
+// CloneOptions make a clone smaller than a full one
+type CloneOptions struct {
+	Depth        int    // Truncate history to this many commits (shallow clone); 0 for all
+	Filter       string // Partial clone filter, e.g., "blob:none" or "tree:0"
+	SingleBranch bool   // Only clone one branch: Branch, or the remote's HEAD
+	Branch       string // Branch HEAD points to instead of the remote's HEAD
+}
+
+// DepthConfig is the config key recording the depth of a shallow clone, so
+// later fetches keep the same depth
+const DepthConfig = "wtm.depth"
+
 // CloneBare clones a repository as a bare repository
-func CloneBare(url, bareDir string) error {
-	cmd := exec.Command("git", "clone", "--bare", url, bareDir)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git clone failed: %s", string(output))
+func CloneBare(url, bareDir string, opts CloneOptions) error {
+	args := []string{"clone", "--bare"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	args = append(args, url, bareDir)
+
+	if _, err := run(args...); err != nil {
+		return err
+	}
+	if opts.Depth > 0 {
+		return SetConfig(bareDir, DepthConfig, strconv.Itoa(opts.Depth))
 	}
 	return nil
 }
 
+// IsShallow reports whether the repository is a shallow clone
+func IsShallow(bareDir string) bool {
+	output, err := run("--git-dir="+bareDir, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(output) == "true"
+}
+
+// shallowArgs returns the fetch options that keep a shallow clone shallow:
+// without them, fetching a new branch downloads its whole history
+func shallowArgs(bareDir string) []string {
+	if !IsShallow(bareDir) {
+		return nil
+	}
+	depth, _ := GetConfig(bareDir, DepthConfig)
+	if depth == "" {
+		depth = "1"
+	}
+	return []string{"--depth", depth}
+}
+
 // InitBare initializes a new bare repository with an initial branch
 func InitBare(bareDir string) error {
-	cmd := exec.Command("git", "init", "--bare", bareDir)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git init failed: %s", string(output))
-	}
-	return nil
+	_, err := run("init", "--bare", bareDir)
+	return err
 }
 
 // ensureGitUserConfigured checks if git user.name and user.email are configured
 func ensureGitUserConfigured() error {
 	// Check user.name
-	cmd := exec.Command("git", "config", "--get", "user.name")
-	if err := cmd.Run(); err != nil {
+	if _, err := run("config", "--get", "user.name"); err != nil {
 		return fmt.Errorf("git user.name is not configured. Please run:\n  git config --global user.name \"Your Name\"")
 	}
 
 	// Check user.email
-	cmd = exec.Command("git", "config", "--get", "user.email")
-	if err := cmd.Run(); err != nil {
+	if _, err := run("config", "--get", "user.email"); err != nil {
 		return fmt.Errorf("git user.email is not configured. Please run:\n  git config --global user.email \"your.email@example.com\"")
 	}
 
@@ -68,29 +130,24 @@ func CreateInitialBranch(bareDir, branchName string) error {
 	}
 
 	// Set default branch in bare repo
-	cmd := exec.Command("git", "--git-dir="+bareDir, "symbolic-ref", "HEAD", "refs/heads/"+branchName)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to set default branch: %s", string(output))
+	if _, err := run("--git-dir="+bareDir, "symbolic-ref", "HEAD", "refs/heads/"+branchName); err != nil {
+		return fmt.Errorf("failed to set default branch: %w", err)
 	}
 
 	// Git's canonical empty tree hash (this is a constant in Git)
 	emptyTree := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
 
 	// Create initial commit
-	cmd = exec.Command("git", "--git-dir="+bareDir, "commit-tree", emptyTree, "-m", "Initial commit")
-	commitHash, err := cmd.Output()
+	commitHash, err := run("--git-dir="+bareDir, "commit-tree", emptyTree, "-m", "Initial commit")
 	if err != nil {
 		return fmt.Errorf("failed to create initial commit: %w", err)
 	}
 
-	commitID := strings.TrimSpace(string(commitHash))
+	commitID := strings.TrimSpace(commitHash)
 
 	// Update branch reference
-	cmd = exec.Command("git", "--git-dir="+bareDir, "update-ref", "refs/heads/"+branchName, commitID)
-	output, err = cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to update branch reference: %s", string(output))
+	if _, err := run("--git-dir="+bareDir, "update-ref", "refs/heads/"+branchName, commitID); err != nil {
+		return fmt.Errorf("failed to update branch reference: %w", err)
 	}
 
 	return nil
@@ -99,41 +156,35 @@ func CreateInitialBranch(bareDir, branchName string) error {
 // GetDefaultBranch returns the default branch of the repository
 func GetDefaultBranch(bareDir string) (string, error) {
 	// First, try to get HEAD symbolic ref (works for new and cloned repos)
-	cmd := exec.Command("git", "--git-dir="+bareDir, "symbolic-ref", "--short", "HEAD")
-	output, err := cmd.Output()
-	if err == nil {
-		branch := strings.TrimSpace(string(output))
+	if output, err := run("--git-dir="+bareDir, "symbolic-ref", "--short", "HEAD"); err == nil {
+		branch := strings.TrimSpace(output)
 		if branch != "" {
 			return branch, nil
 		}
 	}
 
 	// Try to get symbolic ref from origin
-	cmd = exec.Command("git", "--git-dir="+bareDir, "symbolic-ref", "refs/remotes/origin/HEAD")
-	output, err = cmd.Output()
-	if err == nil {
+	if output, err := run("--git-dir="+bareDir, "symbolic-ref", "refs/remotes/origin/HEAD"); err == nil {
 		// Parse refs/remotes/origin/main -> main
-		branch := strings.TrimSpace(string(output))
+		branch := strings.TrimSpace(output)
 		branch = strings.TrimPrefix(branch, "refs/remotes/origin/")
 		return branch, nil
 	}
 
 	// Fallback: try common branch names
 	for _, branch := range []string{"main", "master", "develop"} {
-		cmd := exec.Command("git", "--git-dir="+bareDir, "show-ref", "--verify", "refs/heads/"+branch)
-		if err := cmd.Run(); err == nil {
+		if _, err := run("--git-dir="+bareDir, "show-ref", "--verify", "refs/heads/"+branch); err == nil {
 			return branch, nil
 		}
 	}
 
 	// Last resort: get first branch
-	cmd = exec.Command("git", "--git-dir="+bareDir, "branch", "--format=%(refname:short)")
-	output, err = cmd.Output()
+	output, err := run("--git-dir="+bareDir, "branch", "--format=%(refname:short)")
 	if err != nil {
 		return "", fmt.Errorf("no branches found")
 	}
 
-	branches := strings.Split(strings.TrimSpace(string(output)), "\n")
+	branches := strings.Split(strings.TrimSpace(output), "\n")
 	if len(branches) > 0 && branches[0] != "" {
 		return branches[0], nil
 	}
@@ -144,6 +195,7 @@ func GetDefaultBranch(bareDir string) (string, error) {
 // AddWorktree adds a new worktree
 // If startPoint is empty, branch must exist
 // If startPoint is provided, creates new branch from startPoint
+// Submodules and Git LFS files are left to UpdateSubmodules and PullLFS.
 func AddWorktree(bareDir, branch, path, startPoint string) error {
 	args := []string{"--git-dir=" + bareDir, "worktree", "add"}
 
@@ -155,87 +207,249 @@ func AddWorktree(bareDir, branch, path, startPoint string) error {
 		args = append(args, path, branch)
 	}
 
-	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git worktree add failed: %s", string(output))
+	_, err := runNoSmudge(args...)
+	return err
+}
+
+// AddWorktreeSparse adds a new worktree like AddWorktree, but only checks out
+// the directories matching the cone-mode sparse-checkout patterns
+func AddWorktreeSparse(bareDir, branch, path, startPoint string, patterns []string) error {
+	args := []string{"--git-dir=" + bareDir, "worktree", "add", "--no-checkout"}
+	if startPoint != "" {
+		args = append(args, "-b", branch, path, startPoint)
+	} else {
+		args = append(args, path, branch)
+	}
+	if _, err := run(args...); err != nil {
+		return err
 	}
 
-	// Initialize submodules if any
-	cmd = exec.Command("git", "-C", path, "submodule", "update", "--init", "--recursive")
-	_ = cmd.Run() // Ignore errors as submodules may not exist
+	if err := SetSparseCheckout(path, patterns); err != nil {
+		return err
+	}
+	// The worktree was added without checkout: populate it from HEAD
+	_, err := runNoSmudge("-C", path, "read-tree", "-mu", "HEAD")
+	return err
+}
 
-	return nil
+// SetSparseCheckout restricts a worktree to the directories matching the
+// cone-mode patterns, enabling sparse checkout when needed
+func SetSparseCheckout(worktreePath string, patterns []string) error {
+	_, err := run(append([]string{"-C", worktreePath, "sparse-checkout", "set", "--cone", "--"}, patterns...)...)
+	return err
+}
+
+// DisableSparseCheckout checks out all files of a sparse worktree again
+func DisableSparseCheckout(worktreePath string) error {
+	_, err := run("-C", worktreePath, "sparse-checkout", "disable")
+	return err
+}
+
+// SparseCheckoutPatterns returns the cone-mode directories of a sparse
+// worktree, or nil when it checks out all files
+func SparseCheckoutPatterns(worktreePath string) ([]string, error) {
+	if enabled, _ := run("-C", worktreePath, "config", "--bool", "core.sparseCheckout"); strings.TrimSpace(enabled) != "true" {
+		return nil, nil
+	}
+	output, err := run("-C", worktreePath, "sparse-checkout", "list")
+	if err != nil {
+		return nil, err
+	}
+	if output = strings.TrimSpace(output); output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
 }
 
 // LocalBranchExists checks if a local branch exists in the repository (not remote)
 func LocalBranchExists(bareDir, branch string) (bool, error) {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
-	err := cmd.Run()
+	_, err := run("--git-dir="+bareDir, "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
 	return err == nil, nil
 }
 
 // BranchExists checks if a branch exists in the repository (local or remote)
 func BranchExists(bareDir, branch string) (bool, error) {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
-	err := cmd.Run()
-	if err == nil {
+	if _, err := run("--git-dir="+bareDir, "show-ref", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
 		return true, nil
 	}
 
 	// Check if it's a remote branch
-	cmd = exec.Command("git", "--git-dir="+bareDir, "show-ref", "--verify", "--quiet", "refs/remotes/origin/"+branch)
-	err = cmd.Run()
+	_, err := run("--git-dir="+bareDir, "show-ref", "--verify", "--quiet", "refs/remotes/origin/"+branch)
 	return err == nil, nil
 }
 
 // ListWorktrees lists all worktrees
 func ListWorktrees(bareDir string) (string, error) {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "worktree", "list")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("git worktree list failed: %s", string(output))
-	}
-	return string(output), nil
+	return run("--git-dir="+bareDir, "worktree", "list")
 }
 
 // RemoveWorktree removes a worktree
 func RemoveWorktree(bareDir, path string) error {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "worktree", "remove", path)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git worktree remove failed: %s", string(output))
-	}
-	return nil
+	_, err := run("--git-dir="+bareDir, "worktree", "remove", path)
+	return err
 }
 
 // RemoveWorktreeForce removes a worktree forcefully
 func RemoveWorktreeForce(bareDir, path string) error {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "worktree", "remove", "--force", path)
-	output, err := cmd.CombinedOutput()
+	_, err := run("--git-dir="+bareDir, "worktree", "remove", "--force", path)
+	return err
+}
+
+// RemoveLockedWorktree removes a worktree forcefully, even when it is locked
+func RemoveLockedWorktree(bareDir, path string) error {
+	_, err := run("--git-dir="+bareDir, "worktree", "remove", "--force", "--force", path)
+	return err
+}
+
+// WorktreeRecord is a linked worktree as the bare repository records it
+type WorktreeRecord struct {
+	ID     string // Name of its administrative directory in <bare>/worktrees
+	Path   string // Worktree directory git last recorded
+	GitDir string // The administrative directory
+}
+
+// WorktreeRecords reads the linked worktrees recorded in the bare repository
+// from their administrative directories, so it works when the recorded paths
+// are stale
+func WorktreeRecords(bareDir string) ([]WorktreeRecord, error) {
+	entries, err := os.ReadDir(filepath.Join(bareDir, "worktrees"))
 	if err != nil {
-		return fmt.Errorf("git worktree remove failed: %s", string(output))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
-	return nil
+
+	var records []WorktreeRecord
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		adminDir := filepath.Join(bareDir, "worktrees", entry.Name())
+		content, err := os.ReadFile(filepath.Join(adminDir, "gitdir"))
+		if err != nil {
+			continue
+		}
+
+		// gitdir holds the path of the worktree's .git file, relative to
+		// the administrative directory with worktree.useRelativePaths
+		gitFile := strings.TrimSpace(string(content))
+		if !filepath.IsAbs(gitFile) {
+			gitFile = filepath.Join(adminDir, gitFile)
+		}
+		records = append(records, WorktreeRecord{ID: entry.Name(), Path: filepath.Dir(filepath.Clean(gitFile)), GitDir: adminDir})
+	}
+	return records, nil
 }
 
-// DeleteBranch deletes a branch
-func DeleteBranch(bareDir, branch string) error {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "branch", "-D", branch)
-	output, err := cmd.CombinedOutput()
+// WorktreeLink returns the administrative directory a worktree's .git file
+// points to
+func WorktreeLink(worktreePath string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(worktreePath, ".git"))
 	if err != nil {
-		return fmt.Errorf("git branch delete failed: %s", string(output))
+		return "", err
 	}
-	return nil
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("%s is not a linked worktree", worktreePath)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(worktreePath, gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// SetWorktreeLink points a worktree's .git file at an administrative directory
+func SetWorktreeLink(worktreePath, gitDir string) error {
+	return os.WriteFile(filepath.Join(worktreePath, ".git"), []byte("gitdir: "+gitDir+"\n"), 0644)
+}
+
+// RepairWorktrees rewrites the links between the bare repository and the
+// worktrees at paths, e.g., after they were moved. With relative set, the
+// links are written as relative paths (git 2.48 or later).
+func RepairWorktrees(bareDir string, paths []string, relative bool) error {
+	args := []string{"--git-dir=" + bareDir, "worktree", "repair"}
+	if relative {
+		args = append(args, "--relative-paths")
+	}
+	_, err := run(append(args, paths...)...)
+	return err
+}
+
+// Version returns the major and minor version of the installed git
+func Version() (major, minor int, err error) {
+	output, err := run("--version")
+	if err != nil {
+		return 0, 0, err
+	}
+	m := versionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return 0, 0, fmt.Errorf("unrecognised git version %q", strings.TrimSpace(output))
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	return major, minor, nil
+}
+
+var versionPattern = regexp.MustCompile(`git version (\d+)\.(\d+)`)
+
+// LockWorktree locks a worktree, so it is not removed or pruned, e.g.,
+// while it is on a drive that is not mounted
+func LockWorktree(bareDir, path, reason string) error {
+	args := []string{"--git-dir=" + bareDir, "worktree", "lock"}
+	if reason != "" {
+		args = append(args, "--reason", reason)
+	}
+	_, err := run(append(args, path)...)
+	return err
+}
+
+// UnlockWorktree unlocks a locked worktree
+func UnlockWorktree(bareDir, path string) error {
+	_, err := run("--git-dir="+bareDir, "worktree", "unlock", path)
+	return err
+}
+
+// WorktreeLock reports whether a worktree is locked, and the reason given
+// for the lock, if any
+func WorktreeLock(worktreePath string) (locked bool, reason string, err error) {
+	gitDir, err := GetGitDir(worktreePath)
+	if err != nil {
+		return false, "", err
+	}
+	return readLock(gitDir)
+}
+
+// Lock reports whether a recorded worktree is locked, and the reason given
+// for the lock, if any. Unlike WorktreeLock, it works when the worktree's
+// directory is missing.
+func (r WorktreeRecord) Lock() (locked bool, reason string, err error) {
+	return readLock(r.GitDir)
+}
+
+// readLock reads the lock of a worktree from its administrative directory
+func readLock(gitDir string) (locked bool, reason string, err error) {
+	content, err := os.ReadFile(filepath.Join(gitDir, "locked"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	return true, strings.TrimSpace(string(content)), nil
+}
+
+// DeleteBranch deletes a branch
+func DeleteBranch(bareDir, branch string) error {
+	_, err := run("--git-dir="+bareDir, "branch", "-D", branch)
+	return err
 }
 
 // HasUncommittedChanges checks if the worktree has uncommitted changes
 func HasUncommittedChanges(path string) (bool, error) {
-	cmd := exec.Command("git", "-C", path, "diff-index", "--quiet", "HEAD", "--")
-	err := cmd.Run()
-	if err != nil {
+	if _, err := run("-C", path, "diff-index", "--quiet", "HEAD", "--"); err != nil {
 		// Exit code 1 means there are changes
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		if runner.ExitCode(err) == 1 {
 			return true, nil
 		}
 		return false, err
@@ -243,39 +457,430 @@ func HasUncommittedChanges(path string) (bool, error) {
 	return false, nil
 }
 
-// HasUntrackedFiles checks if the worktree has untracked files
-func HasUntrackedFiles(path string) (bool, error) {
-	cmd := exec.Command("git", "-C", path, "ls-files", "--others", "--exclude-standard")
-	output, err := cmd.Output()
+// UntrackedFiles returns the untracked, non-ignored paths in the worktree, relative to its root
+func UntrackedFiles(path string) ([]string, error) {
+	output, err := run("-C", path, "ls-files", "--others", "--exclude-standard")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return len(strings.TrimSpace(string(output))) > 0, nil
+
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
 }
 
 // FetchRef fetches a specific ref from origin
 func FetchRef(bareDir, ref string) error {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "fetch", "origin", ref)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git fetch failed: %s", string(output))
-	}
-	return nil
+	_, err := run("--git-dir="+bareDir, "fetch", "origin", ref)
+	return err
 }
 
 // GetWorktreeBranch returns the branch name checked out in a worktree
 // Returns an error if the worktree is in detached HEAD state or if git command fails
 func GetWorktreeBranch(worktreePath string) (string, error) {
-	cmd := exec.Command("git", "-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmd.Output()
+	output, err := run("-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("failed to get branch name: %w", err)
 	}
 
-	branch := strings.TrimSpace(string(output))
+	branch := strings.TrimSpace(output)
 	if branch == "" || branch == "HEAD" {
 		return "", fmt.Errorf("worktree is in detached HEAD state")
 	}
 
 	return branch, nil
 }
+
+// GetGitDir returns the absolute administrative directory of a worktree
+// (e.g., <root>/.bare/worktrees/<name>)
+func GetGitDir(worktreePath string) (string, error) {
+	output, err := run("-C", worktreePath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// EnableWorktreeConfig turns on extensions.worktreeConfig so each worktree can
+// have its own config.worktree. As git requires, core.bare is moved from the
+// shared config to the bare repository's own config.worktree.
+func EnableWorktreeConfig(bareDir string) error {
+	if output, err := run("--git-dir="+bareDir, "config", "--bool", "extensions.worktreeConfig"); err == nil && strings.TrimSpace(output) == "true" {
+		return nil
+	}
+
+	// Extensions are only honoured from repository format version 1
+	steps := [][]string{
+		{"config", "core.repositoryFormatVersion", "1"},
+		{"config", "extensions.worktreeConfig", "true"},
+		{"config", "--worktree", "core.bare", "true"},
+	}
+	for _, args := range steps {
+		if _, err := run(append([]string{"--git-dir=" + bareDir}, args...)...); err != nil {
+			return err
+		}
+	}
+
+	// Exit code 5 means the key was not set
+	if _, err := run("--git-dir="+bareDir, "config", "--unset", "core.bare"); err != nil && runner.ExitCode(err) != 5 {
+		return err
+	}
+	return nil
+}
+
+// GetWorktreeConfig returns a value from a worktree's config.worktree, or ""
+// when it is not set
+func GetWorktreeConfig(worktreePath, key string) (string, error) {
+	// Read the file directly: --worktree is rejected until extensions.worktreeConfig is enabled
+	gitDir, err := GetGitDir(worktreePath)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := run("config", "--file", filepath.Join(gitDir, "config.worktree"), "--get", key)
+	if err != nil {
+		// Exit code 1 means the key is not set, or the file does not exist
+		if runner.ExitCode(err) == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// SetWorktreeConfig sets a value in a worktree's config.worktree
+func SetWorktreeConfig(worktreePath, key, value string) error {
+	_, err := run("-C", worktreePath, "config", "--worktree", key, value)
+	return err
+}
+
+// UnsetWorktreeConfig removes a value from a worktree's config.worktree
+func UnsetWorktreeConfig(worktreePath, key string) error {
+	gitDir, err := GetGitDir(worktreePath)
+	if err != nil {
+		return err
+	}
+
+	configPath := filepath.Join(gitDir, "config.worktree")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	// Exit code 5 means the key was not set
+	if _, err := run("config", "--file", configPath, "--unset", key); err != nil && runner.ExitCode(err) != 5 {
+		return err
+	}
+	return nil
+}
+
+// GlobalExcludesFile returns the user's global excludes file: core.excludesFile
+// from the global config, or the XDG default
+func GlobalExcludesFile() string {
+	if output, err := run("config", "--global", "--path", "--get", "core.excludesFile"); err == nil {
+		if path := strings.TrimSpace(output); path != "" {
+			return path
+		}
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// GetConfig returns a value from the repository config, or "" when it is not set
+func GetConfig(bareDir, key string) (string, error) {
+	output, err := run("--git-dir="+bareDir, "config", "--get", key)
+	if err != nil {
+		// Exit code 1 means the key is not set
+		if runner.ExitCode(err) == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// SetConfig sets a value in the repository config
+func SetConfig(bareDir, key, value string) error {
+	_, err := run("--git-dir="+bareDir, "config", key, value)
+	return err
+}
+
+// ListRemotes returns the names of the configured remotes
+func ListRemotes(bareDir string) ([]string, error) {
+	output, err := run("--git-dir="+bareDir, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// AddRemote adds a remote that only fetches the given branch
+func AddRemote(bareDir, name, url, branch string) error {
+	_, err := run("--git-dir="+bareDir, "remote", "add", "-t", branch, name, url)
+	return err
+}
+
+// AddRemoteBranch adds a branch to the branches a remote fetches
+func AddRemoteBranch(bareDir, name, branch string) error {
+	output, _ := run("--git-dir="+bareDir, "config", "--get-all", "remote."+name+".fetch")
+	refSpec := "+refs/heads/" + branch + ":refs/remotes/" + name + "/" + branch
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == refSpec {
+			return nil
+		}
+	}
+
+	_, err := run("--git-dir="+bareDir, "remote", "set-branches", "--add", name, branch)
+	return err
+}
+
+// RemoveRemote removes a remote with its remote-tracking branches
+func RemoveRemote(bareDir, name string) error {
+	_, err := run("--git-dir="+bareDir, "remote", "remove", name)
+	return err
+}
+
+// FetchRefspec returns the standard refspec that maps a remote's branches to
+// refs/remotes/<remote>/*
+func FetchRefspec(remote string) string {
+	return "+refs/heads/*:refs/remotes/" + remote + "/*"
+}
+
+// GetFetchRefspecs returns the refspecs a remote fetches by default
+func GetFetchRefspecs(bareDir, remote string) ([]string, error) {
+	output, err := run("--git-dir="+bareDir, "config", "--get-all", "remote."+remote+".fetch")
+	if err != nil {
+		// Exit code 1 means none are configured
+		if runner.ExitCode(err) == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// AddFetchRefspec makes a remote fetch its branches into
+// refs/remotes/<remote>/*, which git clone --bare leaves unconfigured: all of
+// them, or only branch when it is not empty
+func AddFetchRefspec(bareDir, remote, branch string) error {
+	refSpec := FetchRefspec(remote)
+	if branch != "" {
+		refSpec = "+refs/heads/" + branch + ":refs/remotes/" + remote + "/" + branch
+	}
+	_, err := run("--git-dir="+bareDir, "config", "--add", "remote."+remote+".fetch", refSpec)
+	return err
+}
+
+// PartialCloneFilter returns the filter of a partial clone from a remote, or
+// "" for a full clone
+func PartialCloneFilter(bareDir, remote string) string {
+	filter, _ := GetConfig(bareDir, "remote."+remote+".partialclonefilter")
+	return filter
+}
+
+// Fetch fetches a remote, or all remotes, with their configured refspecs.
+// With prune, remote-tracking branches deleted on the remote are removed.
+func Fetch(bareDir, remote string, all, prune bool) error {
+	args := append([]string{"--git-dir=" + bareDir, "fetch", "--quiet"}, shallowArgs(bareDir)...)
+	if prune {
+		args = append(args, "--prune")
+	}
+	if all {
+		args = append(args, "--all")
+	} else {
+		args = append(args, remote)
+	}
+	_, err := run(args...)
+	return err
+}
+
+// RemoteBranches returns the remote-tracking branches (e.g., origin/main)
+// with the commit each points to
+func RemoteBranches(bareDir string) (map[string]string, error) {
+	output, err := run("--git-dir="+bareDir, "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		// Skip symbolic refs such as origin/HEAD
+		if len(fields) != 2 || strings.HasSuffix(fields[0], "/HEAD") || !strings.Contains(fields[0], "/") {
+			continue
+		}
+		branches[fields[0]] = fields[1]
+	}
+	return branches, nil
+}
+
+// FetchRemoteBranch updates the remote-tracking branch <remote>/<branch>
+func FetchRemoteBranch(bareDir, remote, branch string) error {
+	refSpec := "+refs/heads/" + branch + ":refs/remotes/" + remote + "/" + branch
+	args := append([]string{"--git-dir=" + bareDir, "fetch"}, shallowArgs(bareDir)...)
+	_, err := run(append(args, remote, refSpec)...)
+	return err
+}
+
+// SetUpstream makes a local branch track <remote>/<remoteBranch>
+func SetUpstream(bareDir, branch, remote, remoteBranch string) error {
+	_, err := run("--git-dir="+bareDir, "branch", "--set-upstream-to="+remote+"/"+remoteBranch, branch)
+	return err
+}
+
+// GetUpstream returns the <remote>/<branch> a local branch tracks, or "" when
+// it tracks nothing
+func GetUpstream(bareDir, branch string) (string, error) {
+	remote, err := GetConfig(bareDir, "branch."+branch+".remote")
+	if err != nil {
+		return "", err
+	}
+	merge, err := GetConfig(bareDir, "branch."+branch+".merge")
+	if err != nil {
+		return "", err
+	}
+	if remote == "" || merge == "" {
+		return "", nil
+	}
+	return remote + "/" + strings.TrimPrefix(merge, "refs/heads/"), nil
+}
+
+// BranchesTrackingRemote returns the local branches whose upstream is on a remote
+func BranchesTrackingRemote(bareDir, remote string) ([]string, error) {
+	output, err := run("--git-dir="+bareDir, "for-each-ref", "--format=%(refname:short) %(upstream:remotename)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == remote {
+			branches = append(branches, fields[0])
+		}
+	}
+	return branches, nil
+}
+
+// FetchCommit fetches a ref from a remote (name or URL) without updating any
+// local ref and returns the fetched commit
+func FetchCommit(bareDir, remote, ref string) (string, error) {
+	if _, err := run("--git-dir="+bareDir, "fetch", remote, ref); err != nil {
+		return "", err
+	}
+	return ResolveCommit(bareDir, "FETCH_HEAD")
+}
+
+// ResolveCommit returns the commit a revision points to
+func ResolveCommit(bareDir, rev string) (string, error) {
+	output, err := run("--git-dir="+bareDir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%w: cannot resolve '%s' to a commit", ErrInvalidRef, rev)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// IsAncestor reports whether commit ancestor is reachable from commit descendant
+func IsAncestor(bareDir, ancestor, descendant string) bool {
+	_, err := run("--git-dir="+bareDir, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// CountCommits returns the number of commits reachable from rev but not from
+// any of the excluded revisions
+func CountCommits(bareDir, rev string, exclude ...string) (int, error) {
+	args := []string{"--git-dir=" + bareDir, "rev-list", "--count", rev}
+	for _, ex := range exclude {
+		args = append(args, "^"+ex)
+	}
+	output, err := run(args...)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	if _, err := fmt.Sscanf(strings.TrimSpace(output), "%d", &count); err != nil {
+		return 0, fmt.Errorf("unexpected git rev-list output: %s", output)
+	}
+	return count, nil
+}
+
+// UpdateRef points a ref at a commit, creating it if needed
+func UpdateRef(bareDir, ref, commit string) error {
+	_, err := run("--git-dir="+bareDir, "update-ref", ref, commit)
+	return err
+}
+
+// StashCreate records the uncommitted changes of a worktree as a stash commit
+// without touching the worktree or the stash list. Returns "" when clean.
+func StashCreate(worktreePath string) (string, error) {
+	output, err := run("-C", worktreePath, "stash", "create", "wtm backup")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// FastForward fast-forwards the branch checked out in a worktree to a commit
+func FastForward(worktreePath, commit string) error {
+	_, err := run("-C", worktreePath, "merge", "--ff-only", "--quiet", commit)
+	return err
+}
+
+// ResetHard resets the branch checked out in a worktree, and its files, to a commit
+func ResetHard(worktreePath, commit string) error {
+	_, err := run("-C", worktreePath, "reset", "--hard", "--quiet", commit)
+	return err
+}
+
+// RangeDiff writes the range-diff between the commit series base..oldHead and
+// base..newHead to w
+func RangeDiff(bareDir string, w io.Writer, base, oldHead, newHead string) error {
+	_, err := runner.RunCommand(runner.Command{
+		Name:   "git",
+		Args:   []string{"--git-dir=" + bareDir, "range-diff", base, oldHead, newHead},
+		Stdout: w,
+	})
+	return classify(err)
+}
+
+// CommitEmpty creates a commit without changes on the branch checked out in a
+// worktree. Staged changes stay staged: --only without paths commits none of them.
+func CommitEmpty(worktreePath, message string) error {
+	if err := ensureGitUserConfigured(); err != nil {
+		return err
+	}
+	_, err := run("-C", worktreePath, "commit", "--allow-empty", "--only", "--quiet", "-m", message)
+	return err
+}
+
+// PushUpstream pushes a branch to a remote and sets it as the branch's upstream
+func PushUpstream(worktreePath, remote, branch string) error {
+	_, err := run("-C", worktreePath, "push", "--quiet", "--set-upstream", remote, branch)
+	return err
+}
+
+// CommitSubject returns the subject line of a commit
+func CommitSubject(bareDir, rev string) (string, error) {
+	output, err := run("--git-dir="+bareDir, "log", "-1", "--format=%s", rev)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// CheckBranchName reports whether a name is a valid branch name
+func CheckBranchName(name string) error {
+	if _, err := run("check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("%w: '%s' is not a valid branch name", ErrInvalidRef, name)
+	}
+	return nil
+}
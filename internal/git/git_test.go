@@ -1,11 +1,14 @@
 package git
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
 )
 
 // configureTestGitUser configures git user.name and user.email locally in the test bare repo
@@ -405,3 +408,111 @@ func TestLocalBranchExists(t *testing.T) {
 		}
 	})
 }
+
+func TestCommitEmpty(t *testing.T) {
+	bareDir := filepath.Join(t.TempDir(), ".bare")
+	if err := InitBare(bareDir); err != nil {
+		t.Fatalf("InitBare() error = %v", err)
+	}
+	configureTestGitUser(t, bareDir)
+	if err := CreateInitialBranch(bareDir, "main"); err != nil {
+		t.Fatalf("CreateInitialBranch() error = %v", err)
+	}
+	worktreePath := filepath.Join(filepath.Dir(bareDir), "main")
+	if err := AddWorktree(bareDir, "main", worktreePath, ""); err != nil {
+		t.Fatalf("AddWorktree() error = %v", err)
+	}
+
+	// Half-staged work must not end up in the empty commit
+	if err := os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if output, err := exec.Command("git", "-C", worktreePath, "add", "wip.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %s", output)
+	}
+
+	if err := CommitEmpty(worktreePath, "Start feature"); err != nil {
+		t.Fatalf("CommitEmpty() error = %v", err)
+	}
+
+	output, err := exec.Command("git", "-C", worktreePath, "show", "--name-only", "--format=%s", "HEAD").Output()
+	if err != nil || strings.TrimSpace(string(output)) != "Start feature" {
+		t.Errorf("HEAD = %q, %v, want an empty commit", output, err)
+	}
+	output, err = exec.Command("git", "-C", worktreePath, "diff", "--cached", "--name-only").Output()
+	if err != nil || strings.TrimSpace(string(output)) != "wip.txt" {
+		t.Errorf("Staged files = %q, %v, want wip.txt still staged", output, err)
+	}
+}
+
+func TestExitCodeHandling(t *testing.T) {
+	fake := &runner.Fake{Calls: []runner.Call{
+		{Args: []string{"git", "-C", "/wt", "diff-index", "--quiet", "HEAD", "--"}, ExitCode: 1},
+		{Args: []string{"git", "-C", "/wt", "diff-index", "--quiet", "HEAD", "--"}, ExitCode: 128, Stderr: "fatal: bad revision 'HEAD'\n"},
+		{Args: []string{"git", "--git-dir=/bare", "config", "--get", "wtm.missing"}, ExitCode: 1},
+		{Args: []string{"git", "--git-dir=/bare", "config", "--get", "wtm.set"}, Stdout: "value\n"},
+	}}
+	defer runner.Use(context.Background(), fake)()
+
+	if dirty, err := HasUncommittedChanges("/wt"); err != nil || !dirty {
+		t.Errorf("HasUncommittedChanges() = %v, %v, want true (exit code 1)", dirty, err)
+	}
+	_, err := HasUncommittedChanges("/wt")
+	if err == nil || err.Error() != "git diff-index failed: fatal: bad revision 'HEAD'" {
+		t.Errorf("HasUncommittedChanges() error = %v, want git's stderr", err)
+	}
+	if value, err := GetConfig("/bare", "wtm.missing"); err != nil || value != "" {
+		t.Errorf("GetConfig(unset) = %q, %v, want empty", value, err)
+	}
+	if value, err := GetConfig("/bare", "wtm.set"); err != nil || value != "value" {
+		t.Errorf("GetConfig(set) = %q, %v, want %q", value, err, "value")
+	}
+	if remaining := fake.Remaining(); len(remaining) != 0 {
+		t.Errorf("expected calls not made: %v", remaining)
+	}
+}
+
+func TestSubmoduleAndLFSCommands(t *testing.T) {
+	fake := &runner.Fake{Calls: []runner.Call{
+		{Args: []string{"git", "-C", "/wt", "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`},
+			Stdout: "submodule.lib.path vendor/lib\nsubmodule.docs/theme.path docs/theme\n"},
+		{Args: []string{"git", "-C", "/wt", "submodule", "update", "--init", "--recursive", "--depth", "1", "--reference", "/root/.worktree/modules", "--", "vendor/lib"}},
+		{Args: []string{"git", "-C", "/wt", "ls-files", "--", ":(attr:filter=lfs)"}, Stdout: "assets/logo.psd\n"},
+		{Args: []string{"git", "-C", "/wt", "lfs", "pull", "--include=assets/**,*.png", "--exclude=*.psd"}},
+	}}
+	defer runner.Use(context.Background(), fake)()
+
+	submodules, err := Submodules("/wt")
+	want := []Submodule{{Name: "lib", Path: "vendor/lib"}, {Name: "docs/theme", Path: "docs/theme"}}
+	if err != nil || len(submodules) != 2 || submodules[0] != want[0] || submodules[1] != want[1] {
+		t.Errorf("Submodules() = %v, %v, want %v", submodules, err, want)
+	}
+	opts := SubmoduleOptions{Recursive: true, Depth: 1, Paths: []string{"vendor/lib"}, Reference: "/root/.worktree/modules"}
+	if err := UpdateSubmodules("/wt", opts); err != nil {
+		t.Errorf("UpdateSubmodules() error = %v", err)
+	}
+	if !UsesLFS("/wt") {
+		t.Error("UsesLFS() = false, want true")
+	}
+	if err := PullLFS("/wt", []string{"assets/**", "*.png"}, []string{"*.psd"}); err != nil {
+		t.Errorf("PullLFS() error = %v", err)
+	}
+	if remaining := fake.Remaining(); len(remaining) != 0 {
+		t.Errorf("expected calls not made: %v", remaining)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	fake := &runner.Fake{Calls: []runner.Call{
+		{Args: []string{"git", "--version"}, Stdout: "git version 2.48.1 (Apple Git-155)\n"},
+		{Args: []string{"git", "--version"}, Stdout: "hub version 2.14.2\n"},
+	}}
+	defer runner.Use(context.Background(), fake)()
+
+	if major, minor, err := Version(); err != nil || major != 2 || minor != 48 {
+		t.Errorf("Version() = %d.%d, %v, want 2.48", major, minor, err)
+	}
+	if _, _, err := Version(); err == nil {
+		t.Error("Version() should fail on unrecognised output")
+	}
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/runner"
+)
+
+// Submodule is a submodule declared in a worktree's .gitmodules
+type Submodule struct {
+	Name string
+	Path string
+}
+
+// SubmoduleOptions configures UpdateSubmodules
+type SubmoduleOptions struct {
+	Recursive bool     // Also update nested submodules
+	Depth     int      // Clone with this many commits of history; 0 for all
+	Paths     []string // Only update these submodules; all when empty
+	Reference string   // Borrow objects from this repository (a shared module store)
+}
+
+// HasSubmodules reports whether a worktree declares submodules
+func HasSubmodules(worktreePath string) bool {
+	_, err := os.Stat(filepath.Join(worktreePath, ".gitmodules"))
+	return err == nil
+}
+
+// Submodules returns the submodules declared in a worktree's .gitmodules
+func Submodules(worktreePath string) ([]Submodule, error) {
+	output, err := run("-C", worktreePath, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	if err != nil {
+		// Exit code 1 means no submodule declares a path
+		if runner.ExitCode(err) == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var submodules []Submodule
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		key, path, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path")
+		submodules = append(submodules, Submodule{Name: name, Path: path})
+	}
+	return submodules, nil
+}
+
+// InitSubmodules records the URLs of a worktree's submodules (all when paths
+// is empty) in the repository config, resolving relative URLs
+func InitSubmodules(worktreePath string, paths []string) error {
+	_, err := run(append([]string{"-C", worktreePath, "submodule", "init", "--"}, paths...)...)
+	return err
+}
+
+// SubmoduleURL returns the URL of an initialized submodule
+func SubmoduleURL(worktreePath, name string) (string, error) {
+	output, err := run("-C", worktreePath, "config", "--get", "submodule."+name+".url")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// UpdateSubmodules initializes and checks out a worktree's submodules
+func UpdateSubmodules(worktreePath string, opts SubmoduleOptions) error {
+	args := []string{"-C", worktreePath, "submodule", "update", "--init"}
+	if opts.Recursive {
+		args = append(args, "--recursive")
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Reference != "" {
+		args = append(args, "--reference", opts.Reference)
+	}
+	args = append(args, "--")
+	args = append(args, opts.Paths...)
+
+	_, err := runNoSmudge(args...)
+	return err
+}
+
+// FetchModuleStore fetches a submodule's branches and tags into a shared
+// bare repository, under refs/modules/<name>/, creating it if needed.
+//
+// Submodules cloned with the store as reference borrow its objects without
+// keeping them reachable, so the store never loses one: deleted branches are
+// not pruned, and gc is turned off so objects of force-pushed branches stay.
+func FetchModuleStore(storeDir, name, url string) error {
+	if _, err := os.Stat(storeDir); os.IsNotExist(err) {
+		if _, err := run("init", "--bare", storeDir); err != nil {
+			return err
+		}
+	}
+	for _, setting := range [][2]string{{"gc.auto", "0"}, {"gc.pruneExpire", "never"}} {
+		if _, err := run("--git-dir="+storeDir, "config", setting[0], setting[1]); err != nil {
+			return err
+		}
+	}
+
+	prefix := "refs/modules/" + name
+	_, err := run("--git-dir="+storeDir, "fetch", "--no-tags", url,
+		"+refs/heads/*:"+prefix+"/heads/*", "+refs/tags/*:"+prefix+"/tags/*")
+	return err
+}
+
+// UsesLFS reports whether a worktree has files stored with Git LFS
+func UsesLFS(worktreePath string) bool {
+	output, err := run("-C", worktreePath, "ls-files", "--", ":(attr:filter=lfs)")
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+// PullLFS downloads a worktree's Git LFS files and checks them out. Include
+// and exclude are git lfs path patterns; all files are pulled when both are empty.
+func PullLFS(worktreePath string, include, exclude []string) error {
+	args := []string{"-C", worktreePath, "lfs", "pull"}
+	if len(include) > 0 {
+		args = append(args, "--include="+strings.Join(include, ","))
+	}
+	if len(exclude) > 0 {
+		args = append(args, "--exclude="+strings.Join(exclude, ","))
+	}
+	_, err := run(args...)
+	return err
+}
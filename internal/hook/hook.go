@@ -10,7 +10,9 @@ import (
 	"strings"
 	"text/template"
 
-	"github.com/hairyhenderson/gomplate/v4"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/datasource"
+	"github.com/vansdevcode/worktree-manager/internal/metadata"
 	"github.com/vansdevcode/worktree-manager/pkg/ui"
 )
 
@@ -19,6 +21,7 @@ type TemplateData struct {
 	Branch        string
 	Directory     string
 	RootDirectory string
+	SparseProfile string
 }
 
 // RunHook processes a hook script as a Go template and executes it.
@@ -41,34 +44,26 @@ func RunHook(hookPath, branchName, branchDirectory, rootDirectory string) error
 		return nil // Not executable, skip silently
 	}
 
-	// Read script content
-	content, err := os.ReadFile(hookPath)
-	if err != nil {
-		return fmt.Errorf("failed to read hook script: %w", err)
-	}
-
 	// Process template
 	templateData := TemplateData{
 		Branch:        branchName,
 		Directory:     branchDirectory,
 		RootDirectory: rootDirectory,
 	}
-
-	ctx := context.Background()
-	funcMap := gomplate.CreateFuncs(ctx)
-
-	tmpl, err := template.New(filepath.Base(hookPath)).Funcs(funcMap).Parse(string(content))
+	if meta, err := metadata.Load(rootDirectory, branchDirectory); err == nil {
+		templateData.SparseProfile = meta.Sparse
+	}
+	settings, err := config.LoadSettings(rootDirectory)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
 	}
 
-	var outputBuffer bytes.Buffer
-	if err := tmpl.Execute(&outputBuffer, templateData); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	processedContent, err := Render(hookPath, templateData, settings.Datasources, false)
+	if err != nil {
+		return err
 	}
 
 	// Extract shebang from processed content to determine interpreter
-	processedContent := outputBuffer.String()
 	interpreter, scriptContent := ExtractShebang(processedContent)
 
 	if interpreter == "" {
@@ -105,6 +100,33 @@ func RunHook(hookPath, branchName, branchDirectory, rootDirectory string) error
 	return nil
 }
 
+// Render reads a hook script and processes it as a Go template with gomplate functions
+// and the root's datasources, returning the resulting script.
+// With strict set, indexing a map with a missing key is an error (missingkey=error).
+func Render(hookPath string, templateData TemplateData, sources map[string]config.Datasource, strict bool) (string, error) {
+	content, err := os.ReadFile(hookPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read hook script: %w", err)
+	}
+
+	funcMap := datasource.TemplateFuncs(context.Background(), templateData.RootDirectory, sources)
+	tmpl := template.New(filepath.Base(hookPath)).Funcs(funcMap)
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	if _, err := tmpl.Parse(string(content)); err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var outputBuffer bytes.Buffer
+	if err := tmpl.Execute(&outputBuffer, templateData); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return outputBuffer.String(), nil
+}
+
 // RunHookByName finds a hook by name in .worktree/hooks/ and runs it.
 func RunHookByName(rootDirectory, hookName, branchName, branchDirectory string) error {
 	hookPath := filepath.Join(rootDirectory, ".worktree", "hooks", hookName)
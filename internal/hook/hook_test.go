@@ -188,3 +188,46 @@ func TestRunHookByName_NonExistent(t *testing.T) {
 		t.Errorf("Expected nil for non-existent hook, got: %v", err)
 	}
 }
+
+func TestRunHook_Datasource(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "hook-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	// Declare a datasource relative to the root
+	if err := os.MkdirAll(filepath.Join(tmpDir, ".worktree"), 0755); err != nil {
+		t.Fatalf("Failed to create .worktree dir: %v", err)
+	}
+	configContent := "datasources:\n  ports: .worktree/ports.yaml\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".worktree", "config.yaml"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".worktree", "ports.yaml"), []byte("web: 8080\n"), 0644); err != nil {
+		t.Fatalf("Failed to write datasource: %v", err)
+	}
+
+	branchDir := filepath.Join(tmpDir, "feature")
+	if err := os.MkdirAll(branchDir, 0755); err != nil {
+		t.Fatalf("Failed to create branch dir: %v", err)
+	}
+
+	hookPath := filepath.Join(tmpDir, "test-hook")
+	hookContent := "#!/bin/bash\necho -n \"{{ (ds \"ports\").web }}\" > port.txt\n"
+	if err := os.WriteFile(hookPath, []byte(hookContent), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	if err := RunHook(hookPath, "feature", branchDir, tmpDir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(branchDir, "port.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read hook output: %v", err)
+	}
+	if string(content) != "8080" {
+		t.Errorf("Hook output = %q, want %q", content, "8080")
+	}
+}
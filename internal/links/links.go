@@ -0,0 +1,181 @@
+package links
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+// Link is a symlink that wtm maintains inside every worktree
+type Link struct {
+	Path   string // Path relative to the worktree (e.g., "storage")
+	Target string // Absolute path the symlink points to
+}
+
+// Resolve builds the list of links for a root directory.
+// Every top-level entry of .worktree/links/ becomes a link to that entry, and every
+// entry in config.yaml becomes a link to its source or to .worktree/shared/<path>.
+// Config entries override links directory entries with the same path.
+func Resolve(rootDir string, specs []config.LinkSpec) ([]Link, error) {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory: %w", err)
+	}
+
+	byPath := make(map[string]Link)
+
+	linksDir := config.GetLinksDir(rootDir)
+	entries, err := os.ReadDir(linksDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read links directory: %w", err)
+	}
+	for _, entry := range entries {
+		byPath[entry.Name()] = Link{
+			Path:   entry.Name(),
+			Target: filepath.Join(linksDir, entry.Name()),
+		}
+	}
+
+	for _, spec := range specs {
+		path := filepath.Clean(spec.Path)
+		if spec.Path == "" || filepath.IsAbs(path) || path == "." || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("invalid link path %q: must be relative to the worktree", spec.Path)
+		}
+
+		target := filepath.Join(config.GetSharedDir(rootDir), path)
+		if spec.Source != "" {
+			target = spec.Source
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(rootDir, target)
+			}
+		}
+
+		byPath[path] = Link{Path: path, Target: target}
+	}
+
+	result := make([]Link, 0, len(byPath))
+	for _, link := range byPath {
+		result = append(result, link)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+
+	return result, nil
+}
+
+// Create creates the symlinks inside a worktree.
+// Targets under .worktree/shared/ are created as directories when missing.
+// Paths that already exist in the worktree are left untouched and reported as an error.
+func Create(rootDir, worktreeDir string, links []Link) error {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve root directory: %w", err)
+	}
+	worktreeDir, err = filepath.Abs(worktreeDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve worktree directory: %w", err)
+	}
+
+	var skipped []string
+	for _, link := range links {
+		linkPath := filepath.Join(worktreeDir, link.Path)
+
+		if _, err := os.Lstat(linkPath); err == nil {
+			skipped = append(skipped, link.Path)
+			continue
+		}
+
+		if _, err := os.Stat(link.Target); os.IsNotExist(err) {
+			if !isWithin(config.GetSharedDir(rootDir), link.Target) {
+				return fmt.Errorf("link target for %s does not exist: %s", link.Path, link.Target)
+			}
+			if err := os.MkdirAll(link.Target, 0755); err != nil {
+				return fmt.Errorf("failed to create shared directory %s: %w", link.Target, err)
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", link.Path, err)
+		}
+
+		if err := os.Symlink(symlinkTarget(rootDir, linkPath, link.Target), linkPath); err != nil {
+			return fmt.Errorf("failed to create link %s: %w", link.Path, err)
+		}
+	}
+
+	if len(skipped) > 0 {
+		return fmt.Errorf("paths already exist, not linked: %s", strings.Join(skipped, ", "))
+	}
+
+	return nil
+}
+
+// Remove deletes the symlinks wtm created inside a worktree.
+// Only symlinks pointing at the expected target are removed; the targets are never touched.
+// Returns the paths that were removed.
+func Remove(worktreeDir string, links []Link) ([]string, error) {
+	worktreeDir, err := filepath.Abs(worktreeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve worktree directory: %w", err)
+	}
+
+	var removed []string
+	for _, link := range links {
+		linkPath := filepath.Join(worktreeDir, link.Path)
+		if !IsManaged(linkPath, link) {
+			continue
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return removed, fmt.Errorf("failed to remove link %s: %w", link.Path, err)
+		}
+		removed = append(removed, link.Path)
+	}
+	return removed, nil
+}
+
+// IsManaged reports whether linkPath is a symlink to the link's target
+func IsManaged(linkPath string, link Link) bool {
+	info, err := os.Lstat(linkPath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	dest, err := os.Readlink(linkPath)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(linkPath), dest)
+	}
+	dest, err = filepath.Abs(dest)
+	if err != nil {
+		return false
+	}
+
+	return dest == filepath.Clean(link.Target)
+}
+
+// symlinkTarget returns a relative target for links that stay inside the root,
+// so moving the whole root keeps them valid, and an absolute target otherwise
+func symlinkTarget(rootDir, linkPath, target string) string {
+	if !isWithin(rootDir, target) {
+		return target
+	}
+	rel, err := filepath.Rel(filepath.Dir(linkPath), target)
+	if err != nil {
+		return target
+	}
+	return rel
+}
+
+// isWithin reports whether path is inside dir
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,157 @@
+package links
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+func TestResolve(t *testing.T) {
+	rootDir := t.TempDir()
+
+	// Create entries in the links directory
+	linksDir := config.GetLinksDir(rootDir)
+	if err := os.MkdirAll(filepath.Join(linksDir, "storage"), 0755); err != nil {
+		t.Fatalf("Failed to create links dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(linksDir, ".npmrc"), []byte("registry=x"), 0644); err != nil {
+		t.Fatalf("Failed to write .npmrc: %v", err)
+	}
+
+	specs := []config.LinkSpec{
+		{Path: ".idea/caches"},
+		{Path: "storage", Source: "fixtures/storage"},
+	}
+
+	got, err := Resolve(rootDir, specs)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	want := []Link{
+		{Path: ".idea/caches", Target: filepath.Join(config.GetSharedDir(rootDir), ".idea/caches")},
+		{Path: ".npmrc", Target: filepath.Join(linksDir, ".npmrc")},
+		{Path: "storage", Target: filepath.Join(rootDir, "fixtures/storage")},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Resolve() returned %d links, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Resolve()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResolve_InvalidPath(t *testing.T) {
+	tests := []string{"", "/etc/passwd", "../outside", "..", "."}
+
+	for _, path := range tests {
+		t.Run(path, func(t *testing.T) {
+			if _, err := Resolve(t.TempDir(), []config.LinkSpec{{Path: path}}); err == nil {
+				t.Errorf("Resolve() with path %q expected error, got nil", path)
+			}
+		})
+	}
+}
+
+func TestResolve_DotDotName(t *testing.T) {
+	rootDir := t.TempDir()
+
+	got, err := Resolve(rootDir, []config.LinkSpec{{Path: "..cache"}})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := Link{Path: "..cache", Target: filepath.Join(config.GetSharedDir(rootDir), "..cache")}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Resolve() = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestCreateAndRemove(t *testing.T) {
+	rootDir := t.TempDir()
+	worktreeDir := filepath.Join(rootDir, "feature")
+	if err := os.MkdirAll(worktreeDir, 0755); err != nil {
+		t.Fatalf("Failed to create worktree dir: %v", err)
+	}
+
+	linksDir := config.GetLinksDir(rootDir)
+	if err := os.MkdirAll(linksDir, 0755); err != nil {
+		t.Fatalf("Failed to create links dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(linksDir, ".npmrc"), []byte("registry=x"), 0644); err != nil {
+		t.Fatalf("Failed to write .npmrc: %v", err)
+	}
+
+	worktreeLinks, err := Resolve(rootDir, []config.LinkSpec{{Path: "storage/app"}})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	if err := Create(rootDir, worktreeDir, worktreeLinks); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Shared directory is created on demand and reachable through the link
+	if err := os.WriteFile(filepath.Join(worktreeDir, "storage", "app", "data.txt"), []byte("shared"), 0644); err != nil {
+		t.Fatalf("Failed to write through link: %v", err)
+	}
+	sharedFile := filepath.Join(config.GetSharedDir(rootDir), "storage", "app", "data.txt")
+	if _, err := os.Stat(sharedFile); err != nil {
+		t.Errorf("Expected shared file %s: %v", sharedFile, err)
+	}
+
+	// Links inside the root are relative
+	dest, err := os.Readlink(filepath.Join(worktreeDir, ".npmrc"))
+	if err != nil {
+		t.Fatalf("Readlink() error = %v", err)
+	}
+	if filepath.IsAbs(dest) {
+		t.Errorf("Expected relative link target, got %s", dest)
+	}
+
+	// Creating again reports existing paths
+	if err := Create(rootDir, worktreeDir, worktreeLinks); err == nil {
+		t.Errorf("Create() on existing links expected error, got nil")
+	}
+
+	removed, err := Remove(worktreeDir, worktreeLinks)
+	if err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Remove() removed %v, want 2 links", removed)
+	}
+
+	// Targets survive removal
+	if _, err := os.Stat(sharedFile); err != nil {
+		t.Errorf("Shared file was deleted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(linksDir, ".npmrc")); err != nil {
+		t.Errorf("Link source was deleted: %v", err)
+	}
+}
+
+func TestRemove_SkipsUnmanagedPaths(t *testing.T) {
+	rootDir := t.TempDir()
+	worktreeDir := filepath.Join(rootDir, "feature")
+	if err := os.MkdirAll(filepath.Join(worktreeDir, "storage"), 0755); err != nil {
+		t.Fatalf("Failed to create worktree dir: %v", err)
+	}
+
+	worktreeLinks := []Link{{Path: "storage", Target: filepath.Join(config.GetSharedDir(rootDir), "storage")}}
+
+	removed, err := Remove(worktreeDir, worktreeLinks)
+	if err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Remove() removed %v, want nothing", removed)
+	}
+	if _, err := os.Stat(filepath.Join(worktreeDir, "storage")); err != nil {
+		t.Errorf("Regular directory was removed: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/template"
+)
+
+// fileName is the manifest file inside a worktree's state directory
+const fileName = "files.json"
+
+// Entry records one file generated from .worktree/files
+type Entry struct {
+	Path       string    `json:"path"`       // Output path relative to the worktree
+	Source     string    `json:"source"`     // Source path relative to .worktree/files
+	Hash       string    `json:"hash"`       // Hash of the content as generated
+	RenderedAt time.Time `json:"renderedAt"` // When the file was generated
+}
+
+// Manifest lists the files generated into a worktree
+type Manifest struct {
+	Branch string  `json:"branch"` // Branch the files were rendered for
+	Files  []Entry `json:"files"`
+}
+
+// Status describes how a generated file differs from what was recorded
+type Status struct {
+	Path     string
+	Source   string
+	Modified bool  // The file was edited in the worktree
+	Deleted  bool  // The file no longer exists in the worktree
+	Outdated bool  // The source now renders differently
+	Orphaned bool  // The source was removed from .worktree/files
+	New      bool  // The source has not been generated into the worktree
+	Err      error // Rendering the source failed
+}
+
+// Clean reports whether the file is unchanged on both sides
+func (s Status) Clean() bool {
+	return !s.Modified && !s.Deleted && !s.Outdated && !s.Orphaned && !s.New && s.Err == nil
+}
+
+// Path returns the manifest path of a worktree
+func Path(rootDir, worktreeDir string) string {
+	return filepath.Join(config.GetWorktreeStateDir(rootDir, filepath.Base(worktreeDir)), fileName)
+}
+
+// New creates a manifest for freshly generated files
+func New(branch string, files []template.GeneratedFile, renderedAt time.Time) *Manifest {
+	m := &Manifest{Branch: branch}
+	for _, file := range files {
+		m.Files = append(m.Files, Entry{
+			Path:       file.Path,
+			Source:     file.Source,
+			Hash:       Hash(file.Content),
+			RenderedAt: renderedAt,
+		})
+	}
+	return m
+}
+
+// Load reads the manifest of a worktree. A missing manifest yields an empty one.
+func Load(rootDir, worktreeDir string) (*Manifest, error) {
+	m := &Manifest{}
+
+	content, err := os.ReadFile(Path(rootDir, worktreeDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", Path(rootDir, worktreeDir), err)
+	}
+	return m, nil
+}
+
+// Save writes the manifest of a worktree. An empty manifest removes the file.
+func Save(rootDir, worktreeDir string, m *Manifest) error {
+	path := Path(rootDir, worktreeDir)
+
+	if len(m.Files) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove manifest: %w", err)
+		}
+		return nil
+	}
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Hash returns the content hash stored in the manifest
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// hashFile hashes a file in the worktree. A missing file returns an empty hash.
+func hashFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return Hash(content), nil
+}
+
+// Unmodified returns the paths of generated files whose content still
+// matches the manifest
+func (m *Manifest) Unmodified(worktreeDir string) map[string]bool {
+	unmodified := make(map[string]bool)
+	for _, entry := range m.Files {
+		if hash, err := hashFile(filepath.Join(worktreeDir, filepath.FromSlash(entry.Path))); err == nil && hash == entry.Hash {
+			unmodified[entry.Path] = true
+		}
+	}
+	return unmodified
+}
+
+// Modified returns the paths of generated files edited since they were generated
+func (m *Manifest) Modified(worktreeDir string) []string {
+	var modified []string
+	for _, entry := range m.Files {
+		if hash, err := hashFile(filepath.Join(worktreeDir, filepath.FromSlash(entry.Path))); err == nil && hash != "" && hash != entry.Hash {
+			modified = append(modified, entry.Path)
+		}
+	}
+	return modified
+}
+
+// Status compares the generated files with the worktree and with what their
+// sources render to now. Sources that were never generated are reported as new.
+func (m *Manifest) Status(worktreeDir, filesDir string, data template.TemplateData, sources map[string]config.Datasource) ([]Status, error) {
+	var statuses []Status
+	known := make(map[string]bool)
+
+	for _, entry := range m.Files {
+		known[entry.Source] = true
+		status := Status{Path: entry.Path, Source: entry.Source}
+
+		hash, err := hashFile(filepath.Join(worktreeDir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Path, err)
+		}
+		status.Deleted = hash == ""
+		status.Modified = hash != "" && hash != entry.Hash
+
+		sourcePath := filepath.Join(filesDir, filepath.FromSlash(entry.Source))
+		if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+			status.Orphaned = true
+		} else if content, err := template.RenderFile(sourcePath, data, sources); err != nil {
+			status.Err = err
+		} else {
+			status.Outdated = Hash(content) != entry.Hash
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	// Sources added since the worktree was created
+	err := filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == filesDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(filesDir, path)
+		if err != nil {
+			return err
+		}
+		source := filepath.ToSlash(relPath)
+		if !known[source] {
+			statuses = append(statuses, Status{Path: template.OutputPath(source), Source: source, New: true})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files directory: %w", err)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses, nil
+}
+
+// Clean removes the generated files from the worktree. Files edited since they
+// were generated are kept unless force is set. It returns the removed paths and
+// the entries that were kept; directories left empty are removed too.
+func Clean(worktreeDir string, m *Manifest, force bool) ([]string, []Entry, error) {
+	var removed []string
+	var kept []Entry
+
+	unmodified := m.Unmodified(worktreeDir)
+	for _, entry := range m.Files {
+		path := filepath.Join(worktreeDir, filepath.FromSlash(entry.Path))
+
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			continue
+		}
+		if !force && !unmodified[entry.Path] {
+			kept = append(kept, entry)
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			return removed, append(kept, entry), fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		removed = append(removed, entry.Path)
+		removeEmptyParents(worktreeDir, filepath.Dir(path))
+	}
+
+	return removed, kept, nil
+}
+
+// removeEmptyParents removes dir and its parents up to (not including) the
+// worktree while they are empty
+func removeEmptyParents(worktreeDir, dir string) {
+	for dir != worktreeDir && len(dir) > len(worktreeDir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,171 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vansdevcode/worktree-manager/internal/template"
+)
+
+// writeFiles creates files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+// generate renders the files directory into a new worktree and returns its manifest
+func generate(t *testing.T, rootDir string, files map[string]string) (string, string, *Manifest) {
+	t.Helper()
+
+	filesDir := filepath.Join(rootDir, ".worktree", "files")
+	worktreeDir := filepath.Join(rootDir, "feature-x")
+	writeFiles(t, filesDir, files)
+	if err := os.MkdirAll(worktreeDir, 0755); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	data := template.TemplateData{Branch: "feature/x", Directory: worktreeDir, RootDirectory: rootDir}
+	generated, err := template.Generate(filesDir, worktreeDir, data, nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return filesDir, worktreeDir, New(data.Branch, generated, time.Now())
+}
+
+func TestSaveLoad(t *testing.T) {
+	rootDir := t.TempDir()
+	_, worktreeDir, m := generate(t, rootDir, map[string]string{
+		".env.tmpl":       "BRANCH={{ .Branch }}\n",
+		"config/app.yaml": "debug: true\n",
+	})
+
+	if err := Save(rootDir, worktreeDir, m); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, ".worktree", "state", "feature-x", "files.json")); err != nil {
+		t.Fatalf("Manifest not written to the state directory: %v", err)
+	}
+
+	loaded, err := Load(rootDir, worktreeDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Branch != "feature/x" || len(loaded.Files) != 2 {
+		t.Fatalf("Load() = %+v, want 2 files for feature/x", loaded)
+	}
+	if loaded.Files[0].Path != ".env" || loaded.Files[0].Source != ".env.tmpl" {
+		t.Errorf("Files[0] = %+v, want .env from .env.tmpl", loaded.Files[0])
+	}
+	if loaded.Files[0].Hash != Hash([]byte("BRANCH=feature/x\n")) {
+		t.Errorf("Files[0].Hash = %s, want hash of rendered content", loaded.Files[0].Hash)
+	}
+
+	// Saving an empty manifest removes it
+	if err := Save(rootDir, worktreeDir, &Manifest{}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(Path(rootDir, worktreeDir)); !os.IsNotExist(err) {
+		t.Errorf("Empty manifest was not removed")
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	m, err := Load(t.TempDir(), "feature-x")
+	if err != nil || len(m.Files) != 0 {
+		t.Errorf("Load() = %+v, %v; want empty manifest", m, err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	rootDir := t.TempDir()
+	filesDir, worktreeDir, m := generate(t, rootDir, map[string]string{
+		"clean.tmpl":    "{{ .Branch }}",
+		"edited.tmpl":   "{{ .Branch }}",
+		"outdated.tmpl": "{{ .Branch }}",
+		"deleted.txt":   "static",
+		"orphaned.txt":  "static",
+	})
+
+	writeFiles(t, worktreeDir, map[string]string{"edited": "local change"})
+	writeFiles(t, filesDir, map[string]string{"outdated.tmpl": "v2 {{ .Branch }}", "added.tmpl": "new"})
+	if err := os.Remove(filepath.Join(worktreeDir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(filesDir, "orphaned.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	data := template.TemplateData{Branch: "feature/x", Directory: worktreeDir, RootDirectory: rootDir}
+	statuses, err := m.Status(worktreeDir, filesDir, data, nil)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	want := map[string]Status{
+		"added":        {New: true},
+		"clean":        {},
+		"deleted.txt":  {Deleted: true},
+		"edited":       {Modified: true},
+		"orphaned.txt": {Orphaned: true},
+		"outdated":     {Outdated: true},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("Status() returned %d entries, want %d: %+v", len(statuses), len(want), statuses)
+	}
+	for _, got := range statuses {
+		expected, ok := want[got.Path]
+		if !ok {
+			t.Errorf("unexpected status for %s", got.Path)
+			continue
+		}
+		if got.New != expected.New || got.Deleted != expected.Deleted || got.Modified != expected.Modified ||
+			got.Orphaned != expected.Orphaned || got.Outdated != expected.Outdated || got.Err != nil {
+			t.Errorf("Status(%s) = %+v, want %+v", got.Path, got, expected)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	rootDir := t.TempDir()
+	_, worktreeDir, m := generate(t, rootDir, map[string]string{
+		"config/nested/app.yaml": "debug: true\n",
+		".env.tmpl":              "BRANCH={{ .Branch }}\n",
+	})
+	writeFiles(t, worktreeDir, map[string]string{".env": "BRANCH=edited\n"})
+
+	removed, kept, err := Clean(worktreeDir, m, false)
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "config/nested/app.yaml" {
+		t.Errorf("Clean() removed = %v, want [config/nested/app.yaml]", removed)
+	}
+	if len(kept) != 1 || kept[0].Path != ".env" {
+		t.Errorf("Clean() kept = %+v, want .env", kept)
+	}
+	if _, err := os.Stat(filepath.Join(worktreeDir, "config")); !os.IsNotExist(err) {
+		t.Errorf("Empty directories left behind")
+	}
+	if _, err := os.Stat(worktreeDir); err != nil {
+		t.Errorf("Worktree directory was removed: %v", err)
+	}
+
+	// With force, edited files are removed as well
+	removed, kept, err = Clean(worktreeDir, m, true)
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if len(removed) != 1 || len(kept) != 0 {
+		t.Errorf("Clean(force) removed = %v, kept = %+v", removed, kept)
+	}
+}
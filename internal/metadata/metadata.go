@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+// fileName is the metadata file inside a worktree's state directory
+const fileName = "metadata.json"
+
+// Metadata records what a worktree was created for
+type Metadata struct {
+	Base   string  `json:"base,omitempty"`   // Branch the worktree's branch was created from
+	Change *Change `json:"change,omitempty"` // Pull or merge request checked out in, or opened from, the worktree
+	Issue  *Issue  `json:"issue,omitempty"`  // Issue the worktree was created for
+	Sparse string  `json:"sparse,omitempty"` // Sparse-checkout profile the worktree is restricted to
+}
+
+// Issue identifies the issue a worktree works on
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Change identifies a pull request (kind "pr") or merge request (kind "mr")
+type Change struct {
+	Kind     string `json:"kind"`
+	Number   int    `json:"number"`
+	BaseRef  string `json:"baseRef,omitempty"`  // Branch the change targets
+	HeadRef  string `json:"headRef,omitempty"`  // Branch name in the head repository
+	HeadRepo string `json:"headRepo,omitempty"` // Head repository, for changes from forks
+	Head     string `json:"head,omitempty"`     // Head commit when last fetched
+	Reviewed string `json:"reviewed,omitempty"` // Head commit last marked as reviewed
+	// Published is set for changes opened from the worktree with wtm publish,
+	// rather than checked out with pr/<n> or mr/<n>
+	Published bool `json:"published,omitempty"`
+}
+
+// Path returns the metadata path of a worktree
+func Path(rootDir, worktreeDir string) string {
+	return filepath.Join(config.GetWorktreeStateDir(rootDir, filepath.Base(worktreeDir)), fileName)
+}
+
+// Load reads the metadata of a worktree. Missing metadata yields an empty value.
+func Load(rootDir, worktreeDir string) (*Metadata, error) {
+	m := &Metadata{}
+
+	content, err := os.ReadFile(Path(rootDir, worktreeDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read worktree metadata: %w", err)
+	}
+
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("failed to parse worktree metadata %s: %w", Path(rootDir, worktreeDir), err)
+	}
+	return m, nil
+}
+
+// Save writes the metadata of a worktree
+func Save(rootDir, worktreeDir string, m *Metadata) error {
+	path := Path(rootDir, worktreeDir)
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode worktree metadata: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write worktree metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	rootDir := t.TempDir()
+	worktreeDir := filepath.Join(rootDir, "pr-7")
+
+	// Missing metadata is empty
+	m, err := Load(rootDir, worktreeDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Change != nil {
+		t.Errorf("Load() of missing metadata = %+v, want empty", m)
+	}
+
+	want := &Metadata{Change: &Change{Kind: "pr", Number: 7, BaseRef: "main", HeadRef: "fix", Head: "abc123"}}
+	if err := Save(rootDir, worktreeDir, want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, ".worktree", "state", "pr-7", "metadata.json")); err != nil {
+		t.Errorf("Metadata file not written: %v", err)
+	}
+
+	got, err := Load(rootDir, worktreeDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want %+v", got.Change, want.Change)
+	}
+}
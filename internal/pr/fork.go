@@ -0,0 +1,148 @@
+package pr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/forge"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// forkMarker is the remote config key marking remotes added for forks, so
+// they can be offered for removal once no branch uses them
+const forkMarker = "wtm-fork"
+
+// TrackFork makes the local branch of a cross-repository change track its
+// head branch in the fork, so fixes can be pushed back to the contributor.
+// The fork is added as a remote named after its owner, or an existing remote
+// pointing at it is reused. Returns the remote name, or "" when the change
+// is not from a fork.
+func TrackFork(bareDir string, change *PullRequest) (string, error) {
+	// Only changes fetched with their metadata know their head branch
+	if change.HeadRepo == "" || change.HeadRef == "" || change.Branch == "" {
+		return "", nil
+	}
+
+	originURL, err := forge.RemoteURL(bareDir, "origin")
+	if err != nil {
+		return "", err
+	}
+	host, path, err := forge.ParseRemoteURL(originURL)
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(change.HeadRepo, path) {
+		return "", nil
+	}
+
+	remote, err := findRemote(bareDir, host, change.HeadRepo)
+	if err != nil {
+		return "", err
+	}
+
+	if remote == "" {
+		forkURL, err := forge.SiblingURL(originURL, change.HeadRepo)
+		if err != nil {
+			return "", err
+		}
+		if remote, err = forkRemoteName(bareDir, change.HeadRepo); err != nil {
+			return "", err
+		}
+		if err := git.AddRemote(bareDir, remote, forkURL, change.HeadRef); err != nil {
+			return "", err
+		}
+		if err := git.SetConfig(bareDir, "remote."+remote+"."+forkMarker, "true"); err != nil {
+			return "", err
+		}
+	} else if IsForkRemote(bareDir, remote) {
+		if err := git.AddRemoteBranch(bareDir, remote, change.HeadRef); err != nil {
+			return "", err
+		}
+	}
+
+	if err := git.FetchRemoteBranch(bareDir, remote, change.HeadRef); err != nil {
+		return "", fmt.Errorf("failed to fetch %s from fork %s: %w", change.HeadRef, change.HeadRepo, err)
+	}
+	if err := git.SetUpstream(bareDir, change.Branch, remote, change.HeadRef); err != nil {
+		return "", err
+	}
+	return remote, nil
+}
+
+// headBranch returns the local branch to fetch a change into: its head branch.
+// A change from a fork must not move or re-track a local branch that merely
+// has the same name (e.g., main or patch-1), so when such a branch exists and
+// does not track the fork already, branchName (e.g., pr-7) is used instead.
+func headBranch(bareDir string, repo forge.Repo, change *PullRequest, branchName string) (string, error) {
+	if change.HeadRepo == "" || strings.EqualFold(change.HeadRepo, repo.Path) {
+		return change.HeadRef, nil
+	}
+
+	remote, err := findRemote(bareDir, repo.Host, change.HeadRepo)
+	if err != nil {
+		return "", err
+	}
+	for _, branch := range []string{change.HeadRef, branchName} {
+		exists, err := git.LocalBranchExists(bareDir, branch)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return branch, nil
+		}
+		// Left by an earlier checkout of the change
+		if upstream, _ := git.GetUpstream(bareDir, branch); remote != "" && upstream == remote+"/"+change.HeadRef {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("local branches '%s' and '%s' already exist and do not track %s", change.HeadRef, branchName, change.HeadRepo)
+}
+
+// IsForkRemote reports whether a remote was added by TrackFork
+func IsForkRemote(bareDir, remote string) bool {
+	value, err := git.GetConfig(bareDir, "remote."+remote+"."+forkMarker)
+	return err == nil && value == "true"
+}
+
+// findRemote returns the remote pointing at host/path, or ""
+func findRemote(bareDir, host, path string) (string, error) {
+	remotes, err := git.ListRemotes(bareDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, remote := range remotes {
+		remoteURL, err := forge.RemoteURL(bareDir, remote)
+		if err != nil {
+			continue
+		}
+		remoteHost, remotePath, err := forge.ParseRemoteURL(remoteURL)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(remoteHost, host) && strings.EqualFold(remotePath, path) {
+			return remote, nil
+		}
+	}
+	return "", nil
+}
+
+// forkRemoteName picks a free remote name for a fork: its owner, or fork-<owner>
+func forkRemoteName(bareDir, headRepo string) (string, error) {
+	remotes, err := git.ListRemotes(bareDir)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool)
+	for _, remote := range remotes {
+		taken[remote] = true
+	}
+
+	owner, _, _ := strings.Cut(headRepo, "/")
+	for _, name := range []string{owner, "fork-" + owner} {
+		if !taken[name] {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("remotes '%s' and 'fork-%s' already exist for other repositories", owner, owner)
+}
@@ -0,0 +1,106 @@
+package pr
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// TestTrackFork tests that a fork PR branch tracks the fork through a dedicated remote
+func TestTrackFork(t *testing.T) {
+	bareDir := setupRemote(t, "git@github.com:acme/widgets.git", "main", "refs/pull/7/head")
+
+	// The fork has the two PR branches
+	forkDir := filepath.Join(t.TempDir(), "fork.git")
+	if err := git.InitBare(forkDir); err != nil {
+		t.Fatalf("Failed to init fork: %v", err)
+	}
+	if err := git.CreateInitialBranch(forkDir, "feature/widgets"); err != nil {
+		t.Fatalf("Failed to create initial branch: %v", err)
+	}
+	runGit(t, "--git-dir="+forkDir, "branch", "feature/more", "feature/widgets")
+	runGit(t, "--git-dir="+bareDir, "config", "url."+forkDir+".insteadOf", "git@github.com:octocat/widgets.git")
+
+	// Local branches as FetchPR leaves them
+	for _, branch := range []string{"feature/widgets", "feature/more"} {
+		runGit(t, "--git-dir="+bareDir, "fetch", forkDir, branch+":"+branch)
+	}
+
+	change := &PullRequest{Number: 7, Branch: "feature/widgets", HeadRef: "feature/widgets", HeadRepo: "octocat/widgets"}
+	remote, err := TrackFork(bareDir, change)
+	if err != nil {
+		t.Fatalf("TrackFork() error = %v", err)
+	}
+	if remote != "octocat" {
+		t.Errorf("TrackFork() remote = %q, want octocat", remote)
+	}
+	if url, _ := git.GetConfig(bareDir, "remote.octocat.url"); url != "git@github.com:octocat/widgets.git" {
+		t.Errorf("Fork remote URL = %q, want the SSH form of origin", url)
+	}
+	if !IsForkRemote(bareDir, "octocat") {
+		t.Errorf("Fork remote is not marked")
+	}
+	if upstream := gitOutput(t, "--git-dir="+bareDir, "rev-parse", "--abbrev-ref", "feature/widgets@{upstream}"); upstream != "octocat/feature/widgets" {
+		t.Errorf("Upstream = %q, want octocat/feature/widgets", upstream)
+	}
+
+	// A second PR from the same fork reuses the remote
+	change = &PullRequest{Number: 8, Branch: "feature/more", HeadRef: "feature/more", HeadRepo: "OctoCat/Widgets"}
+	if remote, err := TrackFork(bareDir, change); err != nil || remote != "octocat" {
+		t.Fatalf("TrackFork() for second PR = (%q, %v), want octocat", remote, err)
+	}
+	if fetch := gitOutput(t, "--git-dir="+bareDir, "config", "--get-all", "remote.octocat.fetch"); strings.Count(fetch, "\n") != 1 {
+		t.Errorf("Fork remote fetches %q, want both PR branches", fetch)
+	}
+
+	// A PR whose head branch name was taken locally was fetched into pr-<n>
+	runGit(t, "--git-dir="+forkDir, "branch", "main", "feature/widgets")
+	runGit(t, "--git-dir="+bareDir, "fetch", forkDir, "main:pr-9")
+	change = &PullRequest{Number: 9, Branch: "pr-9", HeadRef: "main", HeadRepo: "octocat/widgets"}
+	if remote, err := TrackFork(bareDir, change); err != nil || remote != "octocat" {
+		t.Fatalf("TrackFork() for renamed branch = (%q, %v), want octocat", remote, err)
+	}
+	if upstream, _ := git.GetUpstream(bareDir, "pr-9"); upstream != "octocat/main" {
+		t.Errorf("Upstream of renamed branch = %q, want octocat/main", upstream)
+	}
+
+	tracking, err := git.BranchesTrackingRemote(bareDir, "octocat")
+	if err != nil || len(tracking) != 3 {
+		t.Errorf("BranchesTrackingRemote() = %v, %v, want all PR branches", tracking, err)
+	}
+}
+
+func TestTrackFork_SameRepository(t *testing.T) {
+	bareDir := setupRemote(t, "https://github.com/acme/widgets.git", "main", "refs/pull/7/head")
+
+	tests := []struct {
+		name   string
+		change *PullRequest
+	}{
+		{name: "same repository", change: &PullRequest{Branch: "fix", HeadRef: "fix", HeadRepo: "acme/widgets"}},
+		{name: "deleted fork", change: &PullRequest{Branch: "fix", HeadRef: "fix"}},
+		{name: "fetched by refspec", change: &PullRequest{Number: 7, Branch: "pr-7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, err := TrackFork(bareDir, tt.change)
+			if err != nil || remote != "" {
+				t.Errorf("TrackFork() = (%q, %v), want no fork", remote, err)
+			}
+		})
+	}
+}
+
+// gitOutput runs a git command and returns its trimmed output
+func gitOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s", strings.Join(args, " "), output)
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -1,156 +1,103 @@
 package pr
 
 import (
-	"encoding/json"
 	"fmt"
-	"os/exec"
-	"strings"
 
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
 	"github.com/vansdevcode/worktree-manager/internal/git"
 )
 
-// FetchPR fetches a pull request using the three-tier fallback strategy
-// Returns the branch name that was created
-func FetchPR(bareDir string, prNumber int, branchName string) (string, error) {
-	// Tier 1: Try gh CLI first (provides best metadata)
-	branch, err := fetchPRWithGH(bareDir, prNumber, branchName)
-	if err == nil {
-		return branch, nil
-	}
-
-	// Tier 2: Try GitHub API (requires no dependencies but needs network)
-	// For now, skip API implementation as it requires token management
-	// TODO: Implement GitHub API fallback
+// PullRequest holds the metadata of a pull or merge request.
+// Only Number and Branch are known when it was fetched by refspec alone.
+type PullRequest = forge.Change
 
-	// Tier 3: Use pull/$ID/head refspec (always works)
-	return fetchPRWithRefspec(bareDir, prNumber, branchName)
+// FetchPR fetches a pull request of the origin remote. The forge is detected
+// from the remote host (see forge.ForRemote); for undeclared hosts only the
+// GitHub pull request ref is fetched, without metadata.
+// Returns the pull request with the branch name that was created.
+func FetchPR(bareDir string, prNumber int, branchName string, forges map[string]config.ForgeSpec) (*PullRequest, error) {
+	return fetchChange(bareDir, prNumber, branchName, forges, forge.GitHub)
 }
 
-// fetchPRWithGH uses gh CLI to fetch PR information and check it out
-func fetchPRWithGH(bareDir string, prNumber int, branchName string) (string, error) {
-	// Check if gh CLI is available
-	if _, err := exec.LookPath("gh"); err != nil {
-		return "", fmt.Errorf("gh CLI not found")
-	}
-
-	// Get the repository slug from the remote URL
-	repoSlug, err := getRepoSlug(bareDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to determine repository: %w", err)
-	}
-
-	// Get PR info using gh CLI with explicit --repo flag
-	cmd := exec.Command("gh", "pr", "view", fmt.Sprintf("%d", prNumber), "--repo", repoSlug, "--json", "headRefName")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("gh pr view failed: %w; output: %s", err, strings.TrimSpace(string(output)))
-	}
-
-	var prInfo struct {
-		HeadRefName string `json:"headRefName"`
-	}
-	if err := json.Unmarshal(output, &prInfo); err != nil {
-		return "", fmt.Errorf("failed to parse PR info: %w", err)
-	}
-
-	// Use the actual PR branch name from GitHub metadata
-	actualBranch := prInfo.HeadRefName
-	if actualBranch == "" {
-		return "", fmt.Errorf("PR metadata missing headRefName")
-	}
-
-	// Fetch the PR using the actual branch name
-	refSpec := fmt.Sprintf("pull/%d/head:%s", prNumber, actualBranch)
-	if err := git.FetchRef(bareDir, refSpec); err != nil {
-		return "", err
-	}
-
-	return actualBranch, nil
+// FetchMR fetches a GitLab merge request of the origin remote by its IID.
+// Self-hosted GitLab hosts must be declared in forges for its metadata;
+// otherwise only the merge request ref is fetched.
+func FetchMR(bareDir string, mrNumber int, branchName string, forges map[string]config.ForgeSpec) (*PullRequest, error) {
+	return fetchChange(bareDir, mrNumber, branchName, forges, forge.GitLab)
 }
 
-// getRepoSlug extracts the repository slug (owner/repo) from the remote URL
-func getRepoSlug(bareDir string) (string, error) {
-	cmd := exec.Command("git", "--git-dir="+bareDir, "remote", "get-url", "origin")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get remote URL: %w", err)
+// fetchChange fetches a change using a two-tier fallback strategy:
+//  1. Forge metadata (CLI or REST API), fetching the head into a branch named
+//     after the change's head branch, or into branchName (see headBranch)
+//  2. The forge's change ref (e.g., pull/$ID/head) into branchName, which
+//     works without network access to the API. Undeclared hosts only get
+//     this tier, with the change refs of fallback.
+func fetchChange(bareDir string, number int, branchName string, forges map[string]config.ForgeSpec, fallback string) (*PullRequest, error) {
+	f, repo, err := forge.ForRemote(bareDir, "origin", forges)
+	if err == nil {
+		change, err := f.GetChange(repo, number)
+		if err == nil && change.HeadRef != "" {
+			branch, err := headBranch(bareDir, repo, change, branchName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch change #%d: %w", number, err)
+			}
+			if err := f.FetchChange(bareDir, "origin", change, branch); err == nil {
+				change.Branch = branch
+				return change, nil
+			}
+		}
 	}
 
-	remoteURL := strings.TrimSpace(string(output))
-
-	// Parse different URL formats:
-	// - https://github.com/owner/repo.git
-	// - git@github.com:owner/repo.git
-	// - https://github.com/owner/repo
-
-	// Remove .git suffix
-	remoteURL = strings.TrimSuffix(remoteURL, ".git")
-
-	// Handle SSH format: git@github.com:owner/repo
-	if strings.HasPrefix(remoteURL, "git@github.com:") {
-		return strings.TrimPrefix(remoteURL, "git@github.com:"), nil
+	kind := fallback
+	if f != nil {
+		kind = f.Kind()
 	}
-
-	// Handle HTTPS format: https://github.com/owner/repo
-	if strings.HasPrefix(remoteURL, "https://github.com/") {
-		return strings.TrimPrefix(remoteURL, "https://github.com/"), nil
+	ref, ok := changeRefs[kind]
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch change #%d: %s has no change refs and its metadata is unavailable", number, kind)
 	}
 
-	// Handle HTTP format: http://github.com/owner/repo
-	if strings.HasPrefix(remoteURL, "http://github.com/") {
-		return strings.TrimPrefix(remoteURL, "http://github.com/"), nil
+	refSpec := fmt.Sprintf("+"+ref+":refs/heads/%s", number, branchName)
+	if err := git.FetchRef(bareDir, refSpec); err != nil {
+		return nil, fmt.Errorf("failed to fetch change #%d: %w", number, err)
 	}
-
-	return "", fmt.Errorf("unsupported remote URL format: %s", remoteURL)
+	return &PullRequest{Number: number, Branch: branchName}, nil
 }
 
-// fetchPRWithRefspec uses the pull/$ID/head refspec to fetch the PR
-func fetchPRWithRefspec(bareDir string, prNumber int, branchName string) (string, error) {
-	// Fetch using pull/$ID/head refspec
-	refSpec := fmt.Sprintf("pull/%d/head:%s", prNumber, branchName)
-
-	cmd := exec.Command("git", "--git-dir="+bareDir, "fetch", "origin", refSpec)
-	err := cmd.Run()
-	if err != nil {
-		// Try alternative format
-		refSpec = fmt.Sprintf("+refs/pull/%d/head:refs/heads/%s", prNumber, branchName)
-		cmd = exec.Command("git", "--git-dir="+bareDir, "fetch", "origin", refSpec)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return "", fmt.Errorf("failed to fetch PR: %s", string(output))
-		}
+// FetchHead fetches the current head of a pull request ("pr") or merge
+// request ("mr") of the origin remote without updating any local branch, and
+// returns its commit
+func FetchHead(bareDir, kind string, number int, forges map[string]config.ForgeSpec) (string, error) {
+	fallback := forge.GitHub
+	if kind == "mr" {
+		fallback = forge.GitLab
 	}
 
-	return branchName, nil
-}
-
-// GetPRInfo attempts to get PR information (best effort)
-// bareDir is required to determine the repository context
-func GetPRInfo(bareDir string, prNumber int) (title string, description string, err error) {
-	// Check if gh CLI is available
-	if _, err := exec.LookPath("gh"); err != nil {
-		return "", "", fmt.Errorf("gh CLI not available")
+	f, repo, err := forge.ForRemote(bareDir, "origin", forges)
+	forgeKind := fallback
+	if err == nil {
+		forgeKind = f.Kind()
 	}
-
-	// Get the repository slug from the remote URL
-	repoSlug, err := getRepoSlug(bareDir)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to determine repository: %w", err)
+	if ref, ok := changeRefs[forgeKind]; ok {
+		return git.FetchCommit(bareDir, "origin", fmt.Sprintf(ref, number))
 	}
 
-	cmd := exec.Command("gh", "pr", "view", fmt.Sprintf("%d", prNumber), "--repo", repoSlug, "--json", "title,body")
-	output, err := cmd.Output()
+	// Forges without change refs: fetch the head branch, from the fork if needed
+	change, err := f.GetChange(repo, number)
 	if err != nil {
-		return "", "", err
-	}
-
-	var prInfo struct {
-		Title string `json:"title"`
-		Body  string `json:"body"`
+		return "", err
 	}
-	if err := json.Unmarshal(output, &prInfo); err != nil {
-		return "", "", err
+	source := "origin"
+	if change.HeadCloneURL != "" {
+		source = change.HeadCloneURL
 	}
+	return git.FetchCommit(bareDir, source, "refs/heads/"+change.HeadRef)
+}
 
-	return strings.TrimSpace(prInfo.Title), strings.TrimSpace(prInfo.Body), nil
+// changeRefs are the refs under which forges publish change heads
+var changeRefs = map[string]string{
+	forge.GitHub: "refs/pull/%d/head",
+	forge.Gitea:  "refs/pull/%d/head",
+	forge.GitLab: "refs/merge-requests/%d/head",
 }
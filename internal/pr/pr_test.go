@@ -0,0 +1,208 @@
+package pr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/forge"
+	"github.com/vansdevcode/worktree-manager/internal/git"
+)
+
+// pullRequestJSON is a trimmed GitHub API response for a fork PR
+const pullRequestJSON = `{
+  "number": 7,
+  "title": "Add widgets",
+  "state": "open",
+  "user": {"login": "octocat"},
+  "head": {"ref": "feature/widgets", "repo": {"full_name": "octocat/widgets", "clone_url": "https://github.com/octocat/widgets.git"}},
+  "base": {"ref": "main"}
+}`
+
+// mergeRequestJSON is a trimmed GitLab API response
+const mergeRequestJSON = `{
+  "iid": 45,
+  "title": "Speed up widgets",
+  "state": "opened",
+  "source_branch": "perf/widgets",
+  "target_branch": "develop",
+  "author": {"username": "tanuki"}
+}`
+
+// newAPIServer serves a single JSON document at path
+func newAPIServer(t *testing.T, path, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != path {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// setupRemote creates a bare repository whose origin looks like remoteURL but
+// is redirected to a local repository with ref pointing at its initial branch
+func setupRemote(t *testing.T, remoteURL, branch, ref string) string {
+	t.Helper()
+
+	originDir := filepath.Join(t.TempDir(), "origin.git")
+	if err := git.InitBare(originDir); err != nil {
+		t.Fatalf("Failed to init origin: %v", err)
+	}
+	if err := git.CreateInitialBranch(originDir, branch); err != nil {
+		t.Fatalf("Failed to create initial branch: %v", err)
+	}
+	runGit(t, "--git-dir="+originDir, "update-ref", ref, "refs/heads/"+branch)
+
+	bareDir := filepath.Join(t.TempDir(), ".bare")
+	if err := git.InitBare(bareDir); err != nil {
+		t.Fatalf("Failed to init bare repo: %v", err)
+	}
+	runGit(t, "--git-dir="+bareDir, "remote", "add", "origin", remoteURL)
+	runGit(t, "--git-dir="+bareDir, "config", "url."+originDir+".insteadOf", remoteURL)
+	return bareDir
+}
+
+// TestFetchPR_API tests that without gh the API tier resolves the real head branch
+func TestFetchPR_API(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed, it would be used instead of the API")
+	}
+
+	bareDir := setupRemote(t, "https://github.com/acme/widgets.git", "main", "refs/pull/7/head")
+	t.Setenv(forge.APIURLEnv(forge.GitHub), newAPIServer(t, "/repos/acme/widgets/pulls/7", pullRequestJSON).URL)
+
+	pr, err := FetchPR(bareDir, 7, "pr-7", nil)
+	if err != nil {
+		t.Fatalf("FetchPR() error = %v", err)
+	}
+	if pr.Branch != "feature/widgets" || pr.Title != "Add widgets" || pr.Author != "octocat" {
+		t.Errorf("FetchPR() = %+v, want branch feature/widgets with metadata", pr)
+	}
+
+	exists, err := git.LocalBranchExists(bareDir, "feature/widgets")
+	if err != nil || !exists {
+		t.Errorf("Branch feature/widgets was not created: %v", err)
+	}
+}
+
+// TestFetchPR_ExistingBranch tests that a fork PR never takes over a local branch of the same name
+func TestFetchPR_ExistingBranch(t *testing.T) {
+	if _, err := exec.LookPath("gh"); err == nil {
+		t.Skip("gh CLI is installed, it would be used instead of the API")
+	}
+
+	bareDir := setupRemote(t, "https://github.com/acme/widgets.git", "main", "refs/pull/7/head")
+	t.Setenv(forge.APIURLEnv(forge.GitHub), newAPIServer(t, "/repos/acme/widgets/pulls/7", pullRequestJSON).URL)
+
+	// The user's own branch happens to have the PR's head branch name
+	runGit(t, "--git-dir="+bareDir, "fetch", "origin", "main:feature/widgets")
+	runGit(t, "--git-dir="+bareDir, "config", "branch.feature/widgets.remote", "origin")
+	runGit(t, "--git-dir="+bareDir, "config", "branch.feature/widgets.merge", "refs/heads/main")
+
+	pr, err := FetchPR(bareDir, 7, "pr-7", nil)
+	if err != nil {
+		t.Fatalf("FetchPR() error = %v", err)
+	}
+	if pr.Branch != "pr-7" {
+		t.Errorf("FetchPR() branch = %q, want pr-7", pr.Branch)
+	}
+	if upstream, _ := git.GetUpstream(bareDir, "feature/widgets"); upstream != "origin/main" {
+		t.Errorf("Upstream of the existing branch = %q, want origin/main", upstream)
+	}
+
+	// Once it tracks the fork, a later checkout reuses the branch
+	runGit(t, "--git-dir="+bareDir, "remote", "add", "octocat", "https://github.com/octocat/widgets.git")
+	runGit(t, "--git-dir="+bareDir, "config", "branch.pr-7.remote", "octocat")
+	runGit(t, "--git-dir="+bareDir, "config", "branch.pr-7.merge", "refs/heads/feature/widgets")
+
+	if pr, err = FetchPR(bareDir, 7, "pr-7", nil); err != nil {
+		t.Fatalf("FetchPR() again error = %v", err)
+	}
+	if pr.Branch != "pr-7" {
+		t.Errorf("FetchPR() again branch = %q, want pr-7", pr.Branch)
+	}
+
+	// Without a free name, the existing branches are left alone
+	runGit(t, "--git-dir="+bareDir, "config", "branch.pr-7.remote", "origin")
+	if _, err := FetchPR(bareDir, 7, "pr-7", nil); err == nil {
+		t.Errorf("FetchPR() with both names taken expected error, got nil")
+	}
+}
+
+// TestFetchPR_ForgeOverride tests that a configured forge type picks the API
+func TestFetchPR_ForgeOverride(t *testing.T) {
+	bareDir := setupRemote(t, "https://git.example.com/acme/widgets.git", "main", "refs/pull/7/head")
+	t.Setenv(forge.APIURLEnv(forge.Gitea), newAPIServer(t, "/repos/acme/widgets/pulls/7", pullRequestJSON).URL)
+
+	forges := map[string]config.ForgeSpec{"git.example.com": {Type: "forgejo"}}
+	pr, err := FetchPR(bareDir, 7, "pr-7", forges)
+	if err != nil {
+		t.Fatalf("FetchPR() error = %v", err)
+	}
+	if pr.Branch != "feature/widgets" {
+		t.Errorf("FetchPR() branch = %q, want feature/widgets", pr.Branch)
+	}
+}
+
+func TestFetchMR_API(t *testing.T) {
+	if _, err := exec.LookPath("glab"); err == nil {
+		t.Skip("glab CLI is installed, it would be used instead of the API")
+	}
+
+	bareDir := setupRemote(t, "git@git.example.com:group/sub/app.git", "develop", "refs/merge-requests/45/head")
+	t.Setenv(forge.APIURLEnv(forge.GitLab), newAPIServer(t, "/projects/group%2Fsub%2Fapp/merge_requests/45", mergeRequestJSON).URL)
+
+	forges := map[string]config.ForgeSpec{"git.example.com": {Type: "gitlab"}}
+	mr, err := FetchMR(bareDir, 45, "mr-45", forges)
+	if err != nil {
+		t.Fatalf("FetchMR() error = %v", err)
+	}
+	if mr.Branch != "perf/widgets" || mr.BaseRef != "develop" || mr.Author != "tanuki" {
+		t.Errorf("FetchMR() = %+v, want branch perf/widgets with metadata", mr)
+	}
+	if exists, _ := git.LocalBranchExists(bareDir, "perf/widgets"); !exists {
+		t.Errorf("Branch perf/widgets was not created")
+	}
+}
+
+func TestFetchMR_Refspec(t *testing.T) {
+	if _, err := exec.LookPath("glab"); err == nil {
+		t.Skip("glab CLI is installed, it would be used instead of the API")
+	}
+
+	bareDir := setupRemote(t, "git@git.example.com:group/sub/app.git", "develop", "refs/merge-requests/45/head")
+
+	// An unreachable API leaves the refspec tier
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	t.Setenv(forge.APIURLEnv(forge.GitLab), server.URL)
+
+	mr, err := FetchMR(bareDir, 45, "mr-45", nil)
+	if err != nil {
+		t.Fatalf("FetchMR() error = %v", err)
+	}
+	if mr.Branch != "mr-45" {
+		t.Errorf("FetchMR() branch = %q, want mr-45", mr.Branch)
+	}
+	if exists, _ := git.LocalBranchExists(bareDir, "mr-45"); !exists {
+		t.Errorf("Branch mr-45 was not created")
+	}
+}
+
+// runGit runs a git command and fails the test on error
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %s", strings.Join(args, " "), output)
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Call is a command a Fake expects, with the result it produces
+type Call struct {
+	Args     []string // Program and arguments (e.g., {"git", "fetch", "origin"})
+	Stdout   string
+	Stderr   string
+	ExitCode int // Non-zero makes the call fail with an *Error
+}
+
+// Fake is a scripted Runner for tests. Each command must match the next
+// expected call, in order; any other command fails.
+type Fake struct {
+	Calls []Call            // Expected calls
+	Paths map[string]string // Programs LookPath finds, by name
+	next  int
+}
+
+// Run checks a command against the next expected call and returns its result
+func (f *Fake) Run(ctx context.Context, c Command) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &Error{Command: c, ExitCode: -1, Err: err}
+	}
+
+	got := append([]string{c.Name}, c.Args...)
+	if f.next >= len(f.Calls) {
+		return nil, &Error{Command: c, ExitCode: -1, Err: fmt.Errorf("unexpected command: %s", c)}
+	}
+	call := f.Calls[f.next]
+	if !slices.Equal(got, call.Args) {
+		return nil, &Error{Command: c, ExitCode: -1, Err: fmt.Errorf("unexpected command: %s, want %s", c, strings.Join(call.Args, " "))}
+	}
+	f.next++
+
+	stdout := []byte(call.Stdout)
+	if c.Stdout != nil {
+		_, _ = c.Stdout.Write(stdout)
+		stdout = nil
+	}
+	if call.ExitCode != 0 {
+		return stdout, &Error{Command: c, ExitCode: call.ExitCode, Stderr: call.Stderr, Err: fmt.Errorf("exit status %d", call.ExitCode)}
+	}
+	return stdout, nil
+}
+
+// LookPath finds the programs listed in Paths
+func (f *Fake) LookPath(name string) (string, error) {
+	if path, ok := f.Paths[name]; ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("%s: executable file not found in $PATH", name)
+}
+
+// Remaining returns the expected calls that were not made
+func (f *Fake) Remaining() []Call {
+	return f.Calls[f.next:]
+}
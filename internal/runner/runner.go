@@ -0,0 +1,197 @@
+// Package runner runs the external programs wtm relies on (git, gh, glab).
+// All invocations go through a Runner so they share a context, can be traced,
+// capture stderr the same way, and can be scripted in tests.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command is an invocation of an external program
+type Command struct {
+	Name   string    // Program to run (e.g., "git")
+	Args   []string  // Arguments, without the program
+	Dir    string    // Working directory; the current directory if empty
+	Env    []string  // Variables added to the environment (KEY=value)
+	Stdout io.Writer // Receives stdout instead of it being returned
+}
+
+// String formats the command for traces and errors, quoting arguments with spaces
+func (c Command) String() string {
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// groupCommands are subcommands named together with their own subcommand,
+// e.g., "git worktree add"
+var groupCommands = map[string]bool{"worktree": true, "remote": true, "stash": true, "submodule": true, "lfs": true, "sparse-checkout": true, "pr": true, "mr": true, "issue": true}
+
+// subcommand returns the program and its first argument that is not an
+// option, e.g., "git fetch" for git --git-dir=x -C dir fetch origin
+func (c Command) subcommand() string {
+	for i := 0; i < len(c.Args); i++ {
+		arg := c.Args[i]
+		switch {
+		case arg == "-C" || arg == "-c":
+			i++ // Skip the option's value
+		case !strings.HasPrefix(arg, "-"):
+			if groupCommands[arg] && i+1 < len(c.Args) && !strings.HasPrefix(c.Args[i+1], "-") {
+				return c.Name + " " + arg + " " + c.Args[i+1]
+			}
+			return c.Name + " " + arg
+		}
+	}
+	return c.Name
+}
+
+// Runner runs commands
+type Runner interface {
+	// Run runs a command and returns its stdout. A command that cannot be
+	// started or exits unsuccessfully returns an *Error.
+	Run(ctx context.Context, cmd Command) ([]byte, error)
+	// LookPath reports where a program is installed, like exec.LookPath
+	LookPath(name string) (string, error)
+}
+
+// Error describes a command that could not be started or exited unsuccessfully
+type Error struct {
+	Command  Command
+	ExitCode int    // -1 when the command did not run to completion
+	Stderr   string // Captured stderr
+	Err      error  // Underlying error (e.g., *exec.ExitError or context.Canceled)
+}
+
+// Error reports the command and its stderr, e.g., "git fetch failed: fatal: ..."
+func (e *Error) Error() string {
+	detail := strings.TrimSpace(e.Stderr)
+	// Progress messages before the actual error are noise
+	lines := strings.Split(detail, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "fatal: ") || strings.HasPrefix(line, "error: ") {
+			detail = strings.Join(lines[i:], "\n")
+			break
+		}
+	}
+	if detail == "" && e.Err != nil {
+		detail = e.Err.Error()
+	}
+	return fmt.Sprintf("%s failed: %s", e.Command.subcommand(), detail)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit code of a command that exited unsuccessfully, or
+// -1 when err does not come from one
+func ExitCode(err error) int {
+	var runErr *Error
+	if errors.As(err, &runErr) {
+		return runErr.ExitCode
+	}
+	return -1
+}
+
+// Exec runs commands with os/exec
+type Exec struct {
+	Trace io.Writer // When set, each command is logged with its duration
+}
+
+// Run runs a command, capturing stdout (unless redirected) and stderr
+func (r *Exec) Run(ctx context.Context, c Command) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdout = &stdout
+	if c.Stdout != nil {
+		cmd.Stdout = c.Stdout
+	}
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	r.trace(c, time.Since(start), err)
+
+	if err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			exitCode = exitErr.ExitCode()
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return stdout.Bytes(), &Error{Command: c, ExitCode: exitCode, Stderr: stderr.String(), Err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// LookPath looks a program up in PATH
+func (r *Exec) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// trace logs a finished command
+func (r *Exec) trace(c Command, elapsed time.Duration, err error) {
+	if r.Trace == nil {
+		return
+	}
+	status := ""
+	if err != nil {
+		status = " (" + err.Error() + ")"
+	}
+	location := ""
+	if c.Dir != "" {
+		location = " in " + c.Dir
+	}
+	fmt.Fprintf(r.Trace, "+ %s%s [%s]%s\n", c, location, elapsed.Round(time.Millisecond), status)
+}
+
+// TraceEnabled reports whether WTM_TRACE asks for command tracing
+func TraceEnabled() bool {
+	value := os.Getenv("WTM_TRACE")
+	return value != "" && value != "0" && value != "false"
+}
+
+var (
+	current    Runner = &Exec{}
+	currentCtx        = context.Background()
+)
+
+// Use makes the package functions run commands with r under ctx, and returns
+// a function restoring the previous runner and context
+func Use(ctx context.Context, r Runner) (restore func()) {
+	previous, previousCtx := current, currentCtx
+	current, currentCtx = r, ctx
+	return func() { current, currentCtx = previous, previousCtx }
+}
+
+// Run runs a program with the current runner and returns its stdout
+func Run(name string, args ...string) ([]byte, error) {
+	return RunCommand(Command{Name: name, Args: args})
+}
+
+// RunCommand runs a command with the current runner and returns its stdout
+func RunCommand(cmd Command) ([]byte, error) {
+	return current.Run(currentCtx, cmd)
+}
+
+// LookPath looks a program up with the current runner
+func LookPath(name string) (string, error) {
+	return current.LookPath(name)
+}
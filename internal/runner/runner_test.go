@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExec_CapturesStderrAndExitCode(t *testing.T) {
+	r := &Exec{}
+	_, err := r.Run(context.Background(), Command{Name: "git", Args: []string{"-C", t.TempDir(), "rev-parse", "--verify", "nope"}})
+	if err == nil {
+		t.Fatal("expected an error outside a repository")
+	}
+
+	var runErr *Error
+	if !errors.As(err, &runErr) {
+		t.Fatalf("error %T is not an *Error", err)
+	}
+	if runErr.ExitCode != 128 {
+		t.Errorf("ExitCode = %d, want 128", runErr.ExitCode)
+	}
+	if ExitCode(err) != 128 {
+		t.Errorf("ExitCode(err) = %d, want 128", ExitCode(err))
+	}
+	if !strings.HasPrefix(err.Error(), "git rev-parse failed: fatal:") {
+		t.Errorf("Error() = %q, want git's stderr after 'git rev-parse failed:'", err.Error())
+	}
+}
+
+func TestExec_Trace(t *testing.T) {
+	var trace bytes.Buffer
+	r := &Exec{Trace: &trace}
+	output, err := r.Run(context.Background(), Command{Name: "git", Args: []string{"--version"}})
+	if err != nil {
+		t.Fatalf("git --version failed: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "git version") {
+		t.Errorf("stdout = %q, want git version", output)
+	}
+	if !strings.HasPrefix(trace.String(), "+ git --version [") {
+		t.Errorf("trace = %q, want '+ git --version [<duration>]'", trace.String())
+	}
+}
+
+func TestExec_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := (&Exec{}).Run(ctx, Command{Name: "git", Args: []string{"--version"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if ExitCode(err) != -1 {
+		t.Errorf("ExitCode(err) = %d, want -1", ExitCode(err))
+	}
+}
+
+func TestCommand_String(t *testing.T) {
+	c := Command{Name: "git", Args: []string{"commit", "-m", "two words", ""}}
+	if got, want := c.String(), `git commit -m "two words" ""`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+
+	c = Command{Name: "git", Args: []string{"--git-dir=/bare", "-C", "/wt", "fetch", "origin"}}
+	if got := c.subcommand(); got != "git fetch" {
+		t.Errorf("subcommand() = %q, want %q", got, "git fetch")
+	}
+}
+
+func TestFake(t *testing.T) {
+	fake := &Fake{
+		Calls: []Call{
+			{Args: []string{"git", "rev-parse", "HEAD"}, Stdout: "abc\n"},
+			{Args: []string{"git", "fetch", "origin"}, ExitCode: 128, Stderr: "fatal: no remote\n"},
+			{Args: []string{"git", "status"}},
+		},
+		Paths: map[string]string{"gh": "/usr/bin/gh"},
+	}
+	defer Use(context.Background(), fake)()
+
+	output, err := Run("git", "rev-parse", "HEAD")
+	if err != nil || string(output) != "abc\n" {
+		t.Errorf("Run() = %q, %v, want %q", output, err, "abc\n")
+	}
+
+	_, err = Run("git", "fetch", "origin")
+	if err == nil || err.Error() != "git fetch failed: fatal: no remote" || ExitCode(err) != 128 {
+		t.Errorf("Run() error = %v (exit code %d), want the scripted failure", err, ExitCode(err))
+	}
+
+	if _, err := Run("git", "log"); err == nil || !strings.Contains(err.Error(), "unexpected command: git log, want git status") {
+		t.Errorf("Run() error = %v, want an unexpected command error", err)
+	}
+	if remaining := fake.Remaining(); len(remaining) != 1 {
+		t.Errorf("Remaining() = %v, want the git status call", remaining)
+	}
+
+	if path, err := LookPath("gh"); err != nil || path != "/usr/bin/gh" {
+		t.Errorf("LookPath(gh) = %q, %v", path, err)
+	}
+	if _, err := LookPath("glab"); err == nil {
+		t.Error("LookPath(glab) should fail")
+	}
+}
+
+func TestError_Message(t *testing.T) {
+	err := &Error{
+		Command:  Command{Name: "git", Args: []string{"--git-dir=/bare", "worktree", "add", "-b", "x", "/wt", "nope"}},
+		ExitCode: 128,
+		Stderr:   "Preparing worktree (new branch 'x')\nfatal: invalid reference: nope\n",
+	}
+	if got, want := err.Error(), "git worktree add failed: fatal: invalid reference: nope"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
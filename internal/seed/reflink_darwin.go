@@ -0,0 +1,23 @@
+//go:build darwin
+
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones src into dst with clonefile(2) (APFS)
+func reflink(src, dst string, perm fs.FileMode) error {
+	if err := unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW); err != nil {
+		switch {
+		case errors.Is(err, unix.ENOTSUP), errors.Is(err, unix.EXDEV):
+			return fmt.Errorf("%w: %v", errors.ErrUnsupported, err)
+		}
+		return err
+	}
+	return nil
+}
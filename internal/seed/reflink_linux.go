@@ -0,0 +1,37 @@
+//go:build linux
+
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones src into dst with the FICLONE ioctl (btrfs, XFS, bcachefs, ...)
+func reflink(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	if err := unix.IoctlFileClone(int(out.Fd()), int(in.Fd())); err != nil {
+		switch {
+		case errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.EXDEV),
+			errors.Is(err, unix.EINVAL), errors.Is(err, unix.ENOTTY), errors.Is(err, unix.ENOSYS):
+			return fmt.Errorf("%w: %v", errors.ErrUnsupported, err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package seed
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// reflink is not available on this platform
+func reflink(src, dst string, perm fs.FileMode) error {
+	return errors.ErrUnsupported
+}
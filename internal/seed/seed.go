@@ -0,0 +1,234 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+// Method is the strategy used to copy files from the source worktree
+type Method string
+
+const (
+	MethodAuto     Method = "auto"     // Reflink where supported, plain copy otherwise
+	MethodReflink  Method = "reflink"  // Copy-on-write clone only (FICLONE / clonefile)
+	MethodHardlink Method = "hardlink" // Hardlink files; edits are visible in both worktrees
+	MethodCopy     Method = "copy"     // Plain byte copy
+)
+
+// ParseMethod validates a method name, defaulting to MethodAuto
+func ParseMethod(name string) (Method, error) {
+	switch Method(name) {
+	case "", MethodAuto:
+		return MethodAuto, nil
+	case MethodReflink, MethodHardlink, MethodCopy:
+		return Method(name), nil
+	}
+	return "", fmt.Errorf("unknown seed method %q (use auto, reflink, hardlink or copy)", name)
+}
+
+// Result describes what happened to a single seed path
+type Result struct {
+	Path    string // Path inside the worktree
+	Method  Method // Method actually used (empty when skipped)
+	Files   int    // Number of files seeded
+	Skipped string // Reason the path was not seeded
+}
+
+// Seed copies the configured paths from srcDir into dstDir.
+// Paths missing from the source, already present in the destination or whose
+// lockfiles differ between the two worktrees are skipped and reported in the result.
+// Paths and lockfiles must stay inside the worktrees.
+func Seed(srcDir, dstDir string, paths []config.SeedPath, method Method) ([]Result, error) {
+	var results []Result
+	for _, p := range paths {
+		result := Result{Path: p.Path}
+
+		if err := checkPath(p.Path); err != nil {
+			return results, err
+		}
+		if p.Lockfile != "" {
+			if err := checkPath(p.Lockfile); err != nil {
+				return results, err
+			}
+		}
+
+		src := filepath.Join(srcDir, p.Path)
+		dst := filepath.Join(dstDir, p.Path)
+
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			result.Skipped = "not present in source worktree"
+			results = append(results, result)
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			result.Skipped = "already exists"
+			results = append(results, result)
+			continue
+		}
+
+		if p.Lockfile != "" {
+			match, err := LockfilesMatch(srcDir, dstDir, p.Lockfile)
+			if err != nil {
+				return results, err
+			}
+			if !match {
+				result.Skipped = fmt.Sprintf("%s differs", p.Lockfile)
+				results = append(results, result)
+				continue
+			}
+		}
+
+		used, files, err := CopyTree(src, dst, method)
+		if err != nil {
+			return results, fmt.Errorf("failed to seed %s: %w", p.Path, err)
+		}
+		result.Method = used
+		result.Files = files
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// checkPath rejects paths that are empty, absolute or escape the worktree
+func checkPath(path string) error {
+	clean := filepath.Clean(path)
+	if path == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid seed path %q: must be relative to the worktree", path)
+	}
+	return nil
+}
+
+// LockfilesMatch reports whether a lockfile exists in both worktrees with identical content
+func LockfilesMatch(srcDir, dstDir, lockfile string) (bool, error) {
+	srcHash, err := hashFile(filepath.Join(srcDir, lockfile))
+	if err != nil || srcHash == nil {
+		return false, err
+	}
+	dstHash, err := hashFile(filepath.Join(dstDir, lockfile))
+	if err != nil || dstHash == nil {
+		return false, err
+	}
+	return bytes.Equal(srcHash, dstHash), nil
+}
+
+// hashFile returns the SHA-256 of a file, or nil if it does not exist
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return h.Sum(nil), nil
+}
+
+// CopyTree copies src to dst, preserving directories, permissions and symlinks.
+// With MethodAuto the first file decides whether reflinks are supported; the
+// remaining files use the same method. Returns the method used and the file count.
+func CopyTree(src, dst string, method Method) (Method, int, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return method, 0, err
+	}
+
+	files := 0
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		target := filepath.Join(dst, relPath)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			// Skip sockets, pipes and devices
+			return nil
+		}
+
+		method, err = copyFile(path, target, info.Mode().Perm(), method)
+		if err != nil {
+			return err
+		}
+		files++
+		return nil
+	})
+	if method == MethodAuto {
+		// Nothing was copied, so no method was chosen
+		method = MethodCopy
+	}
+	return method, files, err
+}
+
+// copyFile copies a single file and returns the method to use for the next one
+func copyFile(src, dst string, perm fs.FileMode, method Method) (Method, error) {
+	switch method {
+	case MethodHardlink:
+		return method, os.Link(src, dst)
+	case MethodCopy:
+		return method, plainCopy(src, dst, perm)
+	}
+
+	err := reflink(src, dst, perm)
+	if err == nil {
+		return MethodReflink, nil
+	}
+	_ = os.Remove(dst)
+	if method == MethodReflink || !errors.Is(err, errors.ErrUnsupported) {
+		return method, fmt.Errorf("reflink %s: %w", src, err)
+	}
+
+	// Filesystem can't clone, fall back to plain copies from now on
+	return MethodCopy, plainCopy(src, dst, perm)
+}
+
+// plainCopy copies file contents byte by byte
+func plainCopy(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
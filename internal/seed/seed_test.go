@@ -0,0 +1,185 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+// writeFiles creates files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Method
+		wantErr bool
+	}{
+		{input: "", want: MethodAuto},
+		{input: "auto", want: MethodAuto},
+		{input: "reflink", want: MethodReflink},
+		{input: "hardlink", want: MethodHardlink},
+		{input: "copy", want: MethodCopy},
+		{input: "rsync", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMethod(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMethod(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMethod(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	for _, method := range []Method{MethodAuto, MethodHardlink, MethodCopy} {
+		t.Run(string(method), func(t *testing.T) {
+			srcDir := t.TempDir()
+			dstDir := t.TempDir()
+
+			writeFiles(t, srcDir, map[string]string{
+				"package-lock.json":              "lock-v1",
+				"composer.lock":                  "composer-v1",
+				"node_modules/left-pad/index.js": "module.exports = 1",
+				"vendor/autoload.php":            "<?php",
+				".cache/build.bin":               "cache",
+			})
+			writeFiles(t, dstDir, map[string]string{
+				"package-lock.json": "lock-v1",
+				"composer.lock":     "composer-v2",
+			})
+			if err := os.Symlink("left-pad/index.js", filepath.Join(srcDir, "node_modules", "link.js")); err != nil {
+				t.Fatalf("Failed to create symlink: %v", err)
+			}
+
+			paths := []config.SeedPath{
+				{Path: "node_modules", Lockfile: "package-lock.json"},
+				{Path: "vendor", Lockfile: "composer.lock"},
+				{Path: ".cache"},
+				{Path: "missing"},
+			}
+
+			results, err := Seed(srcDir, dstDir, paths, method)
+			if err != nil {
+				t.Fatalf("Seed() error = %v", err)
+			}
+			if len(results) != 4 {
+				t.Fatalf("Seed() returned %d results, want 4", len(results))
+			}
+
+			if results[0].Skipped != "" || results[0].Files != 1 {
+				t.Errorf("node_modules result = %+v, want 1 seeded file", results[0])
+			}
+			if results[1].Skipped == "" {
+				t.Errorf("vendor result = %+v, want skipped for differing lockfile", results[1])
+			}
+			if results[2].Skipped != "" || results[2].Files != 1 {
+				t.Errorf(".cache result = %+v, want 1 seeded file", results[2])
+			}
+			if results[3].Skipped == "" {
+				t.Errorf("missing result = %+v, want skipped", results[3])
+			}
+
+			content, err := os.ReadFile(filepath.Join(dstDir, "node_modules/left-pad/index.js"))
+			if err != nil || string(content) != "module.exports = 1" {
+				t.Errorf("Seeded file content = %q, err = %v", content, err)
+			}
+			if dest, err := os.Readlink(filepath.Join(dstDir, "node_modules", "link.js")); err != nil || dest != "left-pad/index.js" {
+				t.Errorf("Seeded symlink = %q, err = %v", dest, err)
+			}
+			if _, err := os.Stat(filepath.Join(dstDir, "vendor")); !os.IsNotExist(err) {
+				t.Errorf("vendor should not have been seeded")
+			}
+		})
+	}
+}
+
+func TestSeed_ExistingDestinationIsSkipped(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	writeFiles(t, srcDir, map[string]string{"vendor/a.php": "source"})
+	writeFiles(t, dstDir, map[string]string{"vendor/a.php": "destination"})
+
+	results, err := Seed(srcDir, dstDir, []config.SeedPath{{Path: "vendor"}}, MethodCopy)
+	if err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	if results[0].Skipped == "" {
+		t.Errorf("Expected existing destination to be skipped, got %+v", results[0])
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dstDir, "vendor/a.php"))
+	if string(content) != "destination" {
+		t.Errorf("Existing file was overwritten: %q", content)
+	}
+}
+
+func TestSeed_PathOutsideWorktree(t *testing.T) {
+	parent := t.TempDir()
+	srcDir := filepath.Join(parent, "src")
+	dstDir := filepath.Join(parent, "dst")
+	writeFiles(t, parent, map[string]string{"secret/key": "secret", "src/vendor/a.php": "source"})
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []config.SeedPath{
+		{Path: "../secret"},
+		{Path: ".."},
+		{Path: filepath.Join(parent, "secret")},
+		{Path: "."},
+		{Path: "vendor", Lockfile: "../secret/key"},
+	} {
+		if _, err := Seed(srcDir, dstDir, []config.SeedPath{p}, MethodCopy); err == nil || !strings.Contains(err.Error(), "invalid seed path") {
+			t.Errorf("Seed(%+v) error = %v, want an invalid path error", p, err)
+		}
+	}
+	if entries, _ := os.ReadDir(dstDir); len(entries) != 0 {
+		t.Errorf("nothing should be seeded, got %d entries", len(entries))
+	}
+
+	// Names merely starting with two dots stay inside the worktree
+	writeFiles(t, srcDir, map[string]string{"..cache/a": "cached"})
+	if results, err := Seed(srcDir, dstDir, []config.SeedPath{{Path: "..cache"}}, MethodCopy); err != nil || results[0].Files != 1 {
+		t.Errorf("Seed(..cache) = %+v, %v", results, err)
+	}
+}
+
+func TestLockfilesMatch(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	writeFiles(t, srcDir, map[string]string{"a.lock": "same", "b.lock": "one", "c.lock": "only-src"})
+	writeFiles(t, dstDir, map[string]string{"a.lock": "same", "b.lock": "two"})
+
+	tests := map[string]bool{"a.lock": true, "b.lock": false, "c.lock": false, "d.lock": false}
+	for lockfile, want := range tests {
+		got, err := LockfilesMatch(srcDir, dstDir, lockfile)
+		if err != nil {
+			t.Fatalf("LockfilesMatch(%s) error = %v", lockfile, err)
+		}
+		if got != want {
+			t.Errorf("LockfilesMatch(%s) = %v, want %v", lockfile, got, want)
+		}
+	}
+}
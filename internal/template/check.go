@@ -0,0 +1,85 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/datasource"
+)
+
+// Problem describes a template that failed to parse or render
+type Problem struct {
+	File    string // Path of the template
+	Line    int    // Line number, 0 when unknown
+	Message string
+}
+
+// String formats the problem as file:line: message
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// templateErrorPattern matches text/template errors, possibly wrapped, such as
+// `template: name:3: unexpected "}"` and `template: name:3:7: executing "name" at <.Foo>: ...`
+var templateErrorPattern = regexp.MustCompile(`template: (.*?):(\d+)(?::\d+)?: (?:executing "[^"]*" )?(.*)$`)
+
+// ProblemFromError converts a text/template error for file into a Problem
+func ProblemFromError(file string, err error) Problem {
+	problem := Problem{File: file, Message: err.Error()}
+
+	matches := templateErrorPattern.FindStringSubmatch(err.Error())
+	if matches != nil {
+		problem.Line, _ = strconv.Atoi(matches[2])
+		problem.Message = matches[3]
+	}
+
+	return problem
+}
+
+// CheckTemplates renders every .tmpl file under filesDir in memory with
+// missingkey=error and returns the problems found. Nothing is written to disk.
+// File paths in problems are relative to filesDir.
+func CheckTemplates(filesDir string, data TemplateData, sources map[string]config.Datasource) ([]Problem, error) {
+	if _, err := os.Stat(filesDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	funcMap := datasource.TemplateFuncs(context.Background(), data.RootDirectory, sources)
+
+	var problems []Problem
+	err := filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".tmpl") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(filesDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", relPath, err)
+		}
+
+		if _, err := Render(relPath, string(content), data, funcMap, true); err != nil {
+			problems = append(problems, ProblemFromError(relPath, err))
+		}
+		return nil
+	})
+
+	return problems, err
+}
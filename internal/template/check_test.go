@@ -0,0 +1,127 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+func TestProblemFromError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantLine    int
+		wantMessage string
+	}{
+		{
+			name:        "parse error",
+			err:         errors.New(`template: .env.tmpl:3: unexpected "}" in operand`),
+			wantLine:    3,
+			wantMessage: `unexpected "}" in operand`,
+		},
+		{
+			name:        "execution error",
+			err:         errors.New(`template: cfg/app.tmpl:7:12: executing "cfg/app.tmpl" at <.Brnch>: can't evaluate field Brnch`),
+			wantLine:    7,
+			wantMessage: `at <.Brnch>: can't evaluate field Brnch`,
+		},
+		{
+			name:        "wrapped error",
+			err:         fmt.Errorf("failed to parse template: %w", errors.New(`template: hook:2: function "nope" not defined`)),
+			wantLine:    2,
+			wantMessage: `function "nope" not defined`,
+		},
+		{
+			name:        "non-template error",
+			err:         errors.New("permission denied"),
+			wantLine:    0,
+			wantMessage: "permission denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := ProblemFromError("file", tt.err)
+			if problem.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d", problem.Line, tt.wantLine)
+			}
+			if problem.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", problem.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	if got := (Problem{File: "a.tmpl", Line: 4, Message: "boom"}).String(); got != "a.tmpl:4: boom" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Problem{File: "a.tmpl", Message: "boom"}).String(); got != "a.tmpl: boom" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	rootDir := t.TempDir()
+	filesDir := filepath.Join(rootDir, ".worktree", "files")
+
+	files := map[string]string{
+		"ports.yaml":                     "web: 8080\n",
+		".worktree/files/good.env.tmpl":  "PORT={{ (ds \"ports\").web }}\nBRANCH={{ .Branch }}\n",
+		".worktree/files/bad/parse.tmpl": "line one\n{{ if }}\n",
+		".worktree/files/missing.tmpl":   "ok\nok\nPORT={{ (ds \"ports\").api }}\n",
+		".worktree/files/field.tmpl":     "{{ .Brnch }}",
+		".worktree/files/plain.sql":      "{{ not a template }}",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(rootDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	data := TemplateData{Branch: "feature/x", Directory: filepath.Join(rootDir, "feature-x"), RootDirectory: rootDir}
+	sources := map[string]config.Datasource{"ports": {Path: "ports.yaml"}}
+	problems, err := CheckTemplates(filesDir, data, sources)
+	if err != nil {
+		t.Fatalf("CheckTemplates() error = %v", err)
+	}
+
+	want := map[string]int{
+		filepath.Join("bad", "parse.tmpl"): 2,
+		"missing.tmpl":                     3,
+		"field.tmpl":                       1,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("CheckTemplates() returned %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+	for _, problem := range problems {
+		line, ok := want[problem.File]
+		if !ok {
+			t.Errorf("unexpected problem: %s", problem)
+			continue
+		}
+		if problem.Line != line {
+			t.Errorf("problem %s: line = %d, want %d", problem.File, problem.Line, line)
+		}
+	}
+
+	// Nothing is rendered to disk
+	if _, err := os.Stat(data.Directory); !os.IsNotExist(err) {
+		t.Errorf("CheckTemplates() wrote to the worktree directory")
+	}
+}
+
+func TestCheckTemplates_NoFilesDirectory(t *testing.T) {
+	problems, err := CheckTemplates(filepath.Join(t.TempDir(), "missing"), TemplateData{}, nil)
+	if err != nil || len(problems) != 0 {
+		t.Errorf("CheckTemplates() = %v, %v; want no problems", problems, err)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
-	"github.com/hairyhenderson/gomplate/v4"
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/datasource"
 )
 
 // TemplateData contains variables available in templates
@@ -17,27 +19,45 @@ type TemplateData struct {
 	Branch        string // Branch name (e.g., "feature/user-auth")
 	Directory     string // Absolute path to worktree directory
 	RootDirectory string // Absolute path to repository root
+	SparseProfile string // Sparse-checkout profile (e.g., "api"), empty for a full checkout
+}
+
+// GeneratedFile describes a file written into a worktree from .worktree/files/
+type GeneratedFile struct {
+	Path    string // Output path relative to the worktree (slash-separated)
+	Source  string // Source path relative to the files directory (slash-separated)
+	Content []byte // Content written to disk
 }
 
 // ProcessTemplates processes all files in .worktree/files/
 // Files ending with .tmpl are processed as templates and saved without the .tmpl extension
 // Other files are copied as-is
-func ProcessTemplates(filesDir, worktreeDir string, data TemplateData) error {
+func ProcessTemplates(filesDir, worktreeDir string, data TemplateData, sources map[string]config.Datasource) error {
+	_, err := Generate(filesDir, worktreeDir, data, sources)
+	return err
+}
+
+// Generate processes all files in .worktree/files/ like ProcessTemplates and
+// returns the files it wrote. On error, the files written so far are returned.
+func Generate(filesDir, worktreeDir string, data TemplateData, sources map[string]config.Datasource) ([]GeneratedFile, error) {
 	// Check if files directory exists
 	info, err := os.Stat(filesDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// No files directory, nothing to do
-			return nil
+			return nil, nil
 		}
-		return fmt.Errorf("failed to stat files directory %s: %w", filesDir, err)
+		return nil, fmt.Errorf("failed to stat files directory %s: %w", filesDir, err)
 	}
 	if !info.IsDir() {
-		return fmt.Errorf("files path %s is not a directory", filesDir)
+		return nil, fmt.Errorf("files path %s is not a directory", filesDir)
 	}
 
+	var generated []GeneratedFile
+	funcMap := datasource.TemplateFuncs(context.Background(), data.RootDirectory, sources)
+
 	// Walk through all files in files directory
-	return filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+	err = filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -65,83 +85,122 @@ func ProcessTemplates(filesDir, worktreeDir string, data TemplateData) error {
 		}
 
 		// Check if file is a template (ends with .tmpl)
+		var content []byte
 		if filepath.Ext(path) == ".tmpl" {
 			// Process as template and remove .tmpl extension from output
 			outputPath = outputPath[:len(outputPath)-5] // Remove ".tmpl" extension
-			if err := processTemplateFile(path, outputPath, data); err != nil {
+			if content, err = processTemplateFile(path, outputPath, data, funcMap); err != nil {
 				return fmt.Errorf("failed to process template %s: %w", relPath, err)
 			}
 		} else {
 			// Copy file as-is
-			if err := copyFile(path, outputPath); err != nil {
+			if content, err = copyFile(path, outputPath); err != nil {
 				return fmt.Errorf("failed to copy file %s: %w", relPath, err)
 			}
 		}
 
+		outputRel, _ := filepath.Rel(worktreeDir, outputPath)
+		generated = append(generated, GeneratedFile{
+			Path:    filepath.ToSlash(outputRel),
+			Source:  filepath.ToSlash(relPath),
+			Content: content,
+		})
 		return nil
 	})
+
+	return generated, err
+}
+
+// OutputPath returns the worktree-relative output path for a source path
+// relative to the files directory
+func OutputPath(source string) string {
+	return strings.TrimSuffix(source, ".tmpl")
 }
 
-// copyFile copies a file from src to dst, preserving permissions
-func copyFile(src, dst string) error {
+// RenderFile returns the content a source file in the files directory would
+// generate: the rendered template for .tmpl files, the file itself otherwise
+func RenderFile(sourcePath string, data TemplateData, sources map[string]config.Datasource) ([]byte, error) {
+	return renderFile(sourcePath, data, datasource.TemplateFuncs(context.Background(), data.RootDirectory, sources))
+}
+
+// renderFile implements RenderFile with a prepared function map
+func renderFile(sourcePath string, data TemplateData, funcMap template.FuncMap) ([]byte, error) {
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if filepath.Ext(sourcePath) != ".tmpl" {
+		return content, nil
+	}
+
+	output, err := Render(filepath.Base(sourcePath), string(content), data, funcMap, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return output, nil
+}
+
+// copyFile copies a file from src to dst, preserving permissions, and returns the content
+func copyFile(src, dst string) ([]byte, error) {
 	// Read source file
 	content, err := os.ReadFile(src)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// Get source file permissions
 	info, err := os.Stat(src)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	// Write destination file with same permissions
 	if err := os.WriteFile(dst, content, info.Mode()); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return nil, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return nil
+	return content, nil
 }
 
-// processTemplateFile reads a template file, processes it with gomplate functions, and writes the output
-func processTemplateFile(templatePath, outputPath string, templateData TemplateData) error {
-	// Read template content
-	content, err := os.ReadFile(templatePath)
+// processTemplateFile reads a template file, processes it with gomplate functions, writes the output
+// and returns it
+func processTemplateFile(templatePath, outputPath string, templateData TemplateData, funcMap template.FuncMap) ([]byte, error) {
+	output, err := renderFile(templatePath, templateData, funcMap)
 	if err != nil {
-		return fmt.Errorf("failed to read template: %w", err)
+		return nil, err
 	}
 
-	// Create a context for gomplate functions
-	ctx := context.Background()
-
-	// Get gomplate's function map
-	funcMap := gomplate.CreateFuncs(ctx)
-
-	// Create template with gomplate functions
-	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(funcMap).Parse(string(content))
+	// Preserve file permissions from template
+	info, err := os.Stat(templatePath)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to stat template file: %w", err)
 	}
 
-	// Create output buffer
-	var outputBuffer bytes.Buffer
+	// Write output file with the same permissions as the template
+	if err := os.WriteFile(outputPath, output, info.Mode()); err != nil {
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return output, nil
+}
 
-	// Execute template with data
-	if err := tmpl.Execute(&outputBuffer, templateData); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+// Render parses and executes template content in memory.
+// With strict set, indexing a map with a missing key is an error (missingkey=error).
+// Errors are returned unwrapped so ProblemFromError can extract the line number.
+func Render(name, content string, data any, funcMap template.FuncMap, strict bool) ([]byte, error) {
+	tmpl := template.New(name).Funcs(funcMap)
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
 	}
 
-	// Preserve file permissions from template
-	info, err := os.Stat(templatePath)
-	if err != nil {
-		return fmt.Errorf("failed to stat template file: %w", err)
+	if _, err := tmpl.Parse(content); err != nil {
+		return nil, err
 	}
 
-	// Write output file with the same permissions as the template
-	if err := os.WriteFile(outputPath, outputBuffer.Bytes(), info.Mode()); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	var outputBuffer bytes.Buffer
+	if err := tmpl.Execute(&outputBuffer, data); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return outputBuffer.Bytes(), nil
 }
@@ -1,10 +1,13 @@
 package template
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/datasource"
 )
 
 func TestProcessTemplates(t *testing.T) {
@@ -234,7 +237,7 @@ func TestProcessTemplates(t *testing.T) {
 			}
 
 			// Run ProcessTemplates
-			err := ProcessTemplates(filesDir, worktreeDir, tt.templateData)
+			err := ProcessTemplates(filesDir, worktreeDir, tt.templateData, nil)
 
 			// Check error expectation
 			if (err != nil) != tt.wantErr {
@@ -402,7 +405,7 @@ func TestProcessTemplateFile(t *testing.T) {
 			}
 
 			// Process template
-			err := processTemplateFile(templatePath, outputPath, tt.data)
+			_, err := processTemplateFile(templatePath, outputPath, tt.data, datasource.TemplateFuncs(context.Background(), tempDir, nil))
 
 			// Check error expectation
 			if (err != nil) != tt.wantErr {
@@ -488,7 +491,7 @@ func TestCopyFile(t *testing.T) {
 			}
 
 			// Copy file
-			err := copyFile(srcPath, dstPath)
+			_, err := copyFile(srcPath, dstPath)
 
 			// Check error expectation
 			if (err != nil) != tt.wantErr {
@@ -554,7 +557,7 @@ func TestCopyFile_Errors(t *testing.T) {
 			tempDir := t.TempDir()
 			src, dst := tt.setup(t, tempDir)
 
-			err := copyFile(src, dst)
+			_, err := copyFile(src, dst)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("copyFile() error = %v, wantErr %v", err, tt.wantErr)
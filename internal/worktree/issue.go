@@ -0,0 +1,88 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+	"github.com/vansdevcode/worktree-manager/internal/datasource"
+	"github.com/vansdevcode/worktree-manager/internal/template"
+)
+
+// Defaults for config.IssueSettings
+const (
+	DefaultIssueBranch    = `{{ .Type }}/{{ .Number }}-{{ .Title | strings.Slug }}`
+	DefaultIssueMaxLength = 60
+	DefaultIssueType      = "feature"
+)
+
+// defaultIssueTypes maps issue labels to types when none are configured
+var defaultIssueTypes = map[string]string{"bug": "fix"}
+
+// IssueBranchData contains the variables available in issue branch templates
+type IssueBranchData struct {
+	Number int
+	Title  string
+	Type   string // Type mapped from the issue's labels (e.g., "fix" for bug)
+	Labels []string
+}
+
+// IssueType returns the type of the first label with one, or the default type
+func IssueType(labels []string, settings config.IssueSettings) string {
+	types := settings.Types
+	if types == nil {
+		types = defaultIssueTypes
+	}
+	for _, label := range labels {
+		for name, issueType := range types {
+			if strings.EqualFold(name, label) {
+				return issueType
+			}
+		}
+	}
+	if settings.DefaultType != "" {
+		return settings.DefaultType
+	}
+	return DefaultIssueType
+}
+
+// IssueBranch renders the branch name of an issue from the configured template
+// and truncates it to the configured length
+func IssueBranch(rootDir string, settings config.IssueSettings, sources map[string]config.Datasource, number int, title string, labels []string) (string, error) {
+	pattern := settings.Branch
+	if pattern == "" {
+		pattern = DefaultIssueBranch
+	}
+	maxLength := settings.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultIssueMaxLength
+	}
+
+	funcMap := datasource.TemplateFuncs(context.Background(), rootDir, sources)
+	data := IssueBranchData{
+		Number: number,
+		Title:  title,
+		Type:   IssueType(labels, settings),
+		Labels: labels,
+	}
+	output, err := template.Render("issues.branch", pattern, data, funcMap, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to render issue branch name: %w", err)
+	}
+
+	branch := strings.TrimSpace(string(output))
+	if len(branch) > maxLength {
+		branch = branch[:maxLength]
+		for !utf8.ValidString(branch) {
+			branch = branch[:len(branch)-1]
+		}
+	}
+	// Don't leave a dangling separator where the name was cut
+	branch = strings.TrimRight(branch, "-_./")
+	if branch == "" {
+		return "", fmt.Errorf("issue branch template rendered an empty name")
+	}
+	return branch, nil
+}
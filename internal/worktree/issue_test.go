@@ -0,0 +1,68 @@
+package worktree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vansdevcode/worktree-manager/internal/config"
+)
+
+func TestIssueType(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   []string
+		settings config.IssueSettings
+		want     string
+	}{
+		{name: "default bug mapping", labels: []string{"ui", "Bug"}, want: "fix"},
+		{name: "no mapped label", labels: []string{"ui"}, want: "feature"},
+		{name: "configured", labels: []string{"docs"}, settings: config.IssueSettings{Types: map[string]string{"docs": "docs"}}, want: "docs"},
+		{name: "configured replaces defaults", labels: []string{"bug"}, settings: config.IssueSettings{Types: map[string]string{"docs": "docs"}, DefaultType: "feat"}, want: "feat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IssueType(tt.labels, tt.settings); got != tt.want {
+				t.Errorf("IssueType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueBranch(t *testing.T) {
+	rootDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		settings config.IssueSettings
+		title    string
+		labels   []string
+		want     string
+		wantErr  string
+	}{
+		{name: "default", title: "Crash on start!", labels: []string{"bug"}, want: "fix/12-crash-on-start"},
+		{name: "custom template", settings: config.IssueSettings{Branch: "{{ .Number }}-{{ .Title | strings.Slug }}"}, title: "Add dark mode", want: "12-add-dark-mode"},
+		{name: "truncated without dangling separator", settings: config.IssueSettings{MaxLength: 18}, title: "Add dark mode to settings", want: "feature/12-add-dar"},
+		{name: "cut at separator", settings: config.IssueSettings{MaxLength: 15}, title: "Add dark mode", want: "feature/12-add"},
+		{name: "unknown field", settings: config.IssueSettings{Branch: "{{ .Milestone }}"}, wantErr: "failed to render"},
+		{name: "empty", settings: config.IssueSettings{Branch: "{{ if false }}x{{ end }}"}, wantErr: "empty name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IssueBranch(rootDir, tt.settings, nil, 12, tt.title, tt.labels)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("IssueBranch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IssueBranch() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IssueBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package ui
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
+// Input is where Confirm reads answers from
+var Input io.Reader = os.Stdin
+
 // Color codes
 const (
 	ColorReset  = "\033[0m"
@@ -39,3 +45,17 @@ func Error(format string, args ...interface{}) {
 func Plain(format string, args ...interface{}) {
 	_, _ = fmt.Fprintf(os.Stdout, format+"\n", args...)
 }
+
+// Confirm asks a yes/no question and reports whether it was answered yes.
+// Anything else, including a closed input, means no.
+func Confirm(format string, args ...interface{}) bool {
+	_, _ = fmt.Fprintf(os.Stdout, ColorYellow+format+" [y/N] "+ColorReset, args...)
+
+	answer, err := bufio.NewReader(Input).ReadString('\n')
+	if err != nil && answer == "" {
+		_, _ = fmt.Fprintln(os.Stdout)
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}